@@ -0,0 +1,1002 @@
+package pcre2
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Finding is a construct of a pattern found by Analyze.
+type Finding struct {
+	Offset  int    // byte offset of the construct in the pattern
+	Rule    string // "nested-quantifier" or "overlapping-alternation"
+	Message string
+}
+
+// String formats the finding with its offset.
+func (f Finding) String() string {
+	return fmt.Sprintf("offset %d: %s: %s", f.Offset, f.Rule, f.Message)
+}
+
+// Analysis is the result of Analyze.
+type Analysis struct {
+	// Findings lists the constructs prone to catastrophic backtracking,
+	// in the order of their offsets.
+	Findings      []Finding
+	MinLength     int  // see Regexp.MinSubjectLength
+	MaxLookbehind int  // see Regexp.MaxLookbehind
+	MatchesEmpty  bool // see Regexp.MatchesEmpty
+	Groups        int  // number of capture groups
+}
+
+// Risky reports whether Analyze found constructs prone to catastrophic
+// backtracking.
+func (a *Analysis) Risky() bool {
+	return len(a.Findings) > 0
+}
+
+// Analyze compiles the pattern with flags, and reports constructs which
+// are prone to catastrophic backtracking, together with information
+// about the compiled pattern, to help vetting patterns before they are
+// used in production. It returns the error if the pattern does not
+// compile.
+//
+// Two constructs are reported in groups which are not atomic, when they
+// are repeated without bound by a quantifier which is not possessive:
+//
+//   - nested-quantifier: the repeated group contains an unbounded
+//     quantifier of characters which can both start and end a
+//     repetition, as in (a+)+ or (\w+\s?)*, so that a subject can be
+//     split between the repetitions in exponentially many ways.
+//   - overlapping-alternation: two alternatives of the repeated group
+//     can start with the same character, as in (\w|\d)+.
+//
+// The analysis is a heuristic: it works on the characters the items of
+// the pattern can match, and treats back references, recursion and
+// Unicode properties as matching anything. A pattern without findings
+// can still be slow, and one with findings may be harmless for the
+// subjects it is used with; limits, see SafeCompile, are the safeguard.
+func Analyze(pattern string, flags uint32) (*Analysis, error) {
+	re, err := Compile(pattern, flags)
+	if err != nil {
+		return nil, err
+	}
+	defer re.Free()
+	a := &Analysis{
+		MinLength:     re.MinSubjectLength(),
+		MaxLookbehind: re.MaxLookbehind(),
+		MatchesEmpty:  re.MatchesEmpty(),
+		Groups:        re.Groups(),
+	}
+	ps := &patternParser{pattern: pattern}
+	opts := parseOptions{
+		caseless: flags&CASELESS != 0,
+		dotall:   flags&DOTALL != 0,
+		extended: flags&(EXTENDED|EXTENDED_MORE) != 0,
+		ucp:      flags&UCP != 0,
+	}
+	if flags&LITERAL != 0 {
+		return a, nil
+	}
+	root := &node{kind: nodeGroup, alts: ps.parseAlternatives(&opts)}
+	a.Findings = root.check(nil)
+	slices.SortStableFunc(a.Findings, func(x, y Finding) int {
+		return cmp.Compare(x.Offset, y.Offset)
+	})
+	return a, nil
+}
+
+// charSet is a set of bytes. Non-ASCII characters are represented by the
+// first byte of their UTF-8 encoding.
+type charSet [4]uint64
+
+func (s *charSet) add(c byte) {
+	s[c/64] |= 1 << (c % 64)
+}
+
+func (s *charSet) addRange(lo, hi byte) {
+	for c := int(lo); c <= int(hi); c++ {
+		s.add(byte(c))
+	}
+}
+
+func (s *charSet) addString(chars string) {
+	for i := 0; i < len(chars); i++ {
+		s.add(chars[i])
+	}
+}
+
+func (s *charSet) union(t charSet) {
+	for i := range s {
+		s[i] |= t[i]
+	}
+}
+
+func (s charSet) intersects(t charSet) bool {
+	for i := range s {
+		if s[i]&t[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (s charSet) invert() charSet {
+	for i := range s {
+		s[i] = ^s[i]
+	}
+	return s
+}
+
+func (s charSet) has(c byte) bool {
+	return s[c/64]&(1<<(c%64)) != 0
+}
+
+// fold adds the other case of the ASCII letters in the set.
+func (s charSet) fold() charSet {
+	for c := byte('A'); c <= 'Z'; c++ {
+		if s.has(c) || s.has(c+'a'-'A') {
+			s.add(c)
+			s.add(c + 'a' - 'A')
+		}
+	}
+	return s
+}
+
+var allChars = charSet{}.invert()
+
+// charSetOf returns the set of the characters.
+func charSetOf(chars string) charSet {
+	var s charSet
+	s.addString(chars)
+	return s
+}
+
+var (
+	digitChars  = charSetOf("0123456789")
+	spaceChars  = charSetOf(" \t\n\v\f\r")
+	hspaceChars = charSetOf(" \t")
+	vspaceChars = charSetOf("\n\v\f\r")
+	wordChars   = func() charSet {
+		s := digitChars
+		s.addRange('A', 'Z')
+		s.addRange('a', 'z')
+		s.add('_')
+		return s
+	}()
+	nonASCII = func() charSet {
+		var s charSet
+		s.addRange(0x80, 0xff)
+		return s
+	}()
+)
+
+// posixClasses maps the names of POSIX classes to their characters.
+var posixClasses = map[string]charSet{
+	"alpha":  func() charSet { var s charSet; s.addRange('A', 'Z'); s.addRange('a', 'z'); return s }(),
+	"digit":  digitChars,
+	"alnum":  func() charSet { s := digitChars; s.addRange('A', 'Z'); s.addRange('a', 'z'); return s }(),
+	"word":   wordChars,
+	"space":  spaceChars,
+	"blank":  hspaceChars,
+	"upper":  func() charSet { var s charSet; s.addRange('A', 'Z'); return s }(),
+	"lower":  func() charSet { var s charSet; s.addRange('a', 'z'); return s }(),
+	"xdigit": func() charSet { s := digitChars; s.addRange('A', 'F'); s.addRange('a', 'f'); return s }(),
+	"punct":  charSetOf("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"),
+	"print":  func() charSet { var s charSet; s.addRange(0x20, 0x7e); return s }(),
+	"graph":  func() charSet { var s charSet; s.addRange(0x21, 0x7e); return s }(),
+	"cntrl":  func() charSet { var s charSet; s.addRange(0, 0x1f); s.add(0x7f); return s }(),
+	"ascii":  func() charSet { var s charSet; s.addRange(0, 0x7f); return s }(),
+}
+
+type nodeKind int
+
+const (
+	nodeChars  nodeKind = iota // a single character of set
+	nodeEmpty                  // zero width, e.g. an anchor or option setting
+	nodeAny                    // unknown, e.g. a back reference or recursion
+	nodeGroup                  // a group, or the whole pattern
+	nodeRepeat                 // a quantified item
+)
+
+// node is an item of a parsed pattern.
+type node struct {
+	kind   nodeKind
+	offset int
+	set    charSet   // nodeChars
+	alts   [][]*node // nodeGroup
+	// nodeGroup: the group is atomic, or a lookaround assertion, so that
+	// its contents are not retried by backtracking into it
+	atomic     bool
+	lookaround bool
+	sub        *node // nodeRepeat
+	min, max   int   // nodeRepeat; max is -1 if unbounded
+	possessive bool  // nodeRepeat
+}
+
+// nullable reports whether the node can match the empty string.
+func (n *node) nullable() bool {
+	switch n.kind {
+	case nodeChars:
+		return false
+	case nodeGroup:
+		if n.lookaround {
+			return true
+		}
+		for _, alt := range n.alts {
+			if nullableSeq(alt) {
+				return true
+			}
+		}
+		return false
+	case nodeRepeat:
+		return n.min == 0 || n.sub.nullable()
+	}
+	return true
+}
+
+func nullableSeq(seq []*node) bool {
+	for _, n := range seq {
+		if !n.nullable() {
+			return false
+		}
+	}
+	return true
+}
+
+// first returns the characters a match of the node can start with, or
+// with last set, end with.
+func (n *node) first(last bool) charSet {
+	switch n.kind {
+	case nodeChars:
+		return n.set
+	case nodeAny:
+		return allChars
+	case nodeGroup:
+		var s charSet
+		if !n.lookaround {
+			for _, alt := range n.alts {
+				s.union(firstSeq(alt, last))
+			}
+		}
+		return s
+	case nodeRepeat:
+		if n.max == 0 {
+			return charSet{}
+		}
+		return n.sub.first(last)
+	}
+	return charSet{}
+}
+
+func firstSeq(seq []*node, last bool) charSet {
+	var s charSet
+	for i := range seq {
+		n := seq[i]
+		if last {
+			n = seq[len(seq)-1-i]
+		}
+		s.union(n.first(last))
+		if !n.nullable() {
+			break
+		}
+	}
+	return s
+}
+
+// chars returns all characters the node can match.
+func (n *node) chars() charSet {
+	switch n.kind {
+	case nodeChars:
+		return n.set
+	case nodeAny:
+		return allChars
+	case nodeGroup:
+		var s charSet
+		if !n.lookaround {
+			for _, alt := range n.alts {
+				for _, item := range alt {
+					s.union(item.chars())
+				}
+			}
+		}
+		return s
+	case nodeRepeat:
+		return n.sub.chars()
+	}
+	return charSet{}
+}
+
+// backtracks reports whether the node is an unbounded repetition which
+// is retried by backtracking.
+func (n *node) backtracks() bool {
+	return n.kind == nodeRepeat && n.max < 0 && !n.possessive && n.sub.kind != nodeEmpty
+}
+
+// repeats calls fn for the unbounded repetitions inside the node which
+// are retried by backtracking, not descending into atomic groups and
+// lookarounds.
+func (n *node) repeats(fn func(*node)) {
+	switch n.kind {
+	case nodeGroup:
+		if n.atomic || n.lookaround {
+			return
+		}
+		for _, alt := range n.alts {
+			for _, item := range alt {
+				item.repeats(fn)
+			}
+		}
+	case nodeRepeat:
+		if n.possessive {
+			return
+		}
+		if n.backtracks() {
+			fn(n)
+		}
+		n.sub.repeats(fn)
+	}
+}
+
+// check returns the findings for the node and the nodes inside it.
+func (n *node) check(findings []Finding) []Finding {
+	switch n.kind {
+	case nodeGroup:
+		for _, alt := range n.alts {
+			for _, item := range alt {
+				findings = item.check(findings)
+			}
+		}
+		return findings
+	case nodeRepeat:
+		findings = n.sub.check(findings)
+	default:
+		return findings
+	}
+	g := n.sub
+	if !n.backtracks() || g.kind != nodeGroup || g.atomic || g.lookaround {
+		return findings
+	}
+	first, last := g.first(false), g.first(true)
+	nested := false
+	g.repeats(func(inner *node) {
+		chars := inner.chars()
+		if !nested && chars.intersects(first) && chars.intersects(last) {
+			nested = true
+			findings = append(findings, Finding{g.offset, "nested-quantifier", fmt.Sprintf(
+				"the repeated group contains an unbounded quantifier (offset %d) which can match across its repetitions; "+
+					"make one of the quantifiers possessive or the group atomic", inner.offset)})
+		}
+	})
+	if nested {
+		return findings
+	}
+	for i := range g.alts {
+		for j := i + 1; j < len(g.alts); j++ {
+			if firstSeq(g.alts[i], false).intersects(firstSeq(g.alts[j], false)) {
+				return append(findings, Finding{g.offset, "overlapping-alternation", fmt.Sprintf(
+					"alternatives %d and %d of the repeated group can start with the same character; "+
+						"make them mutually exclusive or the group atomic", i+1, j+1)})
+			}
+		}
+	}
+	return findings
+}
+
+// parseOptions are the options affecting how a pattern is parsed.
+type parseOptions struct {
+	caseless, dotall, extended, ucp bool
+}
+
+// patternParser parses a pattern, which is known to compile, into nodes
+// for Analyze. Only what matters for the analysis is kept.
+type patternParser struct {
+	pattern string
+	i       int
+}
+
+func (ps *patternParser) more() bool {
+	return ps.i < len(ps.pattern)
+}
+
+func (ps *patternParser) peek() byte {
+	if ps.i < len(ps.pattern) {
+		return ps.pattern[ps.i]
+	}
+	return 0
+}
+
+// skipTo moves past the next occurrence of c.
+func (ps *patternParser) skipTo(c byte) {
+	if i := strings.IndexByte(ps.pattern[ps.i:], c); i >= 0 {
+		ps.i += i + 1
+	} else {
+		ps.i = len(ps.pattern)
+	}
+}
+
+// skipSpace skips white space and comments in extended mode.
+func (ps *patternParser) skipSpace(opts *parseOptions) {
+	for opts.extended && ps.more() {
+		switch ps.peek() {
+		case ' ', '\t', '\n', '\v', '\f', '\r':
+			ps.i++
+		case '#':
+			ps.skipTo('\n')
+		default:
+			return
+		}
+	}
+}
+
+// parseAlternatives parses alternatives up to the closing parenthesis
+// of the group, or the end of the pattern. Options set inside the group
+// change opts.
+func (ps *patternParser) parseAlternatives(opts *parseOptions) [][]*node {
+	alts := [][]*node{nil}
+	for {
+		ps.skipSpace(opts)
+		if !ps.more() || ps.peek() == ')' {
+			return alts
+		}
+		if ps.peek() == '|' {
+			ps.i++
+			alts = append(alts, nil)
+			continue
+		}
+		seq := &alts[len(alts)-1]
+		if strings.HasPrefix(ps.pattern[ps.i:], `\Q`) {
+			// Every character up to \E is a literal.
+			ps.i += 2
+			for ps.more() && !strings.HasPrefix(ps.pattern[ps.i:], `\E`) {
+				*seq = append(*seq, ps.literal(opts))
+			}
+			ps.i = min(ps.i+2, len(ps.pattern))
+			if n := len(*seq); n > 0 {
+				(*seq)[n-1] = ps.parseQuantifier((*seq)[n-1], opts)
+			}
+			continue
+		}
+		if n := ps.parseAtom(opts); n != nil {
+			*seq = append(*seq, ps.parseQuantifier(n, opts))
+		}
+	}
+}
+
+// literal parses a literal character.
+func (ps *patternParser) literal(opts *parseOptions) *node {
+	n := &node{kind: nodeChars, offset: ps.i}
+	c := ps.pattern[ps.i]
+	n.set.add(c)
+	if c >= utf8.RuneSelf {
+		_, size := utf8.DecodeRuneInString(ps.pattern[ps.i:])
+		ps.i += size
+	} else {
+		ps.i++
+	}
+	if opts.caseless {
+		n.set = n.set.fold()
+	}
+	return n
+}
+
+// parseAtom parses an item which can be quantified. It returns nil for
+// items which match nothing, like comments and option settings.
+func (ps *patternParser) parseAtom(opts *parseOptions) *node {
+	start := ps.i
+	switch ps.peek() {
+	case '(':
+		return ps.parseGroup(opts)
+	case '[':
+		n := &node{kind: nodeChars, offset: start, set: ps.parseClass(opts)}
+		if opts.caseless {
+			n.set = n.set.fold()
+		}
+		return n
+	case '\\':
+		return ps.parseEscape(opts)
+	case '.':
+		ps.i++
+		n := &node{kind: nodeChars, offset: start, set: allChars}
+		if !opts.dotall {
+			n.set[0] &^= 1 << '\n'
+		}
+		return n
+	case '^', '$':
+		ps.i++
+		return &node{kind: nodeEmpty, offset: start}
+	}
+	return ps.literal(opts)
+}
+
+// parseQuantifier parses the quantifier following n, if there is one.
+func (ps *patternParser) parseQuantifier(n *node, opts *parseOptions) *node {
+	ps.skipSpace(opts)
+	start := ps.i
+	lo, hi := 0, 0
+	switch ps.peek() {
+	case '*':
+		lo, hi = 0, -1
+	case '+':
+		lo, hi = 1, -1
+	case '?':
+		lo, hi = 0, 1
+	case '{':
+		var ok bool
+		if lo, hi, ok = ps.parseBraces(); !ok {
+			return n
+		}
+	default:
+		return n
+	}
+	if ps.i == start {
+		ps.i++
+	}
+	r := &node{kind: nodeRepeat, offset: start, sub: n, min: lo, max: hi}
+	switch ps.peek() {
+	case '+':
+		r.possessive = true
+		ps.i++
+	case '?':
+		ps.i++
+	}
+	return r
+}
+
+// parseBraces parses a {n}, {n,} or {n,m} quantifier, leaving ps.i after
+// it. ok is false if the brace is a literal.
+func (ps *patternParser) parseBraces() (lo, hi int, ok bool) {
+	end := strings.IndexByte(ps.pattern[ps.i:], '}')
+	if end < 0 {
+		return 0, 0, false
+	}
+	body := strings.ReplaceAll(ps.pattern[ps.i+1:ps.i+end], " ", "")
+	loText, hiText, comma := strings.Cut(body, ",")
+	if !isDigits(loText) && !(comma && loText == "" && isDigits(hiText)) {
+		return 0, 0, false
+	}
+	lo = atoi(loText)
+	hi = lo
+	if comma {
+		switch {
+		case hiText == "":
+			hi = -1
+		case isDigits(hiText):
+			hi = atoi(hiText)
+		default:
+			return 0, 0, false
+		}
+	}
+	ps.i += end + 1
+	return lo, hi, true
+}
+
+func isDigits(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
+
+func atoi(s string) int {
+	n := 0
+	for i := 0; i < len(s) && n < 1<<20; i++ {
+		n = n*10 + int(s[i]-'0')
+	}
+	return n
+}
+
+// lookarounds are the names of the alphabetic lookaround assertions,
+// e.g. (*pla:...).
+var lookarounds = map[string]bool{
+	"pla": true, "positive_lookahead": true,
+	"nla": true, "negative_lookahead": true,
+	"plb": true, "positive_lookbehind": true,
+	"nlb": true, "negative_lookbehind": true,
+	"napla": true, "non_atomic_positive_lookahead": true,
+	"naplb": true, "non_atomic_positive_lookbehind": true,
+}
+
+// parseGroup parses a parenthesized item.
+func (ps *patternParser) parseGroup(opts *parseOptions) *node {
+	start := ps.i
+	ps.i++
+	g := &node{kind: nodeGroup, offset: start}
+	inner := *opts
+	rest := ps.pattern[ps.i:]
+	switch {
+	case strings.HasPrefix(rest, "*"):
+		// A verb like (*MARK:name), or an alphabetic assertion.
+		name := rest[1:]
+		if i := strings.IndexAny(name, ":)"); i >= 0 && name[i] == ':' && (lookarounds[name[:i]] || name[:i] == "atomic") {
+			g.lookaround = lookarounds[name[:i]]
+			g.atomic = !g.lookaround
+			ps.i += 1 + i + 1
+			break
+		}
+		ps.skipTo(')')
+		return &node{kind: nodeEmpty, offset: start}
+	case !strings.HasPrefix(rest, "?"):
+		// A capture group.
+	case strings.HasPrefix(rest, "?#"):
+		ps.skipTo(')')
+		return nil
+	case strings.HasPrefix(rest, "?:"), strings.HasPrefix(rest, "?|"):
+		ps.i += 2
+	case strings.HasPrefix(rest, "?>"):
+		g.atomic = true
+		ps.i += 2
+	case strings.HasPrefix(rest, "?="), strings.HasPrefix(rest, "?!"), strings.HasPrefix(rest, "?*"):
+		g.lookaround = true
+		ps.i += 2
+	case strings.HasPrefix(rest, "?<="), strings.HasPrefix(rest, "?<!"), strings.HasPrefix(rest, "?<*"):
+		g.lookaround = true
+		ps.i += 3
+	case strings.HasPrefix(rest, "?<"), strings.HasPrefix(rest, "?P<"):
+		ps.skipTo('>')
+	case strings.HasPrefix(rest, "?'"):
+		ps.i += 2
+		ps.skipTo('\'')
+	case strings.HasPrefix(rest, "?C"):
+		ps.skipTo(')')
+		return &node{kind: nodeEmpty, offset: start}
+	case strings.HasPrefix(rest, "?("):
+		// A condition, which is an assertion or a reference.
+		ps.i++
+		if strings.HasPrefix(ps.pattern[ps.i:], "(?") || strings.HasPrefix(ps.pattern[ps.i:], "(*") {
+			ps.parseGroup(&inner)
+		} else {
+			ps.skipTo(')')
+		}
+		g.alts = ps.parseAlternatives(&inner)
+		if len(g.alts) == 1 {
+			// Without a "no" branch, the group matches nothing if the
+			// condition is false.
+			g.alts = append(g.alts, nil)
+		}
+		ps.skipTo(')')
+		return g
+	default:
+		// Option settings, back references by name and recursion.
+		end := strings.IndexAny(rest, ":)")
+		if end < 0 {
+			ps.i = len(ps.pattern)
+			return nil
+		}
+		letters := rest[1:end]
+		if strings.Trim(letters, "imnrsxJU-^") != "" || letters == "" {
+			// (?R), (?1), (?&name), (?P>name) or (?P=name).
+			ps.skipTo(')')
+			return &node{kind: nodeAny, offset: start}
+		}
+		target := &inner
+		if rest[end] == ')' {
+			target = opts
+		}
+		applyOptions(target, letters)
+		ps.i += end + 1
+		if rest[end] == ')' {
+			return nil
+		}
+	}
+	g.alts = ps.parseAlternatives(&inner)
+	ps.skipTo(')')
+	return g
+}
+
+// applyOptions applies inline option letters like "i-x" to opts.
+func applyOptions(opts *parseOptions, letters string) {
+	on := true
+	if strings.HasPrefix(letters, "^") {
+		*opts = parseOptions{ucp: opts.ucp}
+		letters = letters[1:]
+	}
+	for _, c := range letters {
+		switch c {
+		case '-':
+			on = false
+		case 'i':
+			opts.caseless = on
+		case 's':
+			opts.dotall = on
+		case 'x':
+			opts.extended = on
+		}
+	}
+}
+
+// escapeClass returns the characters of an escape like \d, or false if
+// the escape is not a class.
+func escapeClass(c byte, opts *parseOptions) (charSet, bool) {
+	var s charSet
+	switch c | 0x20 {
+	case 'd':
+		s = digitChars
+	case 'w':
+		s = wordChars
+	case 's':
+		s = spaceChars
+	case 'h':
+		s = hspaceChars
+	case 'v':
+		s = vspaceChars
+	case 'p':
+		return allChars, true
+	default:
+		return s, false
+	}
+	if opts.ucp {
+		s.union(nonASCII)
+	}
+	if c >= 'A' && c <= 'Z' {
+		s = s.invert()
+	}
+	return s, true
+}
+
+// parseEscapedChar parses the character of an escape like \x41 or \n,
+// after the backslash. ok is false if the escape is no character.
+func (ps *patternParser) parseEscapedChar() (c byte, ok bool) {
+	e := ps.pattern[ps.i]
+	ps.i++
+	switch e {
+	case 'a':
+		return '\a', true
+	case 'e':
+		return 0x1b, true
+	case 'f':
+		return '\f', true
+	case 'n':
+		return '\n', true
+	case 'r':
+		return '\r', true
+	case 't':
+		return '\t', true
+	case 'c':
+		if ps.more() {
+			c = ps.pattern[ps.i]&^0x20 ^ 0x40
+			ps.i++
+		}
+		return c, true
+	case 'x', 'o':
+		if ps.peek() == '{' {
+			end := strings.IndexByte(ps.pattern[ps.i:], '}')
+			digits := ps.pattern[ps.i+1 : ps.i+end]
+			ps.i += end + 1
+			return firstByte(parseUint(digits, e)), true
+		}
+		if e == 'o' {
+			return 0, false
+		}
+		j := ps.i
+		for j < len(ps.pattern) && j < ps.i+2 && strings.IndexByte("0123456789abcdefABCDEF", ps.pattern[j]) >= 0 {
+			j++
+		}
+		digits := ps.pattern[ps.i:j]
+		ps.i = j
+		return firstByte(parseUint(digits, e)), true
+	case '0':
+		j := ps.i
+		for j < len(ps.pattern) && j < ps.i+2 && ps.pattern[j] >= '0' && ps.pattern[j] <= '7' {
+			j++
+		}
+		digits := ps.pattern[ps.i:j]
+		ps.i = j
+		return firstByte(parseUint(digits, 'o')), true
+	}
+	if e >= utf8.RuneSelf {
+		_, size := utf8.DecodeRuneInString(ps.pattern[ps.i-1:])
+		ps.i += size - 1
+		return e, true
+	}
+	if e >= '0' && e <= '9' || e >= 'A' && e <= 'Z' || e >= 'a' && e <= 'z' {
+		return 0, false
+	}
+	return e, true
+}
+
+// parseUint parses hexadecimal digits for \x, or octal ones for \o.
+func parseUint(digits string, base byte) rune {
+	var r rune
+	for i := 0; i < len(digits); i++ {
+		d := rune(strings.IndexByte("0123456789abcdef", digits[i]|0x20))
+		if base == 'o' {
+			r = r*8 + d
+		} else {
+			r = r*16 + d
+		}
+	}
+	return r
+}
+
+// firstByte returns the first byte of the UTF-8 encoding of a character,
+// or the character itself if it fits in a byte.
+func firstByte(r rune) byte {
+	if r < 0x100 {
+		return byte(r)
+	}
+	return utf8.AppendRune(nil, r)[0]
+}
+
+// parseEscape parses an escape outside a character class.
+func (ps *patternParser) parseEscape(opts *parseOptions) *node {
+	start := ps.i
+	ps.i++
+	if !ps.more() {
+		return &node{kind: nodeEmpty, offset: start}
+	}
+	e := ps.peek()
+	if s, ok := escapeClass(e, opts); ok {
+		ps.i++
+		if e|0x20 == 'p' && ps.peek() == '{' {
+			ps.skipTo('}')
+		} else if e|0x20 == 'p' {
+			ps.i++
+		}
+		return &node{kind: nodeChars, offset: start, set: s}
+	}
+	switch e {
+	case 'b', 'B', 'A', 'z', 'Z', 'G', 'K', 'E':
+		ps.i++
+		return &node{kind: nodeEmpty, offset: start}
+	case 'N':
+		ps.i++
+		return &node{kind: nodeChars, offset: start, set: charSetOf("\n").invert()}
+	case 'R':
+		ps.i++
+		return &node{kind: nodeChars, offset: start, set: vspaceChars}
+	case 'g', 'k':
+		ps.i++
+		switch ps.peek() {
+		case '{':
+			ps.skipTo('}')
+		case '<':
+			ps.skipTo('>')
+		case '\'':
+			ps.i++
+			ps.skipTo('\'')
+		default:
+			for ps.more() && (ps.peek() == '-' || ps.peek() == '+' || ps.peek() >= '0' && ps.peek() <= '9') {
+				ps.i++
+			}
+		}
+		return &node{kind: nodeAny, offset: start}
+	case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		// A back reference, or an octal character, which is treated
+		// alike.
+		for ps.more() && ps.peek() >= '0' && ps.peek() <= '9' {
+			ps.i++
+		}
+		return &node{kind: nodeAny, offset: start}
+	}
+	c, ok := ps.parseEscapedChar()
+	if !ok {
+		// \X, \C and others.
+		return &node{kind: nodeAny, offset: start}
+	}
+	n := &node{kind: nodeChars, offset: start}
+	n.set.add(c)
+	if opts.caseless {
+		n.set = n.set.fold()
+	}
+	return n
+}
+
+// parseClass parses a character class like [^a-z\d], and returns its
+// characters.
+func (ps *patternParser) parseClass(opts *parseOptions) charSet {
+	ps.i++
+	negate := ps.peek() == '^'
+	if negate {
+		ps.i++
+	}
+	var s charSet
+	first := true
+	for ps.more() {
+		c := ps.peek()
+		if c == ']' && !first {
+			ps.i++
+			break
+		}
+		first = false
+		if strings.HasPrefix(ps.pattern[ps.i:], "[:") {
+			if end := strings.Index(ps.pattern[ps.i:], ":]"); end >= 0 {
+				name := ps.pattern[ps.i+2 : ps.i+end]
+				ps.i += end + 2
+				class, ok := posixClasses[strings.TrimPrefix(name, "^")]
+				if !ok {
+					class = allChars
+				}
+				if strings.HasPrefix(name, "^") {
+					class = class.invert()
+				}
+				s.union(class)
+				continue
+			}
+		}
+		if strings.HasPrefix(ps.pattern[ps.i:], `\Q`) {
+			ps.i += 2
+			end := strings.Index(ps.pattern[ps.i:], `\E`)
+			if end < 0 {
+				end = len(ps.pattern) - ps.i
+			}
+			s.addString(ps.pattern[ps.i : ps.i+end])
+			ps.i = min(ps.i+end+2, len(ps.pattern))
+			continue
+		}
+		lo, ok := ps.classChar(&s, opts)
+		if !ok {
+			continue
+		}
+		if ps.peek() == '-' && ps.i+1 < len(ps.pattern) && ps.pattern[ps.i+1] != ']' {
+			ps.i++
+			if hi, ok := ps.classChar(&s, opts); ok && hi >= lo {
+				s.addRange(lo, hi)
+				continue
+			}
+			s.add('-')
+		}
+		s.add(lo)
+	}
+	if negate {
+		s = s.invert()
+	}
+	return s
+}
+
+// classChar parses a character of a class. Escapes like \d are added to
+// s, and ok is false for them.
+func (ps *patternParser) classChar(s *charSet, opts *parseOptions) (c byte, ok bool) {
+	c = ps.peek()
+	if c != '\\' {
+		if c >= utf8.RuneSelf {
+			_, size := utf8.DecodeRuneInString(ps.pattern[ps.i:])
+			ps.i += size
+			return c, true
+		}
+		ps.i++
+		return c, true
+	}
+	ps.i++
+	if !ps.more() {
+		return 0, false
+	}
+	e := ps.peek()
+	if class, ok := escapeClass(e, opts); ok {
+		ps.i++
+		if e|0x20 == 'p' && ps.peek() == '{' {
+			ps.skipTo('}')
+		} else if e|0x20 == 'p' {
+			ps.i++
+		}
+		s.union(class)
+		return 0, false
+	}
+	if e == 'b' {
+		ps.i++
+		return '\b', true
+	}
+	if e == 'E' {
+		ps.i++
+		return 0, false
+	}
+	if e == 'N' || e == 'R' || e == 'X' {
+		ps.i++
+		s.union(allChars)
+		return 0, false
+	}
+	if e >= '1' && e <= '7' {
+		// An octal character.
+		j := ps.i
+		for j < len(ps.pattern) && j < ps.i+3 && ps.pattern[j] >= '0' && ps.pattern[j] <= '7' {
+			j++
+		}
+		digits := ps.pattern[ps.i:j]
+		ps.i = j
+		return firstByte(parseUint(digits, 'o')), true
+	}
+	if c, ok := ps.parseEscapedChar(); ok {
+		return c, true
+	}
+	s.union(allChars)
+	return 0, false
+}
@@ -0,0 +1,79 @@
+package pcre2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyze(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		flags   uint32
+		rules   []string
+		offsets []int
+	}{
+		{`(a+)+b`, 0, []string{"nested-quantifier"}, []int{0}},
+		{`^(\w+\s?)*$`, 0, []string{"nested-quantifier"}, []int{1}},
+		{`(?:.*a)*`, 0, []string{"nested-quantifier"}, []int{0}},
+		{`x((a*)*)+`, 0, []string{"nested-quantifier", "nested-quantifier"}, []int{1, 2}},
+		{`(\w|\d)+`, 0, []string{"overlapping-alternation"}, []int{0}},
+		{`(?:a|A)*`, CASELESS, []string{"overlapping-alternation"}, []int{0}},
+		{`(?i:a|A)*`, 0, []string{"overlapping-alternation"}, []int{0}},
+		{`(?:[a-f]|[0-9a])+`, 0, []string{"overlapping-alternation"}, []int{0}},
+		{`(?=(a+)+b)`, 0, []string{"nested-quantifier"}, []int{3}},
+		{`(?>(a+)+b)`, 0, []string{"nested-quantifier"}, []int{3}},
+		{`( a + ) + # comment`, EXTENDED, []string{"nested-quantifier"}, []int{0}},
+
+		// Possessive, atomic and bounded repetitions do not backtrack.
+		{`(a++)+b`, 0, nil, nil},
+		{`(a+)++b`, 0, nil, nil},
+		{`(?>a+)+b`, 0, nil, nil},
+		{`(?>\w+\s?)*$`, 0, nil, nil},
+		{`(a+){1,3}b`, 0, nil, nil},
+		// The inner repetitions cannot match across the outer one.
+		{`(?:\s*,\s*\w+)*`, 0, nil, nil},
+		{`([a-z]+\d+)*`, 0, nil, nil},
+		{`"(?:[^"\\]|\\.)*"`, 0, nil, nil},
+		{`(?:a|b)*`, 0, nil, nil},
+		{`(?:a|A)*`, 0, nil, nil},
+		{`[(a+)+]`, 0, nil, nil},
+		{`\Q(a+)+\E`, 0, nil, nil},
+		{`(a+)+`, LITERAL, nil, nil},
+		{`(?#(a+x)x{2,}`, 0, nil, nil},
+	} {
+		a, err := Analyze(tc.pattern, tc.flags)
+		if !assert.NoError(t, err, tc.pattern) {
+			continue
+		}
+		var rules []string
+		var offsets []int
+		for _, f := range a.Findings {
+			rules = append(rules, f.Rule)
+			offsets = append(offsets, f.Offset)
+		}
+		assert.Equal(t, tc.rules, rules, tc.pattern)
+		assert.Equal(t, tc.offsets, offsets, tc.pattern)
+		assert.Equal(t, tc.rules != nil, a.Risky(), tc.pattern)
+	}
+}
+
+func TestAnalyzeInfo(t *testing.T) {
+	a, err := Analyze(`(?<=ab)(\d{3})-(\d+)?`, 0)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 4, a.MinLength)
+		assert.Equal(t, 2, a.MaxLookbehind)
+		assert.False(t, a.MatchesEmpty)
+		assert.Equal(t, 2, a.Groups)
+		assert.Empty(t, a.Findings)
+	}
+	a, err = Analyze(`(x+x+)+y`, 0)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3, a.MinLength)
+		assert.Equal(t, "offset 0: nested-quantifier: the repeated group contains an unbounded quantifier (offset 2) "+
+			"which can match across its repetitions; make one of the quantifiers possessive or the group atomic",
+			a.Findings[0].String())
+	}
+	_, err = Analyze(`(`, 0)
+	assert.IsType(t, &CompileError{}, err)
+}
@@ -0,0 +1,92 @@
+package pcre2
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// annotateContext is the number of bytes shown before and after the
+// error offset of long patterns.
+const annotateContext = 40
+
+// offset returns the error offset, clamped to the pattern.
+func (e *CompileError) offset() int {
+	return min(max(e.Offset, 0), len(e.Pattern))
+}
+
+// RuneOffset returns the error offset in runes instead of bytes, for
+// highlighting the offending character of non-ASCII patterns.
+func (e *CompileError) RuneOffset() int {
+	return utf8.RuneCountInString(e.Pattern[:e.offset()])
+}
+
+// Position returns the line and column of the error offset, both
+// counting from 1, e.g. for patterns spanning several lines with
+// EXTENDED. The column counts runes.
+func (e *CompileError) Position() (line, column int) {
+	before := e.Pattern[:e.offset()]
+	start := strings.LastIndexByte(before, '\n') + 1
+	return strings.Count(before, "\n") + 1, utf8.RuneCountInString(before[start:]) + 1
+}
+
+// Annotate renders the part of the pattern around the error offset, with
+// a caret under the offending position:
+//
+//	(?<year>\d{4})-(?<month>\d{2}
+//	                             ^
+//
+// Only the line holding the offset is shown, prefixed by its number if
+// the pattern has several lines, and long lines are cut to the context
+// of the error.
+func (e *CompileError) Annotate() string {
+	pattern := e.Pattern
+	offset := e.offset()
+
+	start := strings.LastIndexByte(pattern[:offset], '\n') + 1
+	end := len(pattern)
+	if i := strings.IndexByte(pattern[offset:], '\n'); i >= 0 {
+		end = offset + i
+	}
+	var prefix string
+	if strings.Contains(pattern, "\n") {
+		line, _ := e.Position()
+		prefix = fmt.Sprintf("line %d: ", line)
+	}
+	head, tail := "", ""
+	if offset-start > annotateContext {
+		start = runeStart(pattern, offset-annotateContext)
+		head = "..."
+	}
+	if end-offset > annotateContext {
+		end = runeStart(pattern, offset+annotateContext)
+		tail = "..."
+	}
+
+	line := prefix + head + pattern[start:end] + tail
+	// Keep tabs, so that the caret lines up however they are shown.
+	var caret strings.Builder
+	for _, r := range prefix + head + pattern[start:offset] {
+		if r == '\t' {
+			caret.WriteByte('\t')
+		} else {
+			caret.WriteByte(' ')
+		}
+	}
+	caret.WriteByte('^')
+	return line + "\n" + caret.String()
+}
+
+// runeStart moves i back to the start of the UTF-8 sequence it is in.
+func runeStart(s string, i int) int {
+	for i > 0 && i < len(s) && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return i
+}
+
+// Detail returns the error message along with the PCRE2 error number
+// and the annotated pattern, see Annotate.
+func (e *CompileError) Detail() string {
+	return fmt.Sprintf("PCRE2 error %d at offset %d: %s\n%s", e.ErrorNum, e.Offset, e.Message, e.Annotate())
+}
@@ -0,0 +1,57 @@
+package pcre2
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func compileError(t *testing.T, pattern string, flags uint32) *CompileError {
+	_, err := Compile(pattern, flags)
+	if !assert.IsType(t, &CompileError{}, err) {
+		t.FailNow()
+	}
+	return err.(*CompileError)
+}
+
+func TestAnnotate(t *testing.T) {
+	e := compileError(t, `abc(def`, 0)
+	assert.Equal(t, "abc(def\n       ^", e.Annotate())
+	assert.Equal(t, "PCRE2 error 114 at offset 7: missing closing parenthesis\nabc(def\n       ^", e.Detail())
+
+	e = compileError(t, "a\tb)c", 0)
+	assert.Equal(t, "a\tb)c\n \t ^", e.Annotate())
+
+	e = compileError(t, "ä)", UTF)
+	assert.Equal(t, "ä)\n ^", e.Annotate())
+
+	e = compileError(t, "a\n b)\nc", EXTENDED)
+	assert.Equal(t, "line 2:  b)\n          ^", e.Annotate())
+}
+
+func TestAnnotateLong(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	e := compileError(t, long+")"+long, 0)
+	lines := strings.Split(e.Annotate(), "\n")
+	assert.Equal(t, "..."+strings.Repeat("x", 40)+")"+strings.Repeat("x", 39)+"...", lines[0])
+	assert.Equal(t, strings.Repeat(" ", 43)+"^", lines[1])
+}
+
+func TestRuneOffset(t *testing.T) {
+	e := compileError(t, "äöü)", UTF)
+	assert.Equal(t, 6, e.Offset)
+	assert.Equal(t, 3, e.RuneOffset())
+	line, col := e.Position()
+	assert.Equal(t, 1, line)
+	assert.Equal(t, 4, col)
+
+	e = compileError(t, "(?x) ä\n  ö)\n", UTF)
+	assert.Equal(t, 10, e.RuneOffset())
+	line, col = e.Position()
+	assert.Equal(t, 2, line)
+	assert.Equal(t, 4, col)
+
+	e = &CompileError{Pattern: "abc", Offset: 10}
+	assert.Equal(t, 3, e.RuneOffset())
+}
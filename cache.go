@@ -0,0 +1,118 @@
+package pcre2
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultCacheCapacity is the number of patterns kept by CompileCached
+// unless changed with SetCacheCapacity.
+const DefaultCacheCapacity = 256
+
+type cacheKey struct {
+	pattern  string
+	flags    uint32
+	jitFlags uint32
+}
+
+type cacheEntry struct {
+	key cacheKey
+	re  *Regexp
+}
+
+// patternCache is the LRU cache of CompileCached. The front of lru is
+// the most recently used entry.
+var patternCache = struct {
+	sync.Mutex
+	capacity int
+	lru      list.List
+	entries  map[cacheKey]*list.Element
+}{
+	capacity: DefaultCacheCapacity,
+	entries:  make(map[cacheKey]*list.Element),
+}
+
+var (
+	statCacheHits   atomic.Int64
+	statCacheMisses atomic.Int64
+)
+
+// SetCacheCapacity sets the number of patterns kept by CompileCached,
+// evicting the least recently used ones if there are more. Zero or less
+// disables the cache.
+func SetCacheCapacity(n int) {
+	patternCache.Lock()
+	defer patternCache.Unlock()
+	patternCache.capacity = n
+	evictCached()
+}
+
+// evictCached removes the least recently used entries beyond the
+// capacity. The cache must be locked.
+func evictCached() {
+	for patternCache.lru.Len() > max(patternCache.capacity, 0) {
+		e := patternCache.lru.Back()
+		patternCache.lru.Remove(e)
+		delete(patternCache.entries, e.Value.(*cacheEntry).key)
+	}
+}
+
+// CompileCached is like Compile, but keeps the compiled pattern in a
+// least recently used cache, keyed by pattern and flags, which is shared
+// by all goroutines. Services which compile the same few patterns over
+// and over again save the compilation.
+//
+// The Regexp is shared with other callers and must not be freed or
+// modified, e.g. with SetMaxSubjectLength. Its C code is freed once it
+// has been evicted from the cache and is no longer referenced, even if
+// automatic cleanup is disabled.
+func CompileCached(pattern string, flags uint32) (*Regexp, error) {
+	return compileCached(cacheKey{pattern, flags, 0})
+}
+
+// CompileCachedJIT is like CompileCached, but the pattern is JIT
+// compiled with jitFlags, see CompileJIT. A pattern compiled with
+// different jitFlags is cached separately.
+func CompileCachedJIT(pattern string, comFlags, jitFlags uint32) (*Regexp, error) {
+	return compileCached(cacheKey{pattern, comFlags, jitFlags})
+}
+
+func compileCached(key cacheKey) (*Regexp, error) {
+	patternCache.Lock()
+	if e := patternCache.entries[key]; e != nil {
+		patternCache.lru.MoveToFront(e)
+		patternCache.Unlock()
+		statCacheHits.Add(1)
+		return e.Value.(*cacheEntry).re, nil
+	}
+	patternCache.Unlock()
+	statCacheMisses.Add(1)
+
+	// Compile without holding the lock, so that a slow compilation does
+	// not block other patterns.
+	re, err := Compile(key.pattern, key.flags)
+	if err != nil {
+		return nil, err
+	}
+	if key.jitFlags != 0 {
+		if err := re.JITCompile(key.jitFlags); err != nil {
+			re.Free()
+			return nil, err
+		}
+	}
+	re.own()
+
+	patternCache.Lock()
+	defer patternCache.Unlock()
+	if e := patternCache.entries[key]; e != nil {
+		// Another goroutine compiled the same pattern meanwhile.
+		patternCache.lru.MoveToFront(e)
+		return e.Value.(*cacheEntry).re, nil
+	}
+	if patternCache.capacity > 0 {
+		patternCache.entries[key] = patternCache.lru.PushFront(&cacheEntry{key, re})
+		evictCached()
+	}
+	return re, nil
+}
@@ -0,0 +1,72 @@
+package pcre2
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileCached(t *testing.T) {
+	defer SetCacheCapacity(DefaultCacheCapacity)
+	SetCacheCapacity(2)
+
+	before := Snapshot()
+	a, err := CompileCached(`a+`, 0)
+	assert.NoError(t, err)
+	again, err := CompileCached(`a+`, 0)
+	assert.NoError(t, err)
+	assert.Same(t, a, again)
+	caseless, _ := CompileCached(`a+`, CASELESS)
+	assert.NotSame(t, a, caseless, "flags are part of the key")
+	after := Snapshot()
+	assert.Equal(t, int64(1), after.CacheHits-before.CacheHits)
+	assert.Equal(t, int64(2), after.CacheMisses-before.CacheMisses)
+
+	// a+ was used less recently than a+ with CASELESS.
+	CompileCached(`b+`, 0)
+	other, _ := CompileCached(`a+`, 0)
+	assert.NotSame(t, a, other, "evicted")
+	same, _ := CompileCached(`b+`, 0)
+	b, _ := CompileCached(`b+`, 0)
+	assert.Same(t, same, b)
+
+	// Evicted patterns stay usable while referenced.
+	assert.Equal(t, "aa", a.FindString("baac", 0))
+
+	_, err = CompileCached(`(`, 0)
+	assert.Error(t, err)
+
+	SetCacheCapacity(0)
+	x, _ := CompileCached(`x`, 0)
+	y, _ := CompileCached(`x`, 0)
+	assert.NotSame(t, x, y)
+}
+
+func TestCompileCachedJIT(t *testing.T) {
+	re, err := CompileCachedJIT(`\d+`, 0, JIT_COMPLETE)
+	if err != nil {
+		t.Skip("JIT not available:", err)
+	}
+	assert.NotZero(t, re.JITSize())
+	plain, _ := CompileCached(`\d+`, 0)
+	assert.NotSame(t, re, plain)
+}
+
+func TestCompileCachedConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				re, err := CompileCached(fmt.Sprintf(`x%d`, j%10), 0)
+				if assert.NoError(t, err) {
+					assert.True(t, re.MatcherString(fmt.Sprintf("ax%d", j%10), 0).Matches())
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
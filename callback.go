@@ -0,0 +1,56 @@
+package pcre2
+
+// This file holds the Go functions which are called back from C.
+// Because of the //export directives, the preamble below must only
+// contain declarations.
+
+/*
+#define PCRE2_CODE_UNIT_WIDTH 8
+
+#include <stdint.h>
+#include <pcre2.h>
+*/
+import "C"
+
+import (
+	"runtime/cgo"
+	"unsafe"
+)
+
+//export goCallout
+func goCallout(block *C.pcre2_callout_block, handle C.uintptr_t) C.int {
+	fn := cgo.Handle(handle).Value().(calloutFunc)
+	return fn(block)
+}
+
+//export goSubstituteCallout
+func goSubstituteCallout(block *C.pcre2_substitute_callout_block, handle C.uintptr_t) C.int {
+	fn := cgo.Handle(handle).Value().(substituteCalloutFunc)
+	return fn(block)
+}
+
+//export goRecursionGuard
+func goRecursionGuard(depth C.uint32_t, handle C.uintptr_t) C.int {
+	guard := cgo.Handle(handle).Value().(RecursionGuard)
+	if guard(uint32(depth)) {
+		return 0
+	}
+	return 1
+}
+
+//export goCalloutEnumerate
+func goCalloutEnumerate(block *C.pcre2_callout_enumerate_block, handle C.uintptr_t) C.int {
+	callouts := cgo.Handle(handle).Value().(*[]Callout)
+	*callouts = append(*callouts, newCallout(block))
+	return 0
+}
+
+//export goMalloc
+func goMalloc(size C.size_t, handle C.uintptr_t) unsafe.Pointer {
+	return cgo.Handle(handle).Value().(Allocator).Malloc(uintptr(size))
+}
+
+//export goFree
+func goFree(ptr unsafe.Pointer, handle C.uintptr_t) {
+	cgo.Handle(handle).Value().(Allocator).Free(ptr)
+}
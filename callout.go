@@ -0,0 +1,213 @@
+package pcre2
+
+/*
+#define PCRE2_CODE_UNIT_WIDTH 8
+
+#include <stdint.h>
+#include <pcre2.h>
+#include "./pcre2_fallback.h"
+
+extern int goCallout(pcre2_callout_block *, uintptr_t);
+
+static int myCalloutTrampoline(pcre2_callout_block *block, void *data) {
+	return goCallout(block, (uintptr_t) data);
+}
+
+static void mySetCallout(pcre2_match_context *mcontext, uintptr_t handle) {
+	if (handle == 0) {
+		pcre2_set_callout(mcontext, NULL, NULL);
+	} else {
+		pcre2_set_callout(mcontext, myCalloutTrampoline, (void *) handle);
+	}
+}
+
+extern int goCalloutEnumerate(pcre2_callout_enumerate_block *, uintptr_t);
+
+static int myCalloutEnumerateTrampoline(pcre2_callout_enumerate_block *block, void *data) {
+	return goCalloutEnumerate(block, (uintptr_t) data);
+}
+
+static int myCalloutEnumerate(const pcre2_code *code, uintptr_t handle) {
+	return pcre2_callout_enumerate(code, myCalloutEnumerateTrampoline, (void *) handle);
+}
+*/
+import "C"
+
+import (
+	"runtime/cgo"
+	"unsafe"
+)
+
+// calloutFunc is invoked for every callout during a match. A return value
+// of zero continues the match, a positive value fails at the current
+// position and a negative value aborts the match with that error code.
+type calloutFunc func(block *C.pcre2_callout_block) C.int
+
+// setCallout installs the callout function which is invoked during the
+// matches of m. A nil function removes it.
+func (m *Matcher) setCallout(fn calloutFunc) {
+	m.callout = fn
+}
+
+// installCallout installs the callout of m in its match context for the
+// duration of a single match. The handle only lives until removeCallout,
+// so an abandoned Matcher does not pin its callout.
+func (m *Matcher) installCallout() cgo.Handle {
+	h := cgo.NewHandle(m.callout)
+	C.mySetCallout(m.privateContext().ptr, C.uintptr_t(h))
+	return h
+}
+
+func (m *Matcher) removeCallout(h cgo.Handle) {
+	C.mySetCallout(m.private.ptr, 0)
+	h.Delete()
+}
+
+// CalloutBlock describes the state of a match at a callout. It is only
+// valid during the call of the CalloutFunc which receives it.
+type CalloutBlock struct {
+	Number          int    // callout number, 255 for automatic callouts
+	String          string // callout string, for (?C"...") callouts
+	StringOffset    int    // offset of the callout string in the pattern
+	StartMatch      int    // subject offset of the current match attempt
+	CurrentPosition int    // current subject offset
+	PatternPosition int    // offset of the next item in the pattern
+	NextItemLength  int    // length of the next item in the pattern
+	CaptureTop      int    // one more than the highest capture group set
+	CaptureLast     int    // most recently closed capture group
+	Flags           uint32 // CALLOUT_STARTMATCH and CALLOUT_BACKTRACK bits
+
+	m     *Matcher
+	block *C.pcre2_callout_block
+}
+
+// CalloutFunc is invoked for callouts during a match. Returning zero
+// continues the match, a positive value makes the match fail at the
+// current position, so that backtracking takes place, and a negative
+// value aborts the whole match with that error code, e.g. ERROR_CALLOUT.
+type CalloutFunc func(block *CalloutBlock) int
+
+// SetCallout installs fn, which is invoked for every callout during the
+// subsequent matches of m. Callouts are written as (?C) or (?Cn) in the
+// pattern, or are inserted before every item by compiling it with
+// AUTO_CALLOUT. A nil function removes the callout.
+func (m *Matcher) SetCallout(fn CalloutFunc) {
+	if fn == nil {
+		m.setCallout(nil)
+		return
+	}
+	m.setCallout(func(block *C.pcre2_callout_block) C.int {
+		return C.int(fn(newCalloutBlock(m, block)))
+	})
+}
+
+func newCalloutBlock(m *Matcher, block *C.pcre2_callout_block) *CalloutBlock {
+	b := &CalloutBlock{
+		Number:          int(block.callout_number),
+		StartMatch:      int(block.start_match),
+		CurrentPosition: int(block.current_position),
+		PatternPosition: int(block.pattern_position),
+		NextItemLength:  int(block.next_item_length),
+		CaptureTop:      int(block.capture_top),
+		CaptureLast:     int(block.capture_last),
+		Flags:           uint32(C.myCalloutFlags(block)),
+		m:               m,
+		block:           block,
+	}
+	if block.callout_string != nil {
+		b.String = C.GoStringN((*C.char)(unsafe.Pointer(block.callout_string)),
+			C.int(block.callout_string_length))
+		b.StringOffset = int(block.callout_string_offset)
+	}
+	return b
+}
+
+// GroupIndices returns the start and end offsets of the capture group
+// as far as it is set at the callout, or nil.
+func (b *CalloutBlock) GroupIndices(group int) []int {
+	if group < 1 || group >= b.CaptureTop {
+		return nil
+	}
+	ovector := unsafe.Slice(b.block.offset_vector, 2*b.CaptureTop)
+	start, end := ovector[2*group], ovector[2*group+1]
+	if start == UNSET {
+		return nil
+	}
+	return []int{int(start), int(end)}
+}
+
+// Group returns the text of the capture group as far as it is set at the
+// callout, or nil.
+func (b *CalloutBlock) Group(group int) []byte {
+	loc := b.GroupIndices(group)
+	if loc == nil {
+		return nil
+	}
+	if b.m.subjectb != nil {
+		return b.m.subjectb[loc[0]:loc[1]]
+	}
+	return []byte(b.m.subjects[loc[0]:loc[1]])
+}
+
+// GroupString is like Group, but returns a string.
+func (b *CalloutBlock) GroupString(group int) string {
+	loc := b.GroupIndices(group)
+	if loc == nil {
+		return ""
+	}
+	if b.m.subjectb != nil {
+		return string(b.m.subjectb[loc[0]:loc[1]])
+	}
+	return b.m.subjects[loc[0]:loc[1]]
+}
+
+// Mark returns the most recently passed (*MARK) name, or "".
+func (b *CalloutBlock) Mark() string {
+	if b.block.mark == nil {
+		return ""
+	}
+	return C.GoString((*C.char)(unsafe.Pointer(b.block.mark)))
+}
+
+// Callout describes a callout point of a compiled pattern.
+type Callout struct {
+	Number          int    // callout number, 255 for automatic callouts
+	String          string // callout string, for (?C"...") callouts
+	StringOffset    int    // offset of the callout string in the pattern
+	PatternPosition int    // offset of the next item in the pattern
+	NextItemLength  int    // length of the next item in the pattern
+}
+
+// Callouts returns the callouts present in the compiled pattern, in the
+// order in which they appear, e.g. to decide whether a handler needs to
+// be installed with SetCallout.
+func (re *Regexp) Callouts() ([]Callout, error) {
+	rptr, err := re.validRegexpPtr()
+	if err != nil {
+		return nil, err
+	}
+	if err := Require(FeatureCalloutEnumerate); err != nil {
+		return nil, err
+	}
+	var callouts []Callout
+	h := cgo.NewHandle(&callouts)
+	defer h.Delete()
+	if rc := C.myCalloutEnumerate(rptr, C.uintptr_t(h)); rc != 0 {
+		return nil, &MatchError{ErrorNum: int(rc), Message: errorMessage(int(rc))}
+	}
+	return callouts, nil
+}
+
+func newCallout(block *C.pcre2_callout_enumerate_block) Callout {
+	c := Callout{
+		Number:          int(block.callout_number),
+		PatternPosition: int(block.pattern_position),
+		NextItemLength:  int(block.next_item_length),
+	}
+	if block.callout_string != nil {
+		c.String = C.GoStringN((*C.char)(unsafe.Pointer(block.callout_string)),
+			C.int(block.callout_string_length))
+		c.StringOffset = int(block.callout_string_offset)
+	}
+	return c
+}
@@ -0,0 +1,85 @@
+package pcre2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallout(t *testing.T) {
+	re := MustCompile(`(a+)(?C1)(*MARK:m)b(?C"tag")`, 0)
+	defer re.Free()
+	m := re.NewMatcher()
+	defer m.Free()
+
+	var blocks []CalloutBlock
+	var groups []string
+	var marks []string
+	m.SetCallout(func(b *CalloutBlock) int {
+		blocks = append(blocks, *b)
+		groups = append(groups, b.GroupString(1))
+		marks = append(marks, b.Mark())
+		return 0
+	})
+	assert.True(t, m.MatchString("xaab", 0))
+	if assert.Len(t, blocks, 2) {
+		assert.Equal(t, 1, blocks[0].Number)
+		assert.Equal(t, 3, blocks[0].CurrentPosition)
+		assert.Equal(t, 1, blocks[0].StartMatch)
+		assert.Equal(t, 0, blocks[1].Number)
+		assert.Equal(t, "tag", blocks[1].String)
+		assert.Equal(t, 4, blocks[1].CurrentPosition)
+	}
+	assert.Equal(t, []string{"aa", "aa"}, groups)
+	assert.Equal(t, []string{"", "m"}, marks)
+
+	// Fail at the first callout of each attempt, then abort.
+	m.SetCallout(func(b *CalloutBlock) int {
+		if b.Number == 1 {
+			return 1
+		}
+		return 0
+	})
+	assert.False(t, m.Match([]byte("aab"), 0))
+	assert.Equal(t, ERROR_NOMATCH, m.rc)
+	m.SetCallout(func(b *CalloutBlock) int { return ERROR_CALLOUT })
+	assert.False(t, m.MatchString("aab", 0))
+	assert.Equal(t, ERROR_CALLOUT, m.rc)
+
+	m.SetCallout(nil)
+	assert.True(t, m.MatchString("aab", 0))
+}
+
+func TestAutoCallout(t *testing.T) {
+	re := MustCompile(`ab`, AUTO_CALLOUT)
+	defer re.Free()
+	m := re.NewMatcher()
+	defer m.Free()
+	var items []string
+	m.SetCallout(func(b *CalloutBlock) int {
+		assert.Equal(t, 255, b.Number)
+		items = append(items, re.Pattern[b.PatternPosition:b.PatternPosition+b.NextItemLength])
+		return 0
+	})
+	assert.True(t, m.MatchString("ab", 0))
+	assert.Equal(t, []string{"a", "b", ""}, items)
+}
+
+func TestCallouts(t *testing.T) {
+	re := MustCompile(`a(?C1)b(?C"tag")c`, 0)
+	defer re.Free()
+	callouts, err := re.Callouts()
+	assert.NoError(t, err)
+	assert.Equal(t, []Callout{
+		{Number: 1, PatternPosition: 6, NextItemLength: 1},
+		{String: "tag", StringOffset: 11, PatternPosition: 16, NextItemLength: 1},
+	}, callouts)
+
+	re = MustCompile(`ab`, 0)
+	callouts, err = re.Callouts()
+	assert.NoError(t, err)
+	assert.Empty(t, callouts)
+	re.Free()
+	_, err = re.Callouts()
+	assert.Equal(t, ErrInvalidRegexp, err)
+}
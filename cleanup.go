@@ -0,0 +1,31 @@
+package pcre2
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+var autoCleanup atomic.Bool
+
+func init() {
+	autoCleanup.Store(true)
+}
+
+// SetAutoCleanup controls whether the C resources of Regexps, Matchers,
+// contexts, JIT stacks and Tables are freed automatically when they are
+// garbage collected, which is the default. Programs which free all objects
+// deterministically with Free can disable it to save the cost of
+// registering the cleanups; objects which are not freed then leak. The
+// setting applies to objects created afterwards.
+func SetAutoCleanup(enabled bool) {
+	autoCleanup.Store(enabled)
+}
+
+// addCleanup is like runtime.AddCleanup, but does nothing if automatic
+// cleanup is disabled with SetAutoCleanup.
+func addCleanup[T, S any](ptr *T, cleanup func(S), arg S) runtime.Cleanup {
+	if !autoCleanup.Load() {
+		return runtime.Cleanup{}
+	}
+	return runtime.AddCleanup(ptr, cleanup, arg)
+}
@@ -0,0 +1,90 @@
+package pcre2
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// waitFor runs the garbage collector until cond holds, or gives up.
+func waitFor(cond func() bool) bool {
+	for i := 0; i < 100 && !cond(); i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	return cond()
+}
+
+func TestAutoCleanup(t *testing.T) {
+	before := Snapshot()
+	func() {
+		re := MustCompile(`(a+)`, 0)
+		re.NewMatcher().MatchString("aa", 0)
+	}()
+	assert.True(t, waitFor(func() bool {
+		s := Snapshot()
+		return s.LiveRegexps <= before.LiveRegexps && s.LiveMatchers <= before.LiveMatchers
+	}))
+}
+
+func TestNoAutoCleanup(t *testing.T) {
+	SetAutoCleanup(false)
+	defer SetAutoCleanup(true)
+	re := MustCompile(`(a+)`, 0)
+	m := re.NewMatcher()
+	before := Snapshot()
+	m.Free()
+	assert.NoError(t, re.Free())
+	after := Snapshot()
+	assert.Equal(t, before.LiveRegexps-1, after.LiveRegexps)
+	assert.Equal(t, before.LiveMatchers-1, after.LiveMatchers)
+	assert.NoError(t, re.Free())
+	assert.Equal(t, after, Snapshot())
+}
+
+func TestNoAutoCleanupInit(t *testing.T) {
+	SetAutoCleanup(false)
+	defer SetAutoCleanup(true)
+	re1 := MustCompile(`(a+)`, 0)
+	defer re1.Free()
+	re2 := MustCompile(`(a)(b)`, 0)
+	defer re2.Free()
+	m := re1.NewMatcher()
+	before := Snapshot()
+	m.Init(re2)
+	assert.Equal(t, before.LiveMatchers, Snapshot().LiveMatchers)
+	assert.True(t, m.MatchString("ab", 0))
+	m.Free()
+	assert.Equal(t, before.LiveMatchers-1, Snapshot().LiveMatchers)
+}
+
+func TestAutoCleanupContexts(t *testing.T) {
+	runtime.GC()
+	before := TotalCBytesAllocated()
+	func() {
+		NewMatchContext()
+		cc := NewCompileContext()
+		cc.SetRecursionGuard(func(uint32) bool { return true })
+		s, err := NewJITStack(32*1024, 64*1024)
+		assert.NoError(t, err)
+		assert.NotNil(t, s)
+	}()
+	assert.True(t, waitFor(func() bool { return TotalCBytesAllocated() <= before }))
+}
+
+func TestNoAutoCleanupContexts(t *testing.T) {
+	SetAutoCleanup(false)
+	defer SetAutoCleanup(true)
+	mc := NewMatchContext()
+	cc := NewCompileContext()
+	s, err := NewJITStack(32*1024, 64*1024)
+	assert.NoError(t, err)
+	assert.Zero(t, mc.cleanup)
+	assert.Zero(t, cc.cleanup)
+	assert.Zero(t, s.cleanup)
+	assert.NoError(t, mc.Free())
+	assert.NoError(t, cc.Free())
+	assert.NoError(t, s.Free())
+}
@@ -0,0 +1,130 @@
+package pcre2
+
+/*
+#define PCRE2_CODE_UNIT_WIDTH 8
+
+#include <pcre2.h>
+*/
+import "C"
+
+import (
+	"unsafe"
+)
+
+// Config returns a numeric build-time setting of the linked PCRE2
+// library, identified by one of the CONFIG_* constants. String settings
+// must be read with ConfigString.
+func Config(what uint32) (uint32, error) {
+	switch what {
+	case CONFIG_VERSION, CONFIG_UNICODE_VERSION, CONFIG_JITTARGET:
+		return 0, &MatchError{ErrorNum: ERROR_BADOPTION, Message: errorMessage(ERROR_BADOPTION)}
+	}
+	var value C.uint32_t
+	if rc := C.pcre2_config(C.uint32_t(what), unsafe.Pointer(&value)); rc < 0 {
+		return 0, &MatchError{ErrorNum: int(rc), Message: errorMessage(int(rc))}
+	}
+	return uint32(value), nil
+}
+
+// ConfigString returns a string setting of the linked PCRE2 library:
+// CONFIG_VERSION, CONFIG_UNICODE_VERSION or CONFIG_JITTARGET.
+func ConfigString(what uint32) (string, error) {
+	switch what {
+	case CONFIG_VERSION, CONFIG_UNICODE_VERSION, CONFIG_JITTARGET:
+	default:
+		return "", &MatchError{ErrorNum: ERROR_BADOPTION, Message: errorMessage(ERROR_BADOPTION)}
+	}
+	// The length includes the terminating zero.
+	n := C.pcre2_config(C.uint32_t(what), nil)
+	if n < 0 {
+		return "", &MatchError{ErrorNum: int(n), Message: errorMessage(int(n))}
+	}
+	buf := make([]byte, n)
+	C.pcre2_config(C.uint32_t(what), unsafe.Pointer(&buf[0]))
+	return string(buf[:n-1]), nil
+}
+
+// configUint32 returns a numeric setting which every library provides.
+func configUint32(what uint32) uint32 {
+	value, _ := Config(what)
+	return value
+}
+
+// configString returns a string setting, or "" if it is unavailable.
+func configString(what uint32) string {
+	value, _ := ConfigString(what)
+	return value
+}
+
+// Version returns the version and release date of the linked library,
+// e.g. "10.42 2022-12-11".
+func Version() string {
+	return configString(CONFIG_VERSION)
+}
+
+// UnicodeVersion returns the Unicode version supported by the library,
+// or "Unicode not supported".
+func UnicodeVersion() string {
+	return configString(CONFIG_UNICODE_VERSION)
+}
+
+// UnicodeSupported reports whether the library supports UTF and UCP.
+func UnicodeSupported() bool {
+	return configUint32(CONFIG_UNICODE) != 0
+}
+
+// JITSupported reports whether the library supports JIT compilation.
+func JITSupported() bool {
+	return configUint32(CONFIG_JIT) != 0
+}
+
+// JITTarget returns the architecture of the JIT compiler, or "" if JIT
+// compilation is not supported.
+func JITTarget() string {
+	if !JITSupported() {
+		return ""
+	}
+	return configString(CONFIG_JITTARGET)
+}
+
+// DefaultMatchLimit returns the match limit of the library, which
+// applies unless set with MatchContext.SetMatchLimit.
+func DefaultMatchLimit() uint32 {
+	return configUint32(CONFIG_MATCHLIMIT)
+}
+
+// DefaultDepthLimit returns the depth limit of the library, which
+// applies unless set with MatchContext.SetDepthLimit.
+func DefaultDepthLimit() uint32 {
+	return configUint32(CONFIG_DEPTHLIMIT)
+}
+
+// DefaultHeapLimit returns the heap limit of the library in KiB, which
+// applies unless set with MatchContext.SetHeapLimit.
+func DefaultHeapLimit() uint32 {
+	return configUint32(CONFIG_HEAPLIMIT)
+}
+
+// DefaultNewline returns the default newline convention of the library,
+// one of the NEWLINE_* constants.
+func DefaultNewline() uint32 {
+	return configUint32(CONFIG_NEWLINE)
+}
+
+// DefaultBSR returns what \R matches by default, BSR_UNICODE or
+// BSR_ANYCRLF.
+func DefaultBSR() uint32 {
+	return configUint32(CONFIG_BSR)
+}
+
+// ParensLimit returns the default maximum nesting depth of parentheses,
+// see CompileContext.SetParensNestLimit.
+func ParensLimit() uint32 {
+	return configUint32(CONFIG_PARENSLIMIT)
+}
+
+// LinkSize returns the number of bytes used for internal links in
+// compiled patterns, which limits their size.
+func LinkSize() uint32 {
+	return configUint32(CONFIG_LINKSIZE)
+}
@@ -0,0 +1,37 @@
+package pcre2
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig(t *testing.T) {
+	assert.True(t, strings.HasPrefix(Version(), "10."), Version())
+	assert.Equal(t, unicodeSupported, UnicodeSupported())
+	if UnicodeSupported() {
+		assert.Regexp(t, `^\d+\.\d+\.\d+$`, UnicodeVersion())
+	}
+	if JITSupported() {
+		assert.NotEmpty(t, JITTarget())
+	} else {
+		assert.Empty(t, JITTarget())
+	}
+	assert.Equal(t, defaultMatchLimit, DefaultMatchLimit())
+	assert.NotZero(t, DefaultDepthLimit())
+	assert.NotZero(t, DefaultHeapLimit())
+	assert.NotZero(t, DefaultNewline())
+	assert.NotZero(t, DefaultBSR())
+	assert.NotZero(t, ParensLimit())
+	assert.Contains(t, []uint32{2, 3, 4}, LinkSize())
+
+	_, err := Config(CONFIG_VERSION)
+	assert.Error(t, err)
+	_, err = ConfigString(CONFIG_MATCHLIMIT)
+	assert.Error(t, err)
+	_, err = Config(9999)
+	if assert.IsType(t, &MatchError{}, err) {
+		assert.Equal(t, ERROR_BADOPTION, err.(*MatchError).ErrorNum)
+	}
+}
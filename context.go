@@ -0,0 +1,325 @@
+package pcre2
+
+/*
+#define PCRE2_CODE_UNIT_WIDTH 8
+
+#include <stdint.h>
+#include <pcre2.h>
+#include "./pcre2_fallback.h"
+
+extern int goRecursionGuard(uint32_t, uintptr_t);
+
+static int myRecursionGuard(uint32_t depth, void *data) {
+	return goRecursionGuard(depth, (uintptr_t) data);
+}
+
+static void mySetRecursionGuard(pcre2_compile_context *ccontext, uintptr_t handle) {
+	if (handle == 0) {
+		pcre2_set_compile_recursion_guard(ccontext, NULL, NULL);
+	} else {
+		pcre2_set_compile_recursion_guard(ccontext, myRecursionGuard, (void *) handle);
+	}
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"runtime"
+	"runtime/cgo"
+	"unsafe"
+)
+
+// MatchContext holds settings for matching beyond the option bits, most
+// importantly resource limits. Without limits, matching an untrusted
+// pattern or subject can take exponential time or large amounts of
+// memory. A MatchContext can be shared by many matchers, but must not
+// be modified while matches using it are running.
+type MatchContext struct {
+	ptr      *C.pcre2_match_context
+	cleanup  runtime.Cleanup
+	jitStack *JITStack // keeps the assigned stack alive
+	// match limit set with SetMatchLimit, or zero for the default
+	matchLimit uint32
+	watchdog   *Watchdog
+	gen        uint64 // incremented by every change, see privateContext
+}
+
+// NewMatchContext creates a match context with default settings.
+func NewMatchContext() *MatchContext {
+	return wrapMatchContext(C.pcre2_match_context_create(generalContext))
+}
+
+func wrapMatchContext(ptr *C.pcre2_match_context) *MatchContext {
+	if ptr == nil {
+		panic(ErrNoMemory)
+	}
+	mc := &MatchContext{ptr: ptr}
+	mc.cleanup = addCleanup(mc, freeMatchContext, ptr)
+	return mc
+}
+
+func freeMatchContext(ptr *C.pcre2_match_context) {
+	C.pcre2_match_context_free(ptr)
+}
+
+// own makes mc owned by the package, which drops it without freeing
+// it: it is freed once garbage collected, even if automatic cleanup is
+// disabled.
+func (mc *MatchContext) own() {
+	mc.cleanup.Stop()
+	mc.cleanup = runtime.AddCleanup(mc, freeMatchContext, mc.ptr)
+}
+
+// Free releases the underlying C resources. The context must not be
+// freed while it is still in use by a Matcher.
+func (mc *MatchContext) Free() error {
+	if mc == nil || mc.ptr == nil {
+		return nil
+	}
+	mc.cleanup.Stop()
+	freeMatchContext(mc.ptr)
+	mc.ptr = nil
+	mc.jitStack = nil
+	return nil
+}
+
+// copy returns an independent copy of the context.
+func (mc *MatchContext) copy() *MatchContext {
+	c := wrapMatchContext(C.pcre2_match_context_copy(mc.ptr))
+	c.jitStack = mc.jitStack
+	c.matchLimit = mc.matchLimit
+	c.watchdog = mc.watchdog
+	return c
+}
+
+// SetMatchLimit limits the number of times the internal match function
+// may be called during a single match, which bounds the running time.
+// When the limit is hit, matching fails with ERROR_MATCHLIMIT.
+func (mc *MatchContext) SetMatchLimit(limit uint32) {
+	C.pcre2_set_match_limit(mc.ptr, C.uint32_t(limit))
+	mc.matchLimit = limit
+	mc.gen++
+}
+
+// SetDepthLimit limits the depth of nested backtracking during a single
+// match. When the limit is hit, matching fails with ERROR_DEPTHLIMIT.
+// Libraries before 10.30 call it the recursion limit.
+func (mc *MatchContext) SetDepthLimit(limit uint32) {
+	if Supports(FeatureDepthLimit) {
+		C.pcre2_set_depth_limit(mc.ptr, C.uint32_t(limit))
+	} else {
+		C.pcre2_set_recursion_limit(mc.ptr, C.uint32_t(limit))
+	}
+	mc.gen++
+}
+
+// SetHeapLimit limits the amount of heap memory, in KiB, which may be
+// used to remember backtracking positions during a single match.
+// When the limit is hit, matching fails with ERROR_HEAPLIMIT. Without
+// FeatureHeapLimit, this has no effect.
+func (mc *MatchContext) SetHeapLimit(kib uint32) {
+	if Supports(FeatureHeapLimit) {
+		C.pcre2_set_heap_limit(mc.ptr, C.uint32_t(kib))
+	}
+	mc.gen++
+}
+
+// SetJITStack assigns a JIT stack which is used by JIT matches with this
+// context. A nil stack restores the default stack.
+func (mc *MatchContext) SetJITStack(s *JITStack) {
+	if s == nil {
+		C.pcre2_jit_stack_assign(mc.ptr, nil, nil)
+	} else {
+		C.pcre2_jit_stack_assign(mc.ptr, nil, unsafe.Pointer(s.ptr))
+	}
+	mc.jitStack = s
+	mc.gen++
+}
+
+// pointer returns the C context to pass to the match functions, or nil.
+func (mc *MatchContext) pointer() *C.pcre2_match_context {
+	if mc == nil {
+		return nil
+	}
+	return mc.ptr
+}
+
+// SetMatchContext makes all subsequent matches of m use the settings of
+// mc. A nil context restores the defaults. This replaces any JIT stack
+// set with SetJITStack.
+func (m *Matcher) SetMatchContext(mc *MatchContext) {
+	m.mctx = mc
+	m.jitStack = nil
+}
+
+// privateContext returns a match context which belongs to m alone, and
+// can therefore be modified for its own purposes, e.g. to install a
+// callout for a single match. It holds the settings of the shared
+// context, i.e. the one set with SetMatchContext or MatchOptions, or
+// the package default, with those of m, such as its JIT stack, layered
+// on top. The copy is kept for later matches until the shared context
+// is replaced or modified.
+func (m *Matcher) privateContext() *MatchContext {
+	base := m.sharedContext()
+	if m.private == nil || !m.privateCurrent(base) {
+		m.private.Free()
+		if base != nil {
+			m.private = base.copy()
+			m.privateGen = base.gen
+		} else {
+			m.private = NewMatchContext()
+			m.privateGen = 0
+		}
+		m.privateBase = base
+		if m.jitStack != nil {
+			m.private.SetJITStack(m.jitStack)
+		}
+	}
+	return m.private
+}
+
+// privateCurrent reports whether the private context of m is a copy of
+// base in its current state.
+func (m *Matcher) privateCurrent(base *MatchContext) bool {
+	if m.privateBase != base {
+		return false
+	}
+	return base == nil || m.privateGen == base.gen
+}
+
+// CompileContext holds settings for compiling patterns beyond the option
+// bits, such as the newline convention. A CompileContext can be reused
+// for any number of compilations.
+type CompileContext struct {
+	ptr              *C.pcre2_compile_context
+	res              *compileResources
+	cleanup          runtime.Cleanup
+	maxPatternLength int
+}
+
+// compileResources holds the resources of a CompileContext. Like
+// regexpCode, it is separate so that the cleanup can free them without
+// keeping the CompileContext reachable.
+type compileResources struct {
+	ptr    *C.pcre2_compile_context
+	guard  cgo.Handle // recursion guard, or zero
+	tables *Tables    // character tables, or nil
+}
+
+func (r *compileResources) free() {
+	C.pcre2_compile_context_free(r.ptr)
+	r.ptr = nil
+	if r.guard != 0 {
+		r.guard.Delete()
+		r.guard = 0
+	}
+	r.tables.release()
+	r.tables = nil
+}
+
+// NewCompileContext creates a compile context with default settings.
+func NewCompileContext() *CompileContext {
+	ptr := C.pcre2_compile_context_create(generalContext)
+	if ptr == nil {
+		panic(ErrNoMemory)
+	}
+	cc := &CompileContext{ptr: ptr, res: &compileResources{ptr: ptr}}
+	cc.cleanup = addCleanup(cc, (*compileResources).free, cc.res)
+	return cc
+}
+
+// Free releases the underlying C resources.
+func (cc *CompileContext) Free() error {
+	if cc == nil || cc.ptr == nil {
+		return nil
+	}
+	cc.cleanup.Stop()
+	cc.res.free()
+	cc.ptr = nil
+	return nil
+}
+
+// SetNewline sets the character sequence which is recognized as a
+// newline, e.g. by ^, $ and dot. It must be one of the NEWLINE_*
+// constants.
+func (cc *CompileContext) SetNewline(newline uint32) error {
+	if C.pcre2_set_newline(cc.ptr, C.uint32_t(newline)) != 0 {
+		return errors.New("invalid newline convention")
+	}
+	return nil
+}
+
+// SetBSR sets the characters which are matched by \R, either
+// BSR_UNICODE (any Unicode line ending) or BSR_ANYCRLF (CR, LF or CRLF).
+func (cc *CompileContext) SetBSR(bsr uint32) error {
+	if C.pcre2_set_bsr(cc.ptr, C.uint32_t(bsr)) != 0 {
+		return errors.New("invalid \\R convention")
+	}
+	return nil
+}
+
+// SetExtraOptions sets the additional compile options which do not fit
+// in the flags word, i.e. a combination of the EXTRA_* constants.
+// Without FeatureExtraOptions, this has no effect.
+func (cc *CompileContext) SetExtraOptions(options uint32) {
+	if Supports(FeatureExtraOptions) {
+		C.pcre2_set_compile_extra_options(cc.ptr, C.uint32_t(options))
+	}
+}
+
+// SetMaxPatternLength limits the length of patterns, in bytes. Longer
+// patterns are rejected with a *CompileError whose ErrorNum is
+// ERROR_PATTERN_STRING_TOO_LONG, before any parsing takes place. This
+// is useful for services which compile user-supplied patterns.
+// Zero removes the limit.
+func (cc *CompileContext) SetMaxPatternLength(n int) {
+	if n <= 0 {
+		n = 0
+		C.pcre2_set_max_pattern_length(cc.ptr, C.PCRE2_UNSET)
+	} else {
+		C.pcre2_set_max_pattern_length(cc.ptr, C.PCRE2_SIZE(n))
+	}
+	cc.maxPatternLength = n
+}
+
+// SetParensNestLimit limits the depth of nested parentheses in patterns,
+// which protects the compiler stack against deeply nested user patterns.
+// Patterns exceeding the limit fail with ERROR_PARENTHESES_NEST_TOO_DEEP.
+func (cc *CompileContext) SetParensNestLimit(limit uint32) {
+	C.pcre2_set_parens_nest_limit(cc.ptr, C.uint32_t(limit))
+}
+
+// RecursionGuard is called by the compiler whenever it starts to compile
+// a parenthesized part of a pattern, with the current nesting depth.
+// Returning false aborts the compilation with
+// ERROR_PARENTHESES_STACK_CHECK.
+type RecursionGuard func(depth uint32) bool
+
+// SetRecursionGuard installs a guard which can abort compilations
+// according to a policy of the application, e.g. when the nesting depth
+// of a pattern becomes too large. A nil guard removes it.
+func (cc *CompileContext) SetRecursionGuard(guard RecursionGuard) {
+	old := cc.res.guard
+	cc.res.guard = 0
+	if guard != nil {
+		cc.res.guard = cgo.NewHandle(guard)
+	}
+	C.mySetRecursionGuard(cc.ptr, C.uintptr_t(cc.res.guard))
+	if old != 0 {
+		old.Delete()
+	}
+}
+
+func (cc *CompileContext) patternTooLong(length int) bool {
+	return cc != nil && cc.maxPatternLength > 0 && length > cc.maxPatternLength
+}
+
+// pointer returns the C context to pass to the compile function, which
+// is the package default for a nil context.
+func (cc *CompileContext) pointer() *C.pcre2_compile_context {
+	if cc == nil {
+		return defaultCompileContext
+	}
+	return cc.ptr
+}
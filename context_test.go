@@ -0,0 +1,223 @@
+package pcre2
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchContextLimits(t *testing.T) {
+	re := MustCompile(`^(a|aa)+$`, NO_JIT)
+	subject := append(bytes.Repeat([]byte("a"), 40), 'b')
+	m := re.NewMatcher()
+
+	mc := NewMatchContext()
+	defer mc.Free()
+	mc.SetMatchLimit(1000)
+	m.SetMatchContext(mc)
+	assert.Equal(t, ERROR_MATCHLIMIT, m.Exec(subject, 0))
+
+	mc = NewMatchContext()
+	mc.SetDepthLimit(10)
+	m.SetMatchContext(mc)
+	assert.Equal(t, ERROR_DEPTHLIMIT, m.Exec(subject, 0))
+
+	mc = NewMatchContext()
+	mc.SetHeapLimit(1)
+	m.SetMatchContext(mc)
+	assert.Equal(t, ERROR_HEAPLIMIT, m.Exec(bytes.Repeat([]byte("a"), 10000), 0))
+
+	m.SetMatchContext(nil)
+	assert.True(t, m.Match([]byte("aaaa"), 0))
+}
+
+func TestMatchContextShared(t *testing.T) {
+	mc := NewMatchContext()
+	defer mc.Free()
+	mc.SetMatchLimit(1000)
+
+	m := MustCompile(`a`, 0).NewMatcher()
+	m.SetMatchContext(mc)
+	assert.True(t, m.mctx == mc)
+
+	// Modifications for the matcher itself do not affect the shared context.
+	m.SetJITStack(nil)
+	s, err := NewJITStack(32*1024, 64*1024)
+	if !assert.NoError(t, err) {
+		return
+	}
+	m.SetJITStack(s)
+	assert.True(t, m.mctx == mc)
+	assert.Nil(t, mc.jitStack)
+	assert.True(t, m.MatchString("a", 0))
+	assert.False(t, m.matchContext() == mc)
+	assert.True(t, m.matchContext().jitStack == s)
+}
+
+func TestPrivateContextFollowsShared(t *testing.T) {
+	re := MustCompile(`^(a|aa)+$`, NO_JIT)
+	defer re.Free()
+	subject := append(bytes.Repeat([]byte("a"), 20), 'b')
+	m := re.NewMatcher()
+	defer m.Free()
+	m.SetCallout(func(*CalloutBlock) int { return 0 })
+
+	mc := NewMatchContext()
+	defer mc.Free()
+	m.SetMatchContext(mc)
+	assert.Equal(t, ERROR_NOMATCH, m.Exec(subject, 0))
+
+	// Changes of the shared context after the private copy are seen.
+	mc.SetMatchLimit(1000)
+	assert.Equal(t, ERROR_MATCHLIMIT, m.Exec(subject, 0))
+
+	m.SetMatchContext(nil)
+	assert.Equal(t, ERROR_NOMATCH, m.Exec(subject, 0))
+	SetDefaultLimits(1000, 0, 0)
+	defer SetDefaultLimits(0, 0, 0)
+	assert.Equal(t, ERROR_MATCHLIMIT, m.Exec(subject, 0))
+
+	// The copy is reused while the shared context is unchanged.
+	opts := MatchOptions{Context: mc, Timeout: time.Minute}
+	assert.Equal(t, ERROR_MATCHLIMIT, m.ExecOptions(subject, opts))
+	private := m.private
+	assert.Equal(t, ERROR_MATCHLIMIT, m.ExecOptions(subject, opts))
+	assert.True(t, m.private == private)
+}
+
+func TestCompileContextNewline(t *testing.T) {
+	cc := NewCompileContext()
+	defer cc.Free()
+
+	re, err := CompileWithContext(`^b$`, MULTILINE, cc)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, uint32(NEWLINE_LF), re.Newline())
+	assert.Nil(t, re.FindStringIndex("a\r\nb\r\n", 0))
+
+	assert.NoError(t, cc.SetNewline(NEWLINE_CRLF))
+	re, err = CompileWithContext(`^b$`, MULTILINE, cc)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, uint32(NEWLINE_CRLF), re.Newline())
+	assert.Equal(t, []int{3, 4}, re.FindStringIndex("a\r\nb\r\n", 0))
+
+	assert.NoError(t, cc.SetNewline(NEWLINE_NUL))
+	re, err = CompileWithContext(`^b$`, MULTILINE, cc)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []int{2, 3}, re.FindStringIndex("a\x00b\x00", 0))
+	}
+
+	assert.Error(t, cc.SetNewline(42))
+}
+
+func TestCompileContextBSR(t *testing.T) {
+	cc := NewCompileContext()
+	defer cc.Free()
+
+	re, err := CompileWithContext(`a\Rb`, 0, cc)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, uint32(BSR_UNICODE), re.BSR())
+	assert.True(t, re.MatcherString("a\x0bb", 0).Matches())
+
+	assert.NoError(t, cc.SetBSR(BSR_ANYCRLF))
+	re, err = CompileWithContext(`a\Rb`, 0, cc)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, uint32(BSR_ANYCRLF), re.BSR())
+	assert.False(t, re.MatcherString("a\x0bb", 0).Matches())
+	assert.True(t, re.MatcherString("a\r\nb", 0).Matches())
+
+	assert.Error(t, cc.SetBSR(42))
+}
+
+func TestCompileContextExtraOptions(t *testing.T) {
+	cc := NewCompileContext()
+	defer cc.Free()
+
+	cc.SetExtraOptions(EXTRA_MATCH_WORD)
+	re, err := CompileWithContext(`cat`, 0, cc)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.False(t, re.MatcherString("concatenate", 0).Matches())
+	assert.Equal(t, "cat", re.FindString("a cat!", 0))
+
+	cc.SetExtraOptions(EXTRA_MATCH_LINE | EXTRA_BAD_ESCAPE_IS_LITERAL)
+	re, err = CompileWithContext(`a\jb`, MULTILINE, cc)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "ajb", re.FindString("xajb\najb\n", 0))
+	assert.Equal(t, []int{5, 8}, re.FindStringIndex("xajb\najb\n", 0))
+
+	cc.SetExtraOptions(0)
+	_, err = CompileWithContext(`a\jb`, 0, cc)
+	assert.Error(t, err)
+}
+
+func TestCompileContextMaxPatternLength(t *testing.T) {
+	cc := NewCompileContext()
+	defer cc.Free()
+	cc.SetMaxPatternLength(5)
+
+	_, err := CompileWithContext(`abcde`, 0, cc)
+	assert.NoError(t, err)
+	_, err = CompileWithContext(`abcdef`, 0, cc)
+	if assert.IsType(t, &CompileError{}, err) {
+		assert.Equal(t, ERROR_PATTERN_STRING_TOO_LONG, err.(*CompileError).ErrorNum)
+	}
+
+	cc.SetMaxPatternLength(0)
+	_, err = CompileWithContext(`abcdef`, 0, cc)
+	assert.NoError(t, err)
+
+	_, err = Compile(`a(`, 0)
+	if assert.IsType(t, &CompileError{}, err) {
+		assert.Equal(t, ERROR_MISSING_CLOSING_PARENTHESIS, err.(*CompileError).ErrorNum)
+	}
+}
+
+func TestCompileContextParensNestLimit(t *testing.T) {
+	cc := NewCompileContext()
+	defer cc.Free()
+	cc.SetParensNestLimit(3)
+
+	_, err := CompileWithContext(`((()))`, 0, cc)
+	assert.NoError(t, err)
+	_, err = CompileWithContext(`(((())))`, 0, cc)
+	if assert.IsType(t, &CompileError{}, err) {
+		assert.Equal(t, ERROR_PARENTHESES_NEST_TOO_DEEP, err.(*CompileError).ErrorNum)
+	}
+}
+
+func TestCompileContextRecursionGuard(t *testing.T) {
+	cc := NewCompileContext()
+	defer cc.Free()
+	var maxDepth uint32
+	cc.SetRecursionGuard(func(depth uint32) bool {
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+		return depth <= 3
+	})
+
+	_, err := CompileWithContext(`(a(b))`, 0, cc)
+	assert.NoError(t, err)
+	assert.NotZero(t, maxDepth)
+	_, err = CompileWithContext(`(((((a)))))`, 0, cc)
+	if assert.IsType(t, &CompileError{}, err) {
+		assert.Equal(t, ERROR_PARENTHESES_STACK_CHECK, err.(*CompileError).ErrorNum)
+	}
+
+	cc.SetRecursionGuard(nil)
+	_, err = CompileWithContext(`(((((a)))))`, 0, cc)
+	assert.NoError(t, err)
+}
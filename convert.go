@@ -0,0 +1,159 @@
+package pcre2
+
+/*
+#define PCRE2_CODE_UNIT_WIDTH 8
+
+#include <stdlib.h>
+#include <pcre2.h>
+#include "./pcre2_fallback.h"
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// ConvertError is returned when a foreign pattern cannot be converted
+// to a PCRE2 pattern.
+type ConvertError struct {
+	Pattern  string // The failed pattern
+	Message  string // The error message
+	Offset   int    // Byte position of error
+	ErrorNum int    // The PCRE2 error number
+}
+
+// Error converts a conversion error to a string.
+func (e *ConvertError) Error() string {
+	return fmt.Sprintf("PCRE2 pattern conversion failed at offset %d: %s", e.Offset, e.Message)
+}
+
+// convert translates a glob or POSIX pattern into a PCRE2 pattern.
+// options must contain one of the CONVERT_GLOB or CONVERT_POSIX_*
+// constants.
+func convert(pattern string, options uint32, cvcontext *C.pcre2_convert_context) (string, error) {
+	if err := Require(FeatureConvert); err != nil {
+		return "", err
+	}
+	pattern1 := C.CString(pattern)
+	defer C.free(unsafe.Pointer(pattern1))
+	var buffer *C.PCRE2_UCHAR
+	var blength C.PCRE2_SIZE
+	rc := C.pcre2_pattern_convert(
+		C.PCRE2_SPTR(unsafe.Pointer(pattern1)),
+		C.PCRE2_SIZE(len(pattern)),
+		C.uint32_t(options),
+		&buffer,
+		&blength,
+		cvcontext,
+	)
+	if rc != 0 {
+		return "", &ConvertError{
+			Pattern:  pattern,
+			Message:  errorMessage(int(rc)),
+			Offset:   int(blength),
+			ErrorNum: int(rc),
+		}
+	}
+	defer C.pcre2_converted_pattern_free(buffer)
+	return C.GoStringN((*C.char)(unsafe.Pointer(buffer)), C.int(blength)), nil
+}
+
+// ConvertGlob converts a shell glob into an equivalent PCRE2 pattern.
+// opts may hold CONVERT_UTF, CONVERT_NO_UTF_CHECK,
+// CONVERT_GLOB_NO_WILD_SEPARATOR and CONVERT_GLOB_NO_STARSTAR. By
+// default wildcards do not match the separator '/', and ** matches any
+// number of path components.
+func ConvertGlob(glob string, opts uint32) (string, error) {
+	return convert(glob, CONVERT_GLOB|opts, nil)
+}
+
+// CompileGlob converts a shell glob, see ConvertGlob, and compiles the
+// result with the given compile flags.
+func CompileGlob(glob string, opts, flags uint32) (*Regexp, error) {
+	pattern, err := ConvertGlob(glob, opts)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(pattern, flags)
+}
+
+// ConvertOptions holds the settings of a glob conversion beyond the
+// option bits. Zero values keep the defaults.
+type ConvertOptions struct {
+	Options uint32 // CONVERT_* option bits, CONVERT_GLOB is implied
+	// Separator is the path separator, which wildcards do not match:
+	// '/' (the default), '\\' or '.'.
+	Separator rune
+	// Escape is the character escaping wildcards, by default a
+	// backslash. It must be a punctuation character. With a backslash
+	// as separator, another escape character has to be chosen, or
+	// NoEscape set.
+	Escape   rune
+	NoEscape bool // disables escaping entirely
+}
+
+// convertContext returns a convert context holding the settings of
+// opts, or nil if none of them differs from the defaults. A non-nil
+// context must be freed with pcre2_convert_context_free.
+func (opts *ConvertOptions) convertContext() (*C.pcre2_convert_context, error) {
+	if opts.Separator == 0 && opts.Escape == 0 && !opts.NoEscape {
+		return nil, nil
+	}
+	if err := Require(FeatureConvert); err != nil {
+		return nil, err
+	}
+	ctx := C.pcre2_convert_context_create(generalContext)
+	if ctx == nil {
+		return nil, ErrNoMemory
+	}
+	escape := opts.Escape
+	switch {
+	case opts.NoEscape:
+		escape = 0
+	case escape == 0 && opts.Separator == '\\':
+		// The default escape character would clash.
+		C.pcre2_convert_context_free(ctx)
+		return nil, errors.New("a backslash separator needs another escape character")
+	}
+	if opts.Escape != 0 || opts.NoEscape {
+		if C.pcre2_set_glob_escape(ctx, C.uint32_t(escape)) != 0 {
+			C.pcre2_convert_context_free(ctx)
+			return nil, fmt.Errorf("invalid glob escape character %q", escape)
+		}
+	}
+	if opts.Separator != 0 {
+		if C.pcre2_set_glob_separator(ctx, C.uint32_t(opts.Separator)) != 0 {
+			C.pcre2_convert_context_free(ctx)
+			return nil, fmt.Errorf("invalid glob separator %q", opts.Separator)
+		}
+	}
+	return ctx, nil
+}
+
+// ConvertGlobWithOptions is like ConvertGlob, but with the settings of
+// opts, e.g. for Windows paths:
+//
+//	ConvertGlobWithOptions(`C:\src\*.go`, ConvertOptions{Separator: '\\', NoEscape: true})
+func ConvertGlobWithOptions(glob string, opts ConvertOptions) (string, error) {
+	ctx, err := opts.convertContext()
+	if err != nil {
+		return "", err
+	}
+	if ctx != nil {
+		defer C.pcre2_convert_context_free(ctx)
+	}
+	return convert(glob, CONVERT_GLOB|opts.Options, ctx)
+}
+
+// CompileGlobWithOptions converts a shell glob, see
+// ConvertGlobWithOptions, and compiles the result with the given
+// compile flags.
+func CompileGlobWithOptions(glob string, opts ConvertOptions, flags uint32) (*Regexp, error) {
+	pattern, err := ConvertGlobWithOptions(glob, opts)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(pattern, flags)
+}
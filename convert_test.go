@@ -0,0 +1,62 @@
+package pcre2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertGlob(t *testing.T) {
+	pattern, err := ConvertGlob("*.go", 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NotEmpty(t, pattern)
+
+	_, err = ConvertGlob("a[", 0)
+	if assert.IsType(t, &ConvertError{}, err) {
+		assert.Equal(t, ERROR_MISSING_SQUARE_BRACKET, err.(*ConvertError).ErrorNum)
+	}
+}
+
+func TestCompileGlob(t *testing.T) {
+	check := func(glob, subject string, opts uint32, want bool) {
+		re, err := CompileGlob(glob, opts, 0)
+		if !assert.NoError(t, err, glob) {
+			return
+		}
+		defer re.Free()
+		assert.Equal(t, want, re.MatcherString(subject, 0).Matches(), "%s ~ %s", glob, subject)
+	}
+	check("*.go", "pcre.go", 0, true)
+	check("*.go", "pcre.c", 0, false)
+	check("*.go", "dir/pcre.go", 0, false)
+	check("*.go", "dir/pcre.go", CONVERT_GLOB_NO_WILD_SEPARATOR, true)
+	check("**/*.go", "a/b/pcre.go", 0, true)
+	check("file?.[ch]", "file1.h", 0, true)
+}
+
+func TestConvertGlobWithOptions(t *testing.T) {
+	windows := ConvertOptions{Separator: '\\', NoEscape: true}
+	re, err := CompileGlobWithOptions(`C:\src\*.go`, windows, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer re.Free()
+	assert.True(t, re.MatcherString(`C:\src\pcre.go`, 0).Matches())
+	assert.False(t, re.MatcherString(`C:\src\sub\pcre.go`, 0).Matches())
+
+	re, err = CompileGlobWithOptions(`a^*b`, ConvertOptions{Escape: '^'}, 0)
+	if assert.NoError(t, err) {
+		defer re.Free()
+		assert.True(t, re.MatcherString("a*b", 0).Matches())
+		assert.False(t, re.MatcherString("axb", 0).Matches())
+	}
+
+	_, err = ConvertGlobWithOptions(`*`, ConvertOptions{Separator: '\\'})
+	assert.Error(t, err)
+	_, err = ConvertGlobWithOptions(`*`, ConvertOptions{Separator: 'x'})
+	assert.Error(t, err)
+	_, err = ConvertGlobWithOptions(`*`, ConvertOptions{Escape: 'x'})
+	assert.Error(t, err)
+}
@@ -0,0 +1,71 @@
+package pcre2
+
+/*
+#include <stdlib.h>
+
+// myClearFree overwrites the buffer before freeing it. The writes are
+// volatile, so that the compiler does not drop them as dead stores.
+static void myClearFree(void *buf, size_t n) {
+	volatile unsigned char *p = buf;
+	while (n-- > 0) {
+		*p++ = 0;
+	}
+	free(buf);
+}
+*/
+import "C"
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+var copySubjects atomic.Bool
+
+// SetCopySubjects controls whether string subjects are copied into C
+// memory for matching, for all Matchers which do not set their own mode.
+// By default PCRE2 reads Go strings in place, which avoids a copy but
+// hands Go memory to C. Copying costs an allocation per match, in
+// exchange for C never seeing Go pointers, e.g. for debugging with
+// checkptr or GODEBUG=cgocheck=2. Results are the same in either mode.
+func SetCopySubjects(enabled bool) {
+	copySubjects.Store(enabled)
+}
+
+// Values of Matcher.copySubjects.
+const (
+	copyDefault int8 = iota // use the package setting
+	copyAlways
+	copyNever
+)
+
+// SetCopySubjects overrides the package setting of SetCopySubjects for
+// this matcher.
+func (m *Matcher) SetCopySubjects(enabled bool) {
+	if enabled {
+		m.copySubjects = copyAlways
+	} else {
+		m.copySubjects = copyNever
+	}
+}
+
+// copiesSubjects reports whether m copies string subjects.
+func (m *Matcher) copiesSubjects() bool {
+	if m.copySubjects == copyDefault {
+		return copySubjects.Load()
+	}
+	return m.copySubjects == copyAlways
+}
+
+// execCopy is like exec, but matches a copy of the subject in C memory.
+// The copy is cleared before it is freed, so that no trace of the
+// subject is left behind, see Zeroize.
+func (m *Matcher) execCopy(subject string, offset int, flags uint32) int {
+	buf := (*C.char)(C.malloc(C.size_t(len(subject))))
+	if buf == nil {
+		return ERROR_NOMEMORY
+	}
+	defer C.myClearFree(unsafe.Pointer(buf), C.size_t(len(subject)))
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(buf)), len(subject)), subject)
+	return m.exec(buf, len(subject), offset, flags)
+}
@@ -0,0 +1,33 @@
+package pcre2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopySubjects(t *testing.T) {
+	re := MustCompile(`(b+)(c)?`, 0)
+	defer re.Free()
+	m := re.NewMatcher()
+	defer m.Free()
+	assert.False(t, m.copiesSubjects())
+
+	m.SetCopySubjects(true)
+	assert.True(t, m.MatchString("abbd", 0))
+	assert.Equal(t, "bb", m.GroupString(1))
+	assert.Equal(t, []int{1, 3}, m.Index())
+	assert.False(t, m.MatchString("", 0))
+	assert.False(t, m.MatchString("xyz", 0))
+	assert.True(t, m.Match([]byte("bc"), 0))
+	assert.Equal(t, "c", m.GroupString(2))
+
+	SetCopySubjects(true)
+	defer SetCopySubjects(false)
+	m2 := re.NewMatcher()
+	defer m2.Free()
+	assert.True(t, m2.copiesSubjects())
+	m2.SetCopySubjects(false)
+	assert.False(t, m2.copiesSubjects())
+	assert.True(t, m2.MatchString("b", 0))
+}
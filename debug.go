@@ -0,0 +1,63 @@
+package pcre2
+
+import (
+	"fmt"
+	"strings"
+)
+
+// String returns a dump of the state of the Matcher for logs and test
+// failures: the pattern, the return code of the last match and what it
+// means, and the offsets and text of every capture group, e.g.
+//
+//	Matcher{pattern: "(a)(x)?", rc: 2 (match), groups: [0:[1,2]"a" 1:[1,2]"a" 2:unset]}
+//
+// Partial matches only report group 0.
+func (m *Matcher) String() string {
+	if m == nil {
+		return "Matcher(nil)"
+	}
+	if m.re == nil {
+		return "Matcher{}"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Matcher{pattern: %q, ", m.re.Pattern)
+	if m.mData == nil {
+		b.WriteString("freed}")
+		return b.String()
+	}
+	switch {
+	case m.partial:
+		b.WriteString("rc: partial match")
+	case m.matches && m.rc == 0:
+		b.WriteString("rc: 0 (match, ovector too small)")
+	case m.matches:
+		fmt.Fprintf(&b, "rc: %d (match)", m.rc)
+	case m.rc == 0:
+		b.WriteString("rc: 0 (no match attempted)")
+	default:
+		fmt.Fprintf(&b, "rc: %d (%v)", m.rc, m.GetError())
+	}
+	if m.matches {
+		groups := m.groups
+		if m.partial {
+			groups = 0
+		}
+		b.WriteString(", groups: [")
+		for i := 0; i <= groups; i++ {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			if start, end, ok := m.mData.offsets(i); ok && start <= end {
+				fmt.Fprintf(&b, "%d:[%d,%d]%q", i, start, end, m.GroupString(i))
+			} else if ok {
+				// \K set the start after the end.
+				fmt.Fprintf(&b, "%d:[%d,%d]", i, start, end)
+			} else {
+				fmt.Fprintf(&b, "%d:unset", i)
+			}
+		}
+		b.WriteByte(']')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
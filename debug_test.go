@@ -0,0 +1,38 @@
+package pcre2
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatcherString(t *testing.T) {
+	re := MustCompile(`(a)(x)?`, 0)
+	m := re.NewMatcher()
+	assert.Equal(t, `Matcher{pattern: "(a)(x)?", rc: 0 (no match attempted)}`, m.String())
+
+	m.MatchString("ba", 0)
+	assert.Equal(t, `Matcher{pattern: "(a)(x)?", rc: 2 (match), groups: [0:[1,2]"a" 1:[1,2]"a" 2:unset]}`, m.String())
+	assert.Equal(t, m.String(), fmt.Sprint(m))
+
+	m.MatchString("b", 0)
+	assert.Equal(t, `Matcher{pattern: "(a)(x)?", rc: -1 (Matching failed: no match)}`, m.String())
+
+	re2 := MustCompile(`abc`, 0)
+	defer re2.Free()
+	m.Init(re2)
+	m.MatchString("xab", PARTIAL_HARD)
+	assert.Equal(t, `Matcher{pattern: "abc", rc: partial match, groups: [0:[1,3]"ab"]}`, m.String())
+
+	re2.SetMaxSubjectLength(2)
+	m.MatchString("abc", 0)
+	assert.Equal(t, `Matcher{pattern: "abc", rc: -1001 (subject exceeds maximum length)}`, m.String())
+
+	m.Free()
+	assert.Equal(t, `Matcher{pattern: "abc", freed}`, m.String())
+	re.Free()
+
+	var nilMatcher *Matcher
+	assert.Equal(t, "Matcher(nil)", nilMatcher.String())
+}
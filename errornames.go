@@ -0,0 +1,202 @@
+package pcre2
+
+import (
+	"strconv"
+	"sync"
+)
+
+// errorCodeNames lists the error codes with their symbolic names. Codes
+// missing from old headers are zero, see pcre2_fallback.h, and synonyms
+// share a code; the first name listed for a code is used.
+var errorCodeNames = []struct {
+	code int
+	name string
+}{
+	{ERROR_END_BACKSLASH, "END_BACKSLASH"},
+	{ERROR_END_BACKSLASH_C, "END_BACKSLASH_C"},
+	{ERROR_UNKNOWN_ESCAPE, "UNKNOWN_ESCAPE"},
+	{ERROR_QUANTIFIER_OUT_OF_ORDER, "QUANTIFIER_OUT_OF_ORDER"},
+	{ERROR_QUANTIFIER_TOO_BIG, "QUANTIFIER_TOO_BIG"},
+	{ERROR_MISSING_SQUARE_BRACKET, "MISSING_SQUARE_BRACKET"},
+	{ERROR_ESCAPE_INVALID_IN_CLASS, "ESCAPE_INVALID_IN_CLASS"},
+	{ERROR_CLASS_RANGE_ORDER, "CLASS_RANGE_ORDER"},
+	{ERROR_QUANTIFIER_INVALID, "QUANTIFIER_INVALID"},
+	{ERROR_INTERNAL_UNEXPECTED_REPEAT, "INTERNAL_UNEXPECTED_REPEAT"},
+	{ERROR_INVALID_AFTER_PARENS_QUERY, "INVALID_AFTER_PARENS_QUERY"},
+	{ERROR_POSIX_CLASS_NOT_IN_CLASS, "POSIX_CLASS_NOT_IN_CLASS"},
+	{ERROR_POSIX_NO_SUPPORT_COLLATING, "POSIX_NO_SUPPORT_COLLATING"},
+	{ERROR_MISSING_CLOSING_PARENTHESIS, "MISSING_CLOSING_PARENTHESIS"},
+	{ERROR_BAD_SUBPATTERN_REFERENCE, "BAD_SUBPATTERN_REFERENCE"},
+	{ERROR_NULL_PATTERN, "NULL_PATTERN"},
+	{ERROR_BAD_OPTIONS, "BAD_OPTIONS"},
+	{ERROR_MISSING_COMMENT_CLOSING, "MISSING_COMMENT_CLOSING"},
+	{ERROR_PARENTHESES_NEST_TOO_DEEP, "PARENTHESES_NEST_TOO_DEEP"},
+	{ERROR_PATTERN_TOO_LARGE, "PATTERN_TOO_LARGE"},
+	{ERROR_HEAP_FAILED, "HEAP_FAILED"},
+	{ERROR_UNMATCHED_CLOSING_PARENTHESIS, "UNMATCHED_CLOSING_PARENTHESIS"},
+	{ERROR_INTERNAL_CODE_OVERFLOW, "INTERNAL_CODE_OVERFLOW"},
+	{ERROR_MISSING_CONDITION_CLOSING, "MISSING_CONDITION_CLOSING"},
+	{ERROR_LOOKBEHIND_NOT_FIXED_LENGTH, "LOOKBEHIND_NOT_FIXED_LENGTH"},
+	{ERROR_ZERO_RELATIVE_REFERENCE, "ZERO_RELATIVE_REFERENCE"},
+	{ERROR_TOO_MANY_CONDITION_BRANCHES, "TOO_MANY_CONDITION_BRANCHES"},
+	{ERROR_CONDITION_ASSERTION_EXPECTED, "CONDITION_ASSERTION_EXPECTED"},
+	{ERROR_BAD_RELATIVE_REFERENCE, "BAD_RELATIVE_REFERENCE"},
+	{ERROR_UNKNOWN_POSIX_CLASS, "UNKNOWN_POSIX_CLASS"},
+	{ERROR_INTERNAL_STUDY_ERROR, "INTERNAL_STUDY_ERROR"},
+	{ERROR_UNICODE_NOT_SUPPORTED, "UNICODE_NOT_SUPPORTED"},
+	{ERROR_PARENTHESES_STACK_CHECK, "PARENTHESES_STACK_CHECK"},
+	{ERROR_CODE_POINT_TOO_BIG, "CODE_POINT_TOO_BIG"},
+	{ERROR_LOOKBEHIND_TOO_COMPLICATED, "LOOKBEHIND_TOO_COMPLICATED"},
+	{ERROR_LOOKBEHIND_INVALID_BACKSLASH_C, "LOOKBEHIND_INVALID_BACKSLASH_C"},
+	{ERROR_UNSUPPORTED_ESCAPE_SEQUENCE, "UNSUPPORTED_ESCAPE_SEQUENCE"},
+	{ERROR_CALLOUT_NUMBER_TOO_BIG, "CALLOUT_NUMBER_TOO_BIG"},
+	{ERROR_MISSING_CALLOUT_CLOSING, "MISSING_CALLOUT_CLOSING"},
+	{ERROR_ESCAPE_INVALID_IN_VERB, "ESCAPE_INVALID_IN_VERB"},
+	{ERROR_UNRECOGNIZED_AFTER_QUERY_P, "UNRECOGNIZED_AFTER_QUERY_P"},
+	{ERROR_MISSING_NAME_TERMINATOR, "MISSING_NAME_TERMINATOR"},
+	{ERROR_DUPLICATE_SUBPATTERN_NAME, "DUPLICATE_SUBPATTERN_NAME"},
+	{ERROR_INVALID_SUBPATTERN_NAME, "INVALID_SUBPATTERN_NAME"},
+	{ERROR_UNICODE_PROPERTIES_UNAVAILABLE, "UNICODE_PROPERTIES_UNAVAILABLE"},
+	{ERROR_MALFORMED_UNICODE_PROPERTY, "MALFORMED_UNICODE_PROPERTY"},
+	{ERROR_UNKNOWN_UNICODE_PROPERTY, "UNKNOWN_UNICODE_PROPERTY"},
+	{ERROR_SUBPATTERN_NAME_TOO_LONG, "SUBPATTERN_NAME_TOO_LONG"},
+	{ERROR_TOO_MANY_NAMED_SUBPATTERNS, "TOO_MANY_NAMED_SUBPATTERNS"},
+	{ERROR_CLASS_INVALID_RANGE, "CLASS_INVALID_RANGE"},
+	{ERROR_OCTAL_BYTE_TOO_BIG, "OCTAL_BYTE_TOO_BIG"},
+	{ERROR_INTERNAL_OVERRAN_WORKSPACE, "INTERNAL_OVERRAN_WORKSPACE"},
+	{ERROR_INTERNAL_MISSING_SUBPATTERN, "INTERNAL_MISSING_SUBPATTERN"},
+	{ERROR_DEFINE_TOO_MANY_BRANCHES, "DEFINE_TOO_MANY_BRANCHES"},
+	{ERROR_BACKSLASH_O_MISSING_BRACE, "BACKSLASH_O_MISSING_BRACE"},
+	{ERROR_INTERNAL_UNKNOWN_NEWLINE, "INTERNAL_UNKNOWN_NEWLINE"},
+	{ERROR_BACKSLASH_G_SYNTAX, "BACKSLASH_G_SYNTAX"},
+	{ERROR_PARENS_QUERY_R_MISSING_CLOSING, "PARENS_QUERY_R_MISSING_CLOSING"},
+	{ERROR_VERB_ARGUMENT_NOT_ALLOWED, "VERB_ARGUMENT_NOT_ALLOWED"},
+	{ERROR_VERB_UNKNOWN, "VERB_UNKNOWN"},
+	{ERROR_SUBPATTERN_NUMBER_TOO_BIG, "SUBPATTERN_NUMBER_TOO_BIG"},
+	{ERROR_SUBPATTERN_NAME_EXPECTED, "SUBPATTERN_NAME_EXPECTED"},
+	{ERROR_INTERNAL_PARSED_OVERFLOW, "INTERNAL_PARSED_OVERFLOW"},
+	{ERROR_INVALID_OCTAL, "INVALID_OCTAL"},
+	{ERROR_SUBPATTERN_NAMES_MISMATCH, "SUBPATTERN_NAMES_MISMATCH"},
+	{ERROR_MARK_MISSING_ARGUMENT, "MARK_MISSING_ARGUMENT"},
+	{ERROR_INVALID_HEXADECIMAL, "INVALID_HEXADECIMAL"},
+	{ERROR_BACKSLASH_C_SYNTAX, "BACKSLASH_C_SYNTAX"},
+	{ERROR_BACKSLASH_K_SYNTAX, "BACKSLASH_K_SYNTAX"},
+	{ERROR_INTERNAL_BAD_CODE_LOOKBEHINDS, "INTERNAL_BAD_CODE_LOOKBEHINDS"},
+	{ERROR_BACKSLASH_N_IN_CLASS, "BACKSLASH_N_IN_CLASS"},
+	{ERROR_CALLOUT_STRING_TOO_LONG, "CALLOUT_STRING_TOO_LONG"},
+	{ERROR_UNICODE_DISALLOWED_CODE_POINT, "UNICODE_DISALLOWED_CODE_POINT"},
+	{ERROR_UTF_IS_DISABLED, "UTF_IS_DISABLED"},
+	{ERROR_UCP_IS_DISABLED, "UCP_IS_DISABLED"},
+	{ERROR_VERB_NAME_TOO_LONG, "VERB_NAME_TOO_LONG"},
+	{ERROR_BACKSLASH_U_CODE_POINT_TOO_BIG, "BACKSLASH_U_CODE_POINT_TOO_BIG"},
+	{ERROR_MISSING_OCTAL_OR_HEX_DIGITS, "MISSING_OCTAL_OR_HEX_DIGITS"},
+	{ERROR_VERSION_CONDITION_SYNTAX, "VERSION_CONDITION_SYNTAX"},
+	{ERROR_INTERNAL_BAD_CODE_AUTO_POSSESS, "INTERNAL_BAD_CODE_AUTO_POSSESS"},
+	{ERROR_CALLOUT_NO_STRING_DELIMITER, "CALLOUT_NO_STRING_DELIMITER"},
+	{ERROR_CALLOUT_BAD_STRING_DELIMITER, "CALLOUT_BAD_STRING_DELIMITER"},
+	{ERROR_BACKSLASH_C_CALLER_DISABLED, "BACKSLASH_C_CALLER_DISABLED"},
+	{ERROR_QUERY_BARJX_NEST_TOO_DEEP, "QUERY_BARJX_NEST_TOO_DEEP"},
+	{ERROR_BACKSLASH_C_LIBRARY_DISABLED, "BACKSLASH_C_LIBRARY_DISABLED"},
+	{ERROR_PATTERN_TOO_COMPLICATED, "PATTERN_TOO_COMPLICATED"},
+	{ERROR_LOOKBEHIND_TOO_LONG, "LOOKBEHIND_TOO_LONG"},
+	{ERROR_PATTERN_STRING_TOO_LONG, "PATTERN_STRING_TOO_LONG"},
+	{ERROR_INTERNAL_BAD_CODE, "INTERNAL_BAD_CODE"},
+	{ERROR_INTERNAL_BAD_CODE_IN_SKIP, "INTERNAL_BAD_CODE_IN_SKIP"},
+	{ERROR_NO_SURROGATES_IN_UTF16, "NO_SURROGATES_IN_UTF16"},
+	{ERROR_BAD_LITERAL_OPTIONS, "BAD_LITERAL_OPTIONS"},
+	{ERROR_SUPPORTED_ONLY_IN_UNICODE, "SUPPORTED_ONLY_IN_UNICODE"},
+	{ERROR_INVALID_HYPHEN_IN_OPTIONS, "INVALID_HYPHEN_IN_OPTIONS"},
+	{ERROR_NOMATCH, "NOMATCH"},
+	{ERROR_PARTIAL, "PARTIAL"},
+	{ERROR_UTF8_ERR1, "UTF8_ERR1"},
+	{ERROR_UTF8_ERR2, "UTF8_ERR2"},
+	{ERROR_UTF8_ERR3, "UTF8_ERR3"},
+	{ERROR_UTF8_ERR4, "UTF8_ERR4"},
+	{ERROR_UTF8_ERR5, "UTF8_ERR5"},
+	{ERROR_UTF8_ERR6, "UTF8_ERR6"},
+	{ERROR_UTF8_ERR7, "UTF8_ERR7"},
+	{ERROR_UTF8_ERR8, "UTF8_ERR8"},
+	{ERROR_UTF8_ERR9, "UTF8_ERR9"},
+	{ERROR_UTF8_ERR10, "UTF8_ERR10"},
+	{ERROR_UTF8_ERR11, "UTF8_ERR11"},
+	{ERROR_UTF8_ERR12, "UTF8_ERR12"},
+	{ERROR_UTF8_ERR13, "UTF8_ERR13"},
+	{ERROR_UTF8_ERR14, "UTF8_ERR14"},
+	{ERROR_UTF8_ERR15, "UTF8_ERR15"},
+	{ERROR_UTF8_ERR16, "UTF8_ERR16"},
+	{ERROR_UTF8_ERR17, "UTF8_ERR17"},
+	{ERROR_UTF8_ERR18, "UTF8_ERR18"},
+	{ERROR_UTF8_ERR19, "UTF8_ERR19"},
+	{ERROR_UTF8_ERR20, "UTF8_ERR20"},
+	{ERROR_UTF8_ERR21, "UTF8_ERR21"},
+	{ERROR_UTF16_ERR1, "UTF16_ERR1"},
+	{ERROR_UTF16_ERR2, "UTF16_ERR2"},
+	{ERROR_UTF16_ERR3, "UTF16_ERR3"},
+	{ERROR_UTF32_ERR1, "UTF32_ERR1"},
+	{ERROR_UTF32_ERR2, "UTF32_ERR2"},
+	{ERROR_BADDATA, "BADDATA"},
+	{ERROR_MIXEDTABLES, "MIXEDTABLES"},
+	{ERROR_BADMAGIC, "BADMAGIC"},
+	{ERROR_BADMODE, "BADMODE"},
+	{ERROR_BADOFFSET, "BADOFFSET"},
+	{ERROR_BADOPTION, "BADOPTION"},
+	{ERROR_BADREPLACEMENT, "BADREPLACEMENT"},
+	{ERROR_BADUTFOFFSET, "BADUTFOFFSET"},
+	{ERROR_CALLOUT, "CALLOUT"},
+	{ERROR_DFA_BADRESTART, "DFA_BADRESTART"},
+	{ERROR_DFA_RECURSE, "DFA_RECURSE"},
+	{ERROR_DFA_UCOND, "DFA_UCOND"},
+	{ERROR_DFA_UFUNC, "DFA_UFUNC"},
+	{ERROR_DFA_UITEM, "DFA_UITEM"},
+	{ERROR_DFA_WSSIZE, "DFA_WSSIZE"},
+	{ERROR_INTERNAL, "INTERNAL"},
+	{ERROR_JIT_BADOPTION, "JIT_BADOPTION"},
+	{ERROR_JIT_STACKLIMIT, "JIT_STACKLIMIT"},
+	{ERROR_MATCHLIMIT, "MATCHLIMIT"},
+	{ERROR_NOMEMORY, "NOMEMORY"},
+	{ERROR_NOSUBSTRING, "NOSUBSTRING"},
+	{ERROR_NOUNIQUESUBSTRING, "NOUNIQUESUBSTRING"},
+	{ERROR_NULL, "NULL"},
+	{ERROR_RECURSELOOP, "RECURSELOOP"},
+	{ERROR_DEPTHLIMIT, "DEPTHLIMIT"},
+	{ERROR_RECURSIONLIMIT, "RECURSIONLIMIT"},
+	{ERROR_UNAVAILABLE, "UNAVAILABLE"},
+	{ERROR_UNSET, "UNSET"},
+	{ERROR_BADOFFSETLIMIT, "BADOFFSETLIMIT"},
+	{ERROR_BADREPESCAPE, "BADREPESCAPE"},
+	{ERROR_REPMISSINGBRACE, "REPMISSINGBRACE"},
+	{ERROR_BADSUBSTITUTION, "BADSUBSTITUTION"},
+	{ERROR_BADSUBSPATTERN, "BADSUBSPATTERN"},
+	{ERROR_TOOMANYREPLACE, "TOOMANYREPLACE"},
+	{ERROR_BADSERIALIZEDDATA, "BADSERIALIZEDDATA"},
+	{ERROR_HEAPLIMIT, "HEAPLIMIT"},
+	{ERROR_CONVERT_SYNTAX, "CONVERT_SYNTAX"},
+	{ERROR_INTERNAL_DUPMATCH, "INTERNAL_DUPMATCH"},
+	{ERROR_SUBJECT_TOO_LONG, "SUBJECT_TOO_LONG"},
+	{ERROR_TIMEOUT, "TIMEOUT"},
+}
+
+var errorCodeIndex = sync.OnceValue(func() map[int]string {
+	index := make(map[int]string, len(errorCodeNames))
+	for _, e := range errorCodeNames {
+		if _, dup := index[e.code]; e.code != 0 && !dup {
+			index[e.code] = e.name
+		}
+	}
+	return index
+})
+
+// ErrorCodeName returns the symbolic name of a PCRE2 error code, i.e. the
+// name of its ERROR_* constant without the prefix, e.g. "MATCHLIMIT" for
+// ERROR_MATCHLIMIT. Unknown codes are returned as decimal numbers.
+func ErrorCodeName(code int) string {
+	if name, ok := errorCodeIndex()[code]; ok {
+		return name
+	}
+	return strconv.Itoa(code)
+}
+
+// Code returns the PCRE2 error code, one of the ERROR_* constants.
+func (e *MatchError) Code() int {
+	return e.ErrorNum
+}
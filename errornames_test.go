@@ -0,0 +1,24 @@
+package pcre2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorCodeName(t *testing.T) {
+	assert.Equal(t, "MATCHLIMIT", ErrorCodeName(ERROR_MATCHLIMIT))
+	assert.Equal(t, "NOMATCH", ErrorCodeName(ERROR_NOMATCH))
+	assert.Equal(t, "DEPTHLIMIT", ErrorCodeName(ERROR_RECURSIONLIMIT))
+	assert.Equal(t, "MISSING_CLOSING_PARENTHESIS", ErrorCodeName(ERROR_MISSING_CLOSING_PARENTHESIS))
+	assert.Equal(t, "TIMEOUT", ErrorCodeName(ERROR_TIMEOUT))
+	assert.Equal(t, "-9999", ErrorCodeName(-9999))
+	assert.Equal(t, "0", ErrorCodeName(0))
+
+	re := MustCompile(`a`, UTF)
+	defer re.Free()
+	m := re.MatcherString("\xff", 0)
+	err := m.GetError().(*MatchError)
+	assert.Equal(t, ERROR_UTF8_ERR21, err.Code())
+	assert.Equal(t, "UTF8_ERR21", ErrorCodeName(err.Code()))
+}
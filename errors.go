@@ -0,0 +1,87 @@
+package pcre2
+
+import "errors"
+
+// Sentinel errors for common PCRE2 error codes. MatchError, CompileError
+// and JITError unwrap to them, so callers can test errors with errors.Is
+// instead of comparing ErrorNum:
+//
+//	if errors.Is(m.GetError(), pcre2.ErrMatchLimit) { ... }
+var (
+	// ErrPartial reports a partial match
+	ErrPartial = errors.New("partial match")
+
+	// ErrMatchLimit is returned when the match limit was reached
+	ErrMatchLimit = errors.New("match limit exceeded")
+
+	// ErrDepthLimit is returned when the depth limit was reached
+	ErrDepthLimit = errors.New("depth limit exceeded")
+
+	// ErrHeapLimit is returned when the heap limit was reached
+	ErrHeapLimit = errors.New("heap limit exceeded")
+
+	// ErrJITStackLimit is returned when the JIT stack was too small
+	ErrJITStackLimit = errors.New("JIT stack limit exceeded")
+
+	// ErrBadUTF is returned for invalid UTF-8, UTF-16 or UTF-32 in a
+	// subject or pattern, and for start offsets inside a character
+	ErrBadUTF = errors.New("invalid UTF string")
+
+	// ErrBadOffset is returned for start offsets beyond the subject
+	ErrBadOffset = errors.New("offset out of range")
+
+	// ErrCompile is wrapped by all compile errors
+	ErrCompile = errors.New("compilation failed")
+)
+
+// errorSentinels maps PCRE2 error codes to sentinel errors. It is a list,
+// as some codes may be zero when built against old headers, see
+// pcre2_fallback.h.
+var errorSentinels = []struct {
+	code int
+	err  error
+}{
+	{ERROR_NOMATCH, ErrNoMatch},
+	{ERROR_PARTIAL, ErrPartial},
+	{ERROR_MATCHLIMIT, ErrMatchLimit},
+	{ERROR_DEPTHLIMIT, ErrDepthLimit},
+	{ERROR_HEAPLIMIT, ErrHeapLimit},
+	{ERROR_JIT_STACKLIMIT, ErrJITStackLimit},
+	{ERROR_NOMEMORY, ErrNoMemory},
+	{ERROR_HEAP_FAILED, ErrNoMemory},
+	{ERROR_BADUTFOFFSET, ErrBadUTF},
+	{ERROR_BADOFFSET, ErrBadOffset},
+}
+
+// errorSentinel returns the sentinel error for a PCRE2 error code, or
+// nil.
+func errorSentinel(code int) error {
+	if code >= ERROR_UTF32_ERR2 && code <= ERROR_UTF8_ERR1 {
+		return ErrBadUTF
+	}
+	for _, s := range errorSentinels {
+		if s.code != 0 && s.code == code {
+			return s.err
+		}
+	}
+	return nil
+}
+
+// Unwrap returns the sentinel error for the error code, if there is one.
+func (e *MatchError) Unwrap() error {
+	return errorSentinel(e.ErrorNum)
+}
+
+// Unwrap returns the sentinel error for the error code, if there is one.
+func (e *JITError) Unwrap() error {
+	return errorSentinel(e.ErrorNum)
+}
+
+// Unwrap returns ErrCompile and the sentinel error for the error code,
+// if there is one.
+func (e *CompileError) Unwrap() []error {
+	if err := errorSentinel(e.ErrorNum); err != nil {
+		return []error{ErrCompile, err}
+	}
+	return []error{ErrCompile}
+}
@@ -0,0 +1,44 @@
+package pcre2
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSentinelErrors(t *testing.T) {
+	re := MustCompile(`(a+)+$`, UTF)
+	defer re.Free()
+	m := re.NewMatcher()
+	defer m.Free()
+
+	m.MatchString("b", 0)
+	assert.True(t, errors.Is(m.GetError(), ErrNoMatch))
+
+	m.MatchString("a\xff", 0)
+	err := m.GetError()
+	assert.True(t, errors.Is(err, ErrBadUTF))
+	var merr *MatchError
+	if assert.True(t, errors.As(err, &merr)) {
+		assert.Equal(t, ERROR_UTF8_ERR21, merr.ErrorNum)
+	}
+
+	ctx := NewMatchContext()
+	defer ctx.Free()
+	ctx.SetMatchLimit(10)
+	m.SetMatchContext(ctx)
+	m.MatchString("aaaaaaaaaaaaaaaaaaaab", 0)
+	assert.True(t, errors.Is(m.GetError(), ErrMatchLimit))
+	assert.False(t, errors.Is(m.GetError(), ErrNoMatch))
+
+	_, err = Compile(`(`, 0)
+	assert.True(t, errors.Is(err, ErrCompile))
+	assert.False(t, errors.Is(err, ErrBadUTF))
+	_, err = Compile("\xff", UTF)
+	assert.True(t, errors.Is(err, ErrCompile))
+	assert.True(t, errors.Is(err, ErrBadUTF))
+
+	assert.Nil(t, errorSentinel(0))
+	assert.Equal(t, ErrNoMemory, errorSentinel(ERROR_NOMEMORY))
+}
@@ -0,0 +1,140 @@
+package pcre2
+
+/*
+#define PCRE2_CODE_UNIT_WIDTH 8
+
+#include <pcre2.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Feature is a capability of PCRE2 which is not available in all
+// versions of the library. Functions and constants of missing features
+// are replaced by stubs in pcre2_fallback.h, so that the package builds
+// against old headers; Supports tells whether they actually work.
+// Functions needing a missing feature fail with a *FeatureError.
+type Feature int
+
+// Features which can be checked with Supports.
+const (
+	FeatureSerialize          Feature = iota // Regexp.Serialize
+	FeatureCalloutEnumerate                  // Regexp.Callouts
+	FeatureDepthLimit                        // MatchContext.SetDepthLimit
+	FeatureHeapLimit                         // MatchContext.SetHeapLimit
+	FeatureEndAnchored                       // ENDANCHORED
+	FeatureLiteral                           // LITERAL
+	FeatureExtraOptions                      // CompileContext.SetExtraOptions
+	FeatureConvert                           // ConvertGlob
+	FeatureCalloutFlags                      // CalloutBlock.Flags
+	FeatureSubstituteCallout                 // pcre2_set_substitute_callout
+	FeatureSubstituteMatched                 // SUBSTITUTE_MATCHED and friends
+	FeatureMaketablesFree                    // Tables.Free
+	FeatureMatchInvalidUTF                   // MATCH_INVALID_UTF
+	FeatureCodeCopy                          // Regexp.Clone
+	FeatureCodeCopyWithTables                // Regexp.CloneWithTables
+	FeatureLookaroundBSK                     // EXTRA_ALLOW_LOOKAROUND_BSK
+)
+
+type featureInfo struct {
+	name  string
+	major int
+	minor int // first version providing the feature
+}
+
+var features = []featureInfo{
+	FeatureSerialize:          {"serialization", 10, 21},
+	FeatureCalloutEnumerate:   {"callout enumeration", 10, 22},
+	FeatureDepthLimit:         {"depth limit", 10, 30},
+	FeatureHeapLimit:          {"heap limit", 10, 30},
+	FeatureEndAnchored:        {"ENDANCHORED", 10, 30},
+	FeatureLiteral:            {"LITERAL", 10, 30},
+	FeatureExtraOptions:       {"extra compile options", 10, 30},
+	FeatureConvert:            {"pattern conversion", 10, 30},
+	FeatureCalloutFlags:       {"callout flags", 10, 30},
+	FeatureSubstituteCallout:  {"substitute callout", 10, 33},
+	FeatureSubstituteMatched:  {"SUBSTITUTE_MATCHED", 10, 34},
+	FeatureMaketablesFree:     {"freeing character tables", 10, 34},
+	FeatureMatchInvalidUTF:    {"MATCH_INVALID_UTF", 10, 34},
+	FeatureCodeCopy:           {"copying patterns", 10, 23},
+	FeatureCodeCopyWithTables: {"copying patterns with tables", 10, 31},
+	FeatureLookaroundBSK:      {"\\K in lookarounds", 10, 38},
+}
+
+// String returns the name of the feature.
+func (f Feature) String() string {
+	if f < 0 || int(f) >= len(features) {
+		return "Feature(" + strconv.Itoa(int(f)) + ")"
+	}
+	return features[f].name
+}
+
+// The versions of the headers the package was built with, and of the
+// library it runs with. A feature needs both.
+var (
+	headerMajor, headerMinor   = int(C.PCRE2_MAJOR), int(C.PCRE2_MINOR)
+	libraryMajor, libraryMinor = parseVersion(Version())
+)
+
+// parseVersion extracts major and minor version from a version string
+// like "10.42 2022-12-11".
+func parseVersion(version string) (major, minor int) {
+	version, _, _ = strings.Cut(version, " ")
+	majorStr, minorStr, _ := strings.Cut(version, ".")
+	major, _ = strconv.Atoi(majorStr)
+	if i := strings.IndexFunc(minorStr, func(r rune) bool {
+		return r < '0' || r > '9'
+	}); i >= 0 {
+		minorStr = minorStr[:i]
+	}
+	minor, _ = strconv.Atoi(minorStr)
+	return
+}
+
+func versionAtLeast(major, minor, wantMajor, wantMinor int) bool {
+	return major > wantMajor || major == wantMajor && minor >= wantMinor
+}
+
+// Supports reports whether the feature is available, i.e. both the
+// headers the package was built with and the linked library are recent
+// enough.
+func Supports(f Feature) bool {
+	if f < 0 || int(f) >= len(features) {
+		return false
+	}
+	info := features[f]
+	return versionAtLeast(headerMajor, headerMinor, info.major, info.minor) &&
+		versionAtLeast(libraryMajor, libraryMinor, info.major, info.minor)
+}
+
+// FeatureError is returned by Require for unavailable features.
+type FeatureError struct {
+	Feature Feature
+	Version string // version of the linked library
+}
+
+// Error converts a feature error to a string.
+func (e *FeatureError) Error() string {
+	info := features[e.Feature]
+	return fmt.Sprintf("pcre2: %s requires PCRE2 %d.%02d, built with %d.%02d, running %s",
+		info.name, info.major, info.minor, headerMajor, headerMinor, e.Version)
+}
+
+// Require returns a *FeatureError for the first of the features which
+// is not available, or nil. Code depending on newer PCRE2 features can
+// use it to fail with a clear error on older system libraries.
+func Require(fs ...Feature) error {
+	for _, f := range fs {
+		if !Supports(f) {
+			if f < 0 || int(f) >= len(features) {
+				return fmt.Errorf("pcre2: unknown feature %v", f)
+			}
+			return &FeatureError{Feature: f, Version: Version()}
+		}
+	}
+	return nil
+}
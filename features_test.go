@@ -0,0 +1,46 @@
+package pcre2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVersion(t *testing.T) {
+	major, minor := parseVersion("10.42 2022-12-11")
+	assert.Equal(t, 10, major)
+	assert.Equal(t, 42, minor)
+	major, minor = parseVersion("10.45-RC1 2024-06-09")
+	assert.Equal(t, 10, major)
+	assert.Equal(t, 45, minor)
+}
+
+func TestSupports(t *testing.T) {
+	assert.True(t, Supports(FeatureSerialize))
+	assert.NoError(t, Require(FeatureSerialize, FeatureConvert))
+	assert.False(t, Supports(Feature(-1)))
+	assert.Equal(t, "Feature(99)", Feature(99).String())
+	assert.Error(t, Require(Feature(99)))
+
+	defer func(major, minor int) { libraryMajor, libraryMinor = major, minor }(libraryMajor, libraryMinor)
+	libraryMajor, libraryMinor = 10, 22
+	assert.False(t, Supports(FeatureHeapLimit))
+	assert.False(t, Supports(FeatureLookaroundBSK))
+	err := Require(FeatureSerialize, FeatureSubstituteCallout)
+	if assert.IsType(t, &FeatureError{}, err) {
+		assert.Equal(t, FeatureSubstituteCallout, err.(*FeatureError).Feature)
+		assert.Contains(t, err.Error(), "substitute callout requires PCRE2 10.33")
+	}
+
+	// Functions of missing features fail instead of calling the library.
+	re := MustCompile(`a`, 0)
+	defer re.Free()
+	_, err = re.CloneWithTables()
+	assert.IsType(t, &FeatureError{}, err)
+	_, err = ConvertGlob("*.go", 0)
+	assert.IsType(t, &FeatureError{}, err)
+	tables, err := MakeTables("")
+	if assert.NoError(t, err) {
+		assert.NoError(t, tables.Free())
+	}
+}
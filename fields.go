@@ -0,0 +1,201 @@
+package pcre2
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// FieldType tells a FieldMapper how to convert the text of a group.
+type FieldType int
+
+// Field types supported by FieldMapper.
+const (
+	FieldString   FieldType = iota // string, unconverted
+	FieldInt                       // int64, parsed with strconv.ParseInt
+	FieldFloat                     // float64, parsed with strconv.ParseFloat
+	FieldBool                      // bool, parsed with strconv.ParseBool
+	FieldTime                      // time.Time, parsed with Layout
+	FieldDuration                  // time.Duration, parsed with time.ParseDuration
+)
+
+// Field maps a named capture group to an output field.
+type Field struct {
+	Group   string    // name of the capture group
+	Name    string    // name of the output field; defaults to Group
+	Type    FieldType // conversion of the captured text
+	Layout  string    // time layout for FieldTime; defaults to time.RFC3339
+	Default any       // value used when the group is unset or empty
+}
+
+// FieldMapper converts the named groups of a match into output fields,
+// according to a declarative list of Fields. It is the extraction layer
+// for log and metric parsers, replacing hand-written glue code:
+//
+//	fm, err := pcre2.NewFieldMapper(re,
+//		pcre2.Field{Group: "ts", Name: "Time", Type: pcre2.FieldTime},
+//		pcre2.Field{Group: "status", Name: "Status", Type: pcre2.FieldInt, Default: int64(0)},
+//	)
+type FieldMapper struct {
+	fields []Field
+	groups []int
+}
+
+// NewFieldMapper checks the fields against the groups of re and returns
+// a FieldMapper for matches of re.
+func NewFieldMapper(re *Regexp, fields ...Field) (*FieldMapper, error) {
+	fm := &FieldMapper{
+		fields: make([]Field, len(fields)),
+		groups: make([]int, len(fields)),
+	}
+	for i, f := range fields {
+		group, err := re.name2index(f.Group)
+		if err != nil {
+			return nil, err
+		}
+		if f.Name == "" {
+			f.Name = f.Group
+		}
+		if f.Type == FieldTime && f.Layout == "" {
+			f.Layout = time.RFC3339
+		}
+		fm.fields[i] = f
+		fm.groups[i] = group
+	}
+	return fm, nil
+}
+
+// value returns the converted value of field i in the match.
+func (fm *FieldMapper) value(m *Matcher, i int) (any, error) {
+	f := fm.fields[i]
+	var text string
+	if m.Present(fm.groups[i]) {
+		text = m.GroupString(fm.groups[i])
+	}
+	if text == "" && f.Default != nil {
+		return f.Default, nil
+	}
+	var v any
+	var err error
+	switch f.Type {
+	case FieldString:
+		v = text
+	case FieldInt:
+		v, err = strconv.ParseInt(text, 10, 64)
+	case FieldFloat:
+		v, err = strconv.ParseFloat(text, 64)
+	case FieldBool:
+		v, err = strconv.ParseBool(text)
+	case FieldTime:
+		v, err = time.Parse(f.Layout, text)
+	case FieldDuration:
+		v, err = time.ParseDuration(text)
+	default:
+		err = fmt.Errorf("unknown field type %d", f.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("field %s: %w", f.Name, err)
+	}
+	return v, nil
+}
+
+// Map returns the fields of the last match of m, keyed by field name.
+func (fm *FieldMapper) Map(m *Matcher) (map[string]any, error) {
+	if !m.Matches() {
+		return nil, ErrNoMatch
+	}
+	result := make(map[string]any, len(fm.fields))
+	for i, f := range fm.fields {
+		v, err := fm.value(m, i)
+		if err != nil {
+			return nil, err
+		}
+		result[f.Name] = v
+	}
+	return result, nil
+}
+
+// Decode stores the fields of the last match of m in the struct pointed
+// to by dst. Each field is stored in the struct field with a
+// `pcre2:"name"` tag matching its name or, failing that, in the struct
+// field with that name. The value must be assignable to the struct
+// field, or be of the same kind of basic type: integers are stored in
+// integer fields of any size which holds them, floats in float fields,
+// strings and bools in fields of a string or bool kind. Conversions
+// which change the value, e.g. of an integer to a string or a float to
+// an integer, are refused.
+func (fm *FieldMapper) Decode(m *Matcher, dst any) error {
+	if !m.Matches() {
+		return ErrNoMatch
+	}
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("Decode: destination must be a pointer to a struct")
+	}
+	rv = rv.Elem()
+	for i, f := range fm.fields {
+		target := structField(rv, f.Name)
+		if !target.IsValid() {
+			return fmt.Errorf("Decode: no struct field for %s", f.Name)
+		}
+		v, err := fm.value(m, i)
+		if err != nil {
+			return err
+		}
+		val := reflect.ValueOf(v)
+		if !storeValue(target, val) {
+			return fmt.Errorf("Decode: cannot store %s in field %s of type %s",
+				val.Type(), f.Name, target.Type())
+		}
+	}
+	return nil
+}
+
+// storeValue stores val in target if it is assignable, or converts it
+// to another type of the same kind without changing the value.
+func storeValue(target, val reflect.Value) bool {
+	if val.Type().AssignableTo(target.Type()) {
+		target.Set(val)
+		return true
+	}
+	switch {
+	case val.CanInt() && target.CanInt():
+		if target.OverflowInt(val.Int()) {
+			return false
+		}
+		target.SetInt(val.Int())
+	case val.CanInt() && target.CanUint():
+		if val.Int() < 0 || target.OverflowUint(uint64(val.Int())) {
+			return false
+		}
+		target.SetUint(uint64(val.Int()))
+	case val.CanFloat() && target.CanFloat():
+		if target.OverflowFloat(val.Float()) {
+			return false
+		}
+		target.SetFloat(val.Float())
+	case val.Kind() == reflect.String && target.Kind() == reflect.String:
+		target.SetString(val.String())
+	case val.Kind() == reflect.Bool && target.Kind() == reflect.Bool:
+		target.SetBool(val.Bool())
+	default:
+		return false
+	}
+	return true
+}
+
+// structField finds the settable struct field for an output field name.
+func structField(rv reflect.Value, name string) reflect.Value {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("pcre2") == name && t.Field(i).IsExported() {
+			return rv.Field(i)
+		}
+	}
+	if sf, ok := t.FieldByName(name); ok && sf.IsExported() {
+		return rv.FieldByIndex(sf.Index)
+	}
+	return reflect.Value{}
+}
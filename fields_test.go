@@ -0,0 +1,101 @@
+package pcre2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldMapper(t *testing.T) {
+	re := MustCompile(`(?<ts>\S+) (?<status>\d+)(?: (?<took>\w+))?`, 0)
+	fm, err := NewFieldMapper(re,
+		Field{Group: "ts", Name: "Time", Type: FieldTime},
+		Field{Group: "status", Type: FieldInt},
+		Field{Group: "took", Name: "Took", Type: FieldDuration, Default: time.Duration(0)},
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	m := re.MatcherString("2020-01-02T03:04:05Z 404 15ms", 0)
+	fields, err := fm.Map(m)
+	if assert.NoError(t, err) {
+		assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), fields["Time"])
+		assert.Equal(t, int64(404), fields["status"])
+		assert.Equal(t, 15*time.Millisecond, fields["Took"])
+	}
+
+	var rec struct {
+		Time   time.Time
+		Status int `pcre2:"status"`
+		Took   time.Duration
+	}
+	m = re.MatcherString("2020-01-02T03:04:05Z 200", 0)
+	if assert.NoError(t, fm.Decode(m, &rec)) {
+		assert.Equal(t, 200, rec.Status)
+		assert.Equal(t, time.Duration(0), rec.Took)
+	}
+
+	m = re.MatcherString("yesterday 200", 0)
+	_, err = fm.Map(m)
+	assert.Error(t, err)
+	assert.Equal(t, ErrNoMatch, fm.Decode(re.MatcherString("", 0), &rec))
+	assert.Error(t, fm.Decode(re.MatcherString("2020-01-02T03:04:05Z 200", 0), rec))
+
+	_, err = NewFieldMapper(re, Field{Group: "missing"})
+	assert.Error(t, err)
+}
+
+func TestFieldMapperDecodeTypes(t *testing.T) {
+	re := MustCompile(`(?<n>\d+) (?<f>[\d.]+)`, 0)
+	m := re.MatcherString("65 3.9", 0)
+	decode := func(fields []Field, dst any) error {
+		fm, err := NewFieldMapper(re, fields...)
+		if !assert.NoError(t, err) {
+			return err
+		}
+		return fm.Decode(m, dst)
+	}
+
+	type level string
+	var ok struct {
+		N  int8
+		U  uint16
+		F  float32
+		S  level
+		D  time.Duration
+		N2 int64 `pcre2:"n2"`
+	}
+	err := decode([]Field{
+		{Group: "n", Name: "N", Type: FieldInt},
+		{Group: "n", Name: "U", Type: FieldInt},
+		{Group: "f", Name: "F", Type: FieldFloat},
+		{Group: "n", Name: "S"},
+		{Group: "n", Name: "D", Type: FieldInt},
+		{Group: "n", Name: "n2", Type: FieldInt},
+	}, &ok)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int8(65), ok.N)
+		assert.Equal(t, uint16(65), ok.U)
+		assert.Equal(t, float32(3.9), ok.F)
+		assert.Equal(t, level("65"), ok.S)
+		assert.Equal(t, time.Duration(65), ok.D)
+		assert.Equal(t, int64(65), ok.N2)
+	}
+
+	var s struct{ S string }
+	err = decode([]Field{{Group: "n", Name: "S", Type: FieldInt}}, &s)
+	assert.ErrorContains(t, err, "cannot store int64 in field S of type string")
+	assert.Empty(t, s.S)
+
+	var i struct{ I int }
+	err = decode([]Field{{Group: "f", Name: "I", Type: FieldFloat}}, &i)
+	assert.ErrorContains(t, err, "cannot store float64 in field I of type int")
+	assert.Zero(t, i.I)
+
+	m = re.MatcherString("300 1", 0)
+	var small struct{ B int8 }
+	err = decode([]Field{{Group: "n", Name: "B", Type: FieldInt}}, &small)
+	assert.ErrorContains(t, err, "cannot store int64 in field B of type int8")
+}
@@ -0,0 +1,89 @@
+package pcre2
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// FilterWriter is a Writer which forwards only the lines of the data
+// written to it which match a Regexp, or which do not match it if
+// inverted, like grep. Lines end with '\n', which is not part of the
+// subject, and are forwarded whole, one Write per line. The last line
+// need not end with '\n'; it is forwarded by Close.
+//
+// A FilterWriter must not be used by several goroutines at once.
+type FilterWriter struct {
+	w      io.Writer
+	m      *Matcher
+	invert bool
+	line   []byte // start of a line whose end has not been written yet
+	err    error  // first error, returned by all further calls
+	closed bool
+}
+
+var errFilterClosed = errors.New("write to closed FilterWriter")
+
+// NewFilterWriter returns a FilterWriter which writes the lines matching
+// re, or if invert is true the lines not matching re, to w.
+func NewFilterWriter(w io.Writer, re *Regexp, invert bool) *FilterWriter {
+	return &FilterWriter{w: w, m: re.NewMatcher(), invert: invert}
+}
+
+// Write implements io.Writer. It returns an error if writing to the
+// underlying Writer or matching a line fails.
+func (fw *FilterWriter) Write(p []byte) (int, error) {
+	if fw.err != nil {
+		return 0, fw.err
+	}
+	if fw.closed {
+		return 0, errFilterClosed
+	}
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			fw.line = append(fw.line, p...)
+			break
+		}
+		line := p[:i+1]
+		if len(fw.line) > 0 {
+			fw.line = append(fw.line, line...)
+			line = fw.line
+		}
+		fw.filter(line)
+		fw.line = fw.line[:0]
+		p = p[i+1:]
+		if fw.err != nil {
+			return 0, fw.err
+		}
+	}
+	return n, nil
+}
+
+// filter forwards the line if it is selected.
+func (fw *FilterWriter) filter(line []byte) {
+	subject := bytes.TrimSuffix(line, []byte{'\n'})
+	if fw.m.Match(subject, 0) == fw.invert {
+		if err := fw.m.matchError(); err != nil {
+			fw.err = err
+		}
+		return
+	}
+	_, fw.err = fw.w.Write(line)
+}
+
+// Close forwards the last line if it does not end with '\n' and is
+// selected, and releases the matcher. It does not close the underlying
+// Writer.
+func (fw *FilterWriter) Close() error {
+	if fw.closed {
+		return fw.err
+	}
+	fw.closed = true
+	if fw.err == nil && len(fw.line) > 0 {
+		fw.filter(fw.line)
+	}
+	fw.m.Free()
+	return fw.err
+}
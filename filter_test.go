@@ -0,0 +1,44 @@
+package pcre2
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterWriter(t *testing.T) {
+	input := "INFO start\nERROR disk full\nINFO retry\nERROR gave up"
+	for _, tc := range []struct {
+		invert bool
+		want   string
+	}{
+		{false, "ERROR disk full\nERROR gave up"},
+		{true, "INFO start\nINFO retry\n"},
+	} {
+		var out bytes.Buffer
+		fw := NewFilterWriter(&out, MustCompile(`^ERROR\b`, 0), tc.invert)
+		// Lines are split across writes.
+		for _, chunk := range strings.SplitAfter(input, "r") {
+			n, err := fw.Write([]byte(chunk))
+			assert.NoError(t, err)
+			assert.Equal(t, len(chunk), n)
+		}
+		assert.NoError(t, fw.Close())
+		assert.Equal(t, tc.want, out.String())
+		_, err := fw.Write([]byte("x"))
+		assert.Error(t, err)
+	}
+}
+
+func TestFilterWriterMatchError(t *testing.T) {
+	defer SetDefaultLimits(0, 0, 0)
+	SetDefaultLimits(100, 0, 0)
+	var out bytes.Buffer
+	fw := NewFilterWriter(&out, MustCompile(`(a+)+$`, 0), false)
+	_, err := fw.Write([]byte("aaaaaaaaaaaaaaaaaab\n"))
+	assert.ErrorIs(t, err, ErrMatchLimit)
+	assert.ErrorIs(t, fw.Close(), ErrMatchLimit)
+	assert.Zero(t, out.Len())
+}
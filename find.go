@@ -0,0 +1,274 @@
+package pcre2
+
+/*
+#define PCRE2_CODE_UNIT_WIDTH 8
+
+#include <pcre2.h>
+*/
+import "C"
+
+import (
+	"iter"
+	"runtime"
+	"time"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// subject is the set of types that can be matched against a Regexp.
+// The matching code is written once against this constraint, and
+// instantiated for both []byte and string subjects.
+type subject interface {
+	[]byte | string
+}
+
+// execAt matches subject starting at the given offset. The subject is
+// recorded in m, so that the group accessors can return parts of it.
+func execAt[S subject](m *Matcher, subject S, offset int, flags uint32) (rc int) {
+	if h := metrics.Load(); h != nil {
+		defer observeMatch(h, m.re, time.Now(), &rc)
+	}
+	if logger.Load() != nil {
+		defer logLimit(m.re, offset, &rc)
+	}
+	if m.re.subjectTooLong(len(subject)) {
+		m.subjects, m.subjectb = "", nil
+		return ERROR_SUBJECT_TOO_LONG
+	}
+	switch s := any(subject).(type) {
+	case []byte:
+		m.subjects, m.subjectb = "", s
+	case string:
+		m.subjects, m.subjectb = s, nil
+	}
+	if m.canMatchLiteral(len(subject), offset, flags) {
+		return execLiteral(m, subject, offset)
+	}
+	if s, ok := any(subject).(string); ok && len(s) > 0 && m.copiesSubjects() {
+		return m.execCopy(s, offset, flags)
+	}
+	subjectptr := dataPtr(subject)
+	// The subject is pinned for the whole exec, which may call into C
+	// several times, see execBounded, and run Go callouts reading the
+	// subject through the callout block in between.
+	var pinner runtime.Pinner
+	pinner.Pin(subjectptr)
+	defer pinner.Unpin()
+	return m.exec(subjectptr, len(subject), offset, flags)
+}
+
+// dataPtr returns a pointer to the data of s without copying it. Empty
+// subjects point to nullbyte, so that the pointer is always valid.
+func dataPtr[S subject](s S) *C.char {
+	if len(s) == 0 {
+		return (*C.char)(unsafe.Pointer(&nullbyte[0]))
+	}
+	switch v := any(s).(type) {
+	case []byte:
+		return (*C.char)(unsafe.Pointer(unsafe.SliceData(v)))
+	case string:
+		return (*C.char)(unsafe.Pointer(unsafe.StringData(v)))
+	}
+	panic("unreachable")
+}
+
+// iterate calls yield with the matcher positioned on each successive
+// non-overlapping match of re in subject, until yield returns false.
+// Empty matches are handled as in pcre2demo: after an empty match the
+// same position is retried with NOTEMPTY_ATSTART|ANCHORED, and if that
+// fails, the search resumes one character further. A match whose start
+// was set after its end by \K in an assertion is yielded too, and the
+// search resumes one character after its end. It returns the error of
+// the match which ended the iteration, if it failed with one other than
+// ERROR_NOMATCH, e.g. because a limit was hit.
+func iterate[S subject](re *Regexp, subject S, flags uint32, yield func(*Matcher) bool) error {
+	m := re.NewMatcher()
+	defer m.Free()
+	return iterateMatcher(m, subject, flags, yield)
+}
+
+// iterateMatcher is like iterate, but matches with m, which can be
+// reused for several subjects.
+func iterateMatcher[S subject](m *Matcher, subject S, flags uint32, yield func(*Matcher) bool) error {
+	re := m.re
+	utf := pcreOptions(re.ptr)&UTF != 0
+	crlf := false
+	switch pcreNewline(re.ptr) {
+	case NEWLINE_CRLF, NEWLINE_ANY, NEWLINE_ANYCRLF:
+		crlf = true
+	}
+
+	// next returns the offset one character after offset.
+	next := func(offset int) int {
+		switch {
+		case crlf && offset+1 < len(subject) && subject[offset] == '\r' && subject[offset+1] == '\n':
+			return offset + 2
+		case utf:
+			offset++
+			for offset < len(subject) && !utf8.RuneStart(subject[offset]) {
+				offset++
+			}
+			return offset
+		}
+		return offset + 1
+	}
+
+	offset := 0
+	var retry uint32
+	for offset <= len(subject) {
+		rc := execAt(m, subject, offset, flags|retry)
+		if rc < 0 {
+			if rc != ERROR_NOMATCH || retry == 0 || offset >= len(subject) {
+				m.record(rc)
+				return m.matchError()
+			}
+			// Advance by one character after a failed retry.
+			offset, retry = next(offset), 0
+			continue
+		}
+		m.record(rc)
+		start, end, _ := m.mData.offsets(0)
+		if !yield(m) {
+			return nil
+		}
+		retry = 0
+		switch {
+		case start > end:
+			// Retrying at the end would find the same match again, as
+			// PCRE2 does not consider it empty.
+			offset = next(int(end))
+			continue
+		case start == end:
+			retry = NOTEMPTY_ATSTART | ANCHORED
+		}
+		offset = int(end)
+	}
+	return nil
+}
+
+// Iterate returns an iterator over all successive non-overlapping
+// matches of the pattern in the subject. The yielded Matcher is reused
+// for every match and freed when the iteration ends, so it must not be
+// retained.
+func (re *Regexp) Iterate(subject []byte, flags uint32) iter.Seq[*Matcher] {
+	return func(yield func(*Matcher) bool) {
+		iterate(re, subject, flags, yield)
+	}
+}
+
+// IterateString is like Iterate, but with a string subject.
+func (re *Regexp) IterateString(subject string, flags uint32) iter.Seq[*Matcher] {
+	return func(yield func(*Matcher) bool) {
+		iterate(re, subject, flags, yield)
+	}
+}
+
+func findIndex[S subject](re *Regexp, subject S, flags uint32) []int {
+	if re.literal && flags == 0 && re.ptr != nil && metrics.Load() == nil {
+		// No need for a Matcher, unless the match is to be observed.
+		if re.subjectTooLong(len(subject)) {
+			return nil
+		}
+		if i := literalIndex(re.Pattern, subject, 0); i >= 0 {
+			return []int{i, i + len(re.Pattern)}
+		}
+		return nil
+	}
+	m := re.NewMatcher()
+	defer m.Free()
+	if m.record(execAt(m, subject, 0, flags)) {
+		start, end, _ := m.mData.offsets(0)
+		return []int{int(start), int(end)}
+	}
+	return nil
+}
+
+func findIndex64[S subject](re *Regexp, subject S, flags uint32) []int64 {
+	m := re.NewMatcher()
+	defer m.Free()
+	if m.record(execAt(m, subject, 0, flags)) {
+		start, end, _ := m.mData.offsets(0)
+		return []int64{start, end}
+	}
+	return nil
+}
+
+func findAllIndex[S subject](re *Regexp, subject S, flags uint32, n int) (locs [][]int, err error) {
+	err = iterate(re, subject, flags, func(m *Matcher) bool {
+		if n >= 0 && len(locs) >= n {
+			return false
+		}
+		start, end, _ := m.mData.offsets(0)
+		locs = append(locs, []int{int(start), int(end)})
+		return true
+	})
+	return
+}
+
+func findAll[S subject](re *Regexp, subject S, flags uint32, n int) (all []S) {
+	locs, _ := findAllIndex(re, subject, flags, n)
+	for _, loc := range locs {
+		all = append(all, subject[loc[0]:loc[1]])
+	}
+	return
+}
+
+// Find returns the text of the first match in the subject,
+// or nil if there is no match.
+func (re *Regexp) Find(subject []byte, flags uint32) []byte {
+	if loc := findIndex(re, subject, flags); loc != nil {
+		return subject[loc[0]:loc[1]]
+	}
+	return nil
+}
+
+// FindString returns the text of the first match in the subject,
+// or an empty string if there is no match.
+func (re *Regexp) FindString(subject string, flags uint32) string {
+	if loc := findIndex(re, subject, flags); loc != nil {
+		return subject[loc[0]:loc[1]]
+	}
+	return ""
+}
+
+// FindStringIndex is like FindIndex, but with a string subject.
+func (re *Regexp) FindStringIndex(subject string, flags uint32) []int {
+	return findIndex(re, subject, flags)
+}
+
+// FindIndex64 is like FindIndex, but returns the offsets as int64, the
+// width of PCRE2_SIZE on 64-bit platforms, for code which handles
+// offsets into huge subjects, e.g. mapped files, as int64 throughout.
+func (re *Regexp) FindIndex64(subject []byte, flags uint32) []int64 {
+	return findIndex64(re, subject, flags)
+}
+
+// FindStringIndex64 is like FindIndex64, but with a string subject.
+func (re *Regexp) FindStringIndex64(subject string, flags uint32) []int64 {
+	return findIndex64(re, subject, flags)
+}
+
+// FindAll returns the text of all successive non-overlapping matches
+// in the subject. If n >= 0, at most n matches are returned.
+func (re *Regexp) FindAll(subject []byte, flags uint32, n int) [][]byte {
+	return findAll(re, subject, flags, n)
+}
+
+// FindAllString is like FindAll, but with a string subject.
+func (re *Regexp) FindAllString(subject string, flags uint32, n int) []string {
+	return findAll(re, subject, flags, n)
+}
+
+// FindAllIndex returns the start and end of all successive
+// non-overlapping matches in the subject. If n >= 0, at most
+// n matches are returned.
+func (re *Regexp) FindAllIndex(subject []byte, flags uint32, n int) [][]int {
+	locs, _ := findAllIndex(re, subject, flags, n)
+	return locs
+}
+
+// FindAllStringIndex is like FindAllIndex, but with a string subject.
+func (re *Regexp) FindAllStringIndex(subject string, flags uint32, n int) [][]int {
+	locs, _ := findAllIndex(re, subject, flags, n)
+	return locs
+}
@@ -0,0 +1,57 @@
+package pcre2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindAll(t *testing.T) {
+	re := MustCompile(`a*`, 0)
+	assert.Equal(t, []string{"", "aaa", "", ""}, re.FindAllString("baaac", 0, -1))
+	assert.Equal(t, [][]int{{0, 0}, {1, 4}, {4, 4}, {5, 5}}, re.FindAllIndex([]byte("baaac"), 0, -1))
+	assert.Equal(t, [][]byte{[]byte(""), []byte("aaa")}, re.FindAll([]byte("baaac"), 0, 2))
+
+	re = MustCompile(`x*`, UTF)
+	assert.Equal(t, [][]int{{0, 0}, {2, 2}}, re.FindAllStringIndex("é", 0, -1))
+
+	re = MustCompile(`(?m)^`, 0)
+	assert.Equal(t, [][]int{{0, 0}, {2, 2}}, re.FindAllStringIndex("a\nb", 0, -1))
+
+	assert.Nil(t, MustCompile(`z`, 0).FindAllString("abc", 0, -1))
+}
+
+func TestFind(t *testing.T) {
+	re := MustCompile(`b+`, 0)
+	assert.Equal(t, "bb", re.FindString("abbc", 0))
+	assert.Equal(t, []byte("bb"), re.Find([]byte("abbc"), 0))
+	assert.Equal(t, []int{1, 3}, re.FindStringIndex("abbc", 0))
+	assert.Equal(t, "", re.FindString("xyz", 0))
+	assert.Nil(t, re.Find([]byte("xyz"), 0))
+}
+
+func TestIterate(t *testing.T) {
+	re := MustCompile(`(\w)(\d)`, 0)
+	var groups []string
+	for m := range re.IterateString("a1 b2 c3", 0) {
+		groups = append(groups, m.GroupString(1)+m.GroupString(2))
+		if len(groups) == 2 {
+			break
+		}
+	}
+	assert.Equal(t, []string{"a1", "b2"}, groups)
+
+	groups = nil
+	for m := range re.Iterate([]byte("x9y8"), 0) {
+		groups = append(groups, string(m.Group(2)))
+	}
+	assert.Equal(t, []string{"9", "8"}, groups)
+}
+
+func TestExtractMixed(t *testing.T) {
+	re := MustCompile(`(a)(b)`, 0)
+	m := re.MatcherString("ab", 0)
+	assert.Equal(t, [][]byte{[]byte("ab"), []byte("a"), []byte("b")}, m.Extract())
+	m = re.Matcher([]byte("ab"), 0)
+	assert.Equal(t, []string{"ab", "a", "b"}, m.ExtractString())
+}
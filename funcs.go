@@ -0,0 +1,28 @@
+package pcre2
+
+// matchOnce takes the compiled pattern from the cache of CompileCached,
+// or compiles it, and matches the subject once.
+func matchOnce[S subject](pattern string, subject S) (bool, error) {
+	re, err := CompileCached(pattern, 0)
+	if err != nil {
+		return false, err
+	}
+	m := re.NewMatcher()
+	defer m.Free()
+	matched := m.record(execAt(m, subject, 0, 0))
+	return matched, m.matchError()
+}
+
+// Match reports whether the byte slice contains any match of the
+// pattern, like regexp.Match. Compiled patterns are cached, so calling
+// it repeatedly with the same pattern is cheap; use Compile for full
+// control. The error is a *CompileError for an invalid pattern, or the
+// match error, e.g. when a limit is hit.
+func Match(pattern string, b []byte) (bool, error) {
+	return matchOnce(pattern, b)
+}
+
+// MatchString is like Match, but for a subject string.
+func MatchString(pattern string, s string) (bool, error) {
+	return matchOnce(pattern, s)
+}
@@ -0,0 +1,26 @@
+package pcre2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchString(t *testing.T) {
+	matched, err := MatchString(`^\d{3}-\d{4}$`, "555-1234")
+	assert.NoError(t, err)
+	assert.True(t, matched)
+
+	matched, err = Match(`^\d{3}-\d{4}$`, []byte("555-12345"))
+	assert.NoError(t, err)
+	assert.False(t, matched)
+
+	_, err = MatchString(`(`, "x")
+	var ce *CompileError
+	assert.ErrorAs(t, err, &ce)
+
+	defer SetDefaultLimits(0, 0, 0)
+	SetDefaultLimits(100, 0, 0)
+	_, err = MatchString(`(a+)+$`, "aaaaaaaaaaaaaaab")
+	assert.ErrorIs(t, err, ErrMatchLimit)
+}
@@ -0,0 +1,137 @@
+package pcre2
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"iter"
+)
+
+// GrepOptions control Grep.
+type GrepOptions struct {
+	Invert   bool // select the lines which do not match
+	MaxCount int  // stop after this many selected lines, unless zero
+	Before   int  // number of context lines before each selected line
+	After    int  // number of context lines after each selected line
+	// MaxLineLength is the maximum length of a line in bytes, or
+	// bufio.MaxScanTokenSize if zero. A longer line ends the iteration
+	// with bufio.ErrTooLong.
+	MaxLineLength int
+}
+
+// GrepLine is a line reported by Grep.
+type GrepLine struct {
+	Number  int    // line number, starting at 1
+	Line    []byte // the line without its '\n'
+	Matches [][]int
+	// Context is set for context lines, which are reported because
+	// of GrepOptions.Before or After, but are not selected.
+	Context bool
+}
+
+// Grep reads lines from r and yields the lines matching re, like the
+// grep utility. For selected lines which match, Matches holds the start
+// and end of all matches in the line, as FindAllIndex returns them.
+// Lines are read one at a time, so input of any size can be scanned,
+// as long as no line exceeds GrepOptions.MaxLineLength.
+//
+// If reading or matching fails, the error is yielded with a zero
+// GrepLine and the iteration ends.
+func (re *Regexp) Grep(r io.Reader, opts GrepOptions) iter.Seq2[GrepLine, error] {
+	return func(yield func(GrepLine, error) bool) {
+		m := re.NewMatcher()
+		defer m.Free()
+		maxLine := opts.MaxLineLength
+		if maxLine <= 0 {
+			maxLine = bufio.MaxScanTokenSize
+		}
+		sc := bufio.NewScanner(r)
+		sc.Buffer(nil, maxLine+1) // room for the '\n'
+		sc.Split(scanLines)
+		var before []GrepLine
+		after, count := 0, 0
+		for number := 1; ; number++ {
+			if opts.MaxCount > 0 && count >= opts.MaxCount && after == 0 {
+				return
+			}
+			if !sc.Scan() {
+				if err := sc.Err(); err != nil {
+					yield(GrepLine{}, err)
+				}
+				return
+			}
+			line := sc.Bytes()
+			selecting := opts.MaxCount == 0 || count < opts.MaxCount
+			matched := false
+			var locs [][]int
+			err := iterateMatcher(m, line, 0, func(m *Matcher) bool {
+				matched = true
+				if opts.Invert || !selecting {
+					return false
+				}
+				start, end, _ := m.mData.offsets(0)
+				locs = append(locs, []int{int(start), int(end)})
+				return true
+			})
+			if err != nil {
+				yield(GrepLine{}, err)
+				return
+			}
+			gl := GrepLine{Number: number}
+			switch {
+			case matched != opts.Invert && selecting:
+				for _, b := range before {
+					if !yield(b, nil) {
+						return
+					}
+				}
+				before = before[:0]
+				count++
+				gl.Line = bytes.Clone(line)
+				gl.Matches = locs
+				if !yield(gl, nil) {
+					return
+				}
+				after = opts.After
+			case after > 0:
+				after--
+				gl.Line = bytes.Clone(line)
+				gl.Context = true
+				if !yield(gl, nil) {
+					return
+				}
+			case opts.Before > 0:
+				gl.Line = bytes.Clone(line)
+				gl.Context = true
+				if len(before) == opts.Before {
+					before = append(before[:0], before[1:]...)
+				}
+				before = append(before, gl)
+			}
+		}
+	}
+}
+
+// scanLines is a bufio.SplitFunc like bufio.ScanLines, but keeps a '\r'
+// at the end of lines, as the pattern may match it.
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// GrepAll is like Grep, but returns all reported lines at once.
+func (re *Regexp) GrepAll(r io.Reader, opts GrepOptions) ([]GrepLine, error) {
+	var lines []GrepLine
+	for line, err := range re.Grep(r, opts) {
+		if err != nil {
+			return lines, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
@@ -0,0 +1,86 @@
+package pcre2
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const grepInput = `one
+two ERROR x ERROR
+three
+four
+five ERROR
+six
+seven`
+
+// summary returns the line numbers, with a '-' for context lines.
+func summary(lines []GrepLine) []string {
+	var s []string
+	for _, l := range lines {
+		prefix := ""
+		if l.Context {
+			prefix = "-"
+		}
+		s = append(s, prefix+string(l.Line))
+	}
+	return s
+}
+
+func TestGrep(t *testing.T) {
+	re := MustCompile(`ERROR`, 0)
+	lines, err := re.GrepAll(strings.NewReader(grepInput), GrepOptions{})
+	assert.NoError(t, err)
+	if assert.Len(t, lines, 2) {
+		assert.Equal(t, 2, lines[0].Number)
+		assert.Equal(t, [][]int{{4, 9}, {12, 17}}, lines[0].Matches)
+		assert.Equal(t, 5, lines[1].Number)
+	}
+
+	lines, _ = re.GrepAll(strings.NewReader(grepInput), GrepOptions{Invert: true, MaxCount: 3})
+	assert.Equal(t, []string{"one", "three", "four"}, summary(lines))
+	assert.Nil(t, lines[0].Matches)
+
+	lines, _ = re.GrepAll(strings.NewReader(grepInput), GrepOptions{Before: 1, After: 1})
+	assert.Equal(t, []string{"-one", "two ERROR x ERROR", "-three", "-four", "five ERROR", "-six"}, summary(lines))
+
+	lines, _ = re.GrepAll(strings.NewReader(grepInput), GrepOptions{MaxCount: 1, After: 2})
+	assert.Equal(t, []string{"two ERROR x ERROR", "-three", "-four"}, summary(lines))
+
+	for line := range re.Grep(strings.NewReader(grepInput), GrepOptions{}) {
+		assert.Equal(t, 2, line.Number)
+		break
+	}
+}
+
+func TestGrepErrors(t *testing.T) {
+	errRead := errors.New("read failed")
+	r := io.MultiReader(strings.NewReader("a\nb\n"), iotest.ErrReader(errRead))
+	lines, err := MustCompile(`a`, 0).GrepAll(r, GrepOptions{})
+	assert.Equal(t, errRead, err)
+	assert.Len(t, lines, 1)
+
+	defer SetDefaultLimits(0, 0, 0)
+	SetDefaultLimits(100, 0, 0)
+	_, err = MustCompile(`(a+)+$`, 0).GrepAll(strings.NewReader("aaaaaaaaaaaaaaaaaab\n"), GrepOptions{})
+	assert.ErrorIs(t, err, ErrMatchLimit)
+}
+
+func TestGrepMaxLineLength(t *testing.T) {
+	re := MustCompile(`b`, 0)
+	defer re.Free()
+	input := "ab\n" + strings.Repeat("x", 100) + "b\nb\n"
+	lines, err := re.GrepAll(strings.NewReader(input), GrepOptions{MaxLineLength: 100})
+	assert.ErrorIs(t, err, bufio.ErrTooLong)
+	assert.Len(t, lines, 1)
+
+	lines, err = re.GrepAll(strings.NewReader(input), GrepOptions{MaxLineLength: 101})
+	assert.NoError(t, err)
+	assert.Len(t, lines, 3)
+	assert.Equal(t, [][]int{{100, 101}}, lines[1].Matches)
+}
@@ -0,0 +1,96 @@
+package pcre2
+
+/*
+#define PCRE2_CODE_UNIT_WIDTH 8
+
+#include <pcre2.h>
+*/
+import "C"
+
+import (
+	"unsafe"
+)
+
+// infoUint32 returns a pattern information item of type uint32_t.
+func (re *Regexp) infoUint32(what C.uint32_t, caller string) uint32 {
+	if re.ptr == nil {
+		panic("Regexp." + caller + ": uninitialized")
+	}
+	var value C.uint32_t
+	C.pcre2_pattern_info(re.ptr, what, unsafe.Pointer(&value))
+	return uint32(value)
+}
+
+// MinSubjectLength returns a lower bound for the length, in characters,
+// of any matching subject. Shorter subjects cannot match and can be
+// skipped without calling the matcher. It is zero if no bound is known.
+func (re *Regexp) MinSubjectLength() int {
+	return int(re.infoUint32(INFO_MINLENGTH, "MinSubjectLength"))
+}
+
+// MaxLookbehind returns the number of characters the longest lookbehind
+// assertion of the pattern looks back, i.e. how much context preceding
+// the start offset is needed when matching parts of a larger subject.
+func (re *Regexp) MaxLookbehind() int {
+	return int(re.infoUint32(INFO_MAXLOOKBEHIND, "MaxLookbehind"))
+}
+
+// BackrefMax returns the number of the highest back reference in the
+// pattern, or zero if there are none.
+func (re *Regexp) BackrefMax() int {
+	return int(re.infoUint32(INFO_BACKREFMAX, "BackrefMax"))
+}
+
+// MatchesEmpty reports whether the pattern can match an empty string.
+func (re *Regexp) MatchesEmpty() bool {
+	return re.infoUint32(INFO_MATCHEMPTY, "MatchesEmpty") != 0
+}
+
+// HasCRorLF reports whether the pattern contains explicit CR or LF
+// characters.
+func (re *Regexp) HasCRorLF() bool {
+	return re.infoUint32(INFO_HASCRORLF, "HasCRorLF") != 0
+}
+
+// Size returns the size of the compiled pattern in bytes, not including
+// JIT compiled code.
+func (re *Regexp) Size() int {
+	if re.ptr == nil {
+		panic("Regexp.Size: uninitialized")
+	}
+	return int(pcreSize(re.ptr))
+}
+
+// JITSize returns the size of the JIT compiled code of the pattern in
+// bytes, or zero if it has not been JIT compiled.
+func (re *Regexp) JITSize() int {
+	if re.ptr == nil {
+		panic("Regexp.JITSize: uninitialized")
+	}
+	return int(pcreJITSize(re.ptr))
+}
+
+// Options returns the compile options in effect for the pattern: those
+// passed to Compile, plus those set by the pattern itself, e.g. UTF by a
+// leading (*UTF). Options changed inside the pattern, e.g. by (?i), are
+// not included.
+func (re *Regexp) Options() uint32 {
+	return re.infoUint32(INFO_ALLOPTIONS, "Options")
+}
+
+// ArgOptions returns the compile options as passed to Compile.
+func (re *Regexp) ArgOptions() uint32 {
+	return re.infoUint32(INFO_ARGOPTIONS, "ArgOptions")
+}
+
+// ExtraOptions returns the extra compile options set with
+// CompileContext.SetExtraOptions, a combination of the EXTRA_* constants.
+func (re *Regexp) ExtraOptions() uint32 {
+	return re.infoUint32(INFO_EXTRAOPTIONS, "ExtraOptions")
+}
+
+// HasOptions reports whether all the given compile options, e.g.
+// UTF|UCP, are in effect for the pattern, see Options.
+func (re *Regexp) HasOptions(options uint32) bool {
+	return re.Options()&options == options
+}
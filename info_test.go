@@ -0,0 +1,59 @@
+package pcre2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatternInfo(t *testing.T) {
+	re := MustCompile(`(?<=ab)(c)d+\1`, 0)
+	defer re.Free()
+	assert.Equal(t, 3, re.MinSubjectLength())
+	assert.Equal(t, 2, re.MaxLookbehind())
+	assert.Equal(t, 1, re.BackrefMax())
+	assert.False(t, re.MatchesEmpty())
+	assert.False(t, re.HasCRorLF())
+
+	re = MustCompile(`a*\r\n`, 0)
+	defer re.Free()
+	assert.Equal(t, 2, re.MinSubjectLength())
+	assert.Zero(t, re.MaxLookbehind())
+	assert.Zero(t, re.BackrefMax())
+	assert.True(t, re.HasCRorLF())
+	re = MustCompile(`a*`, 0)
+	defer re.Free()
+	assert.True(t, re.MatchesEmpty())
+
+	assert.PanicsWithValue(t, "Regexp.MinSubjectLength: uninitialized", func() {
+		(&Regexp{}).MinSubjectLength()
+	})
+}
+
+func TestPatternSize(t *testing.T) {
+	re := MustCompile(`^(\w+)@(\w+)\.com$`, 0)
+	defer re.Free()
+	assert.NotZero(t, re.Size())
+	assert.Equal(t, re.code.size, int64(re.Size()))
+	assert.Zero(t, re.JITSize())
+	if re.JITCompile(JIT_COMPLETE) == nil {
+		assert.NotZero(t, re.JITSize())
+		assert.Equal(t, re.code.jitSize, int64(re.JITSize()))
+	}
+}
+
+func TestPatternOptions(t *testing.T) {
+	re := MustCompile(`(*UTF)a`, CASELESS)
+	defer re.Free()
+	assert.Equal(t, uint32(CASELESS), re.ArgOptions())
+	assert.True(t, re.HasOptions(UTF|CASELESS))
+	assert.False(t, re.HasOptions(UTF|UCP))
+	assert.NotZero(t, re.Options()&UTF)
+	assert.Zero(t, re.ExtraOptions())
+
+	re, err := CompileWithOptions(`a`, CompileOptions{ExtraOptions: EXTRA_MATCH_WORD})
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint32(EXTRA_MATCH_WORD), re.ExtraOptions())
+		re.Free()
+	}
+}
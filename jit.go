@@ -0,0 +1,201 @@
+package pcre2
+
+/*
+#define PCRE2_CODE_UNIT_WIDTH 8
+
+#include <pcre2.h>
+*/
+import "C"
+
+import (
+	"errors"
+	"log/slog"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// JITStack holds a stack for JIT compiled code. By default JIT matching
+// uses a small stack of 32K on the machine stack, which is not enough for
+// some large or heavily recursive patterns; those fail with
+// ERROR_JIT_STACKLIMIT. Assign a JITStack to a Matcher to give them more.
+//
+// A JITStack must not be used by more than one match at a time.
+type JITStack struct {
+	ptr     *C.pcre2_jit_stack
+	cleanup runtime.Cleanup
+}
+
+// NewJITStack creates a JIT stack which starts at startSize bytes
+// and may grow up to maxSize bytes.
+func NewJITStack(startSize, maxSize int) (*JITStack, error) {
+	if startSize <= 0 || maxSize < startSize {
+		return nil, errors.New("invalid JIT stack size")
+	}
+	ptr := C.pcre2_jit_stack_create(C.size_t(startSize), C.size_t(maxSize), generalContext)
+	if ptr == nil {
+		return nil, ErrNoMemory
+	}
+	s := &JITStack{ptr: ptr}
+	s.cleanup = addCleanup(s, freeJITStack, ptr)
+	return s, nil
+}
+
+func freeJITStack(ptr *C.pcre2_jit_stack) {
+	C.pcre2_jit_stack_free(ptr)
+}
+
+// own makes s owned by the package, like MatchContext.own.
+func (s *JITStack) own() {
+	s.cleanup.Stop()
+	s.cleanup = runtime.AddCleanup(s, freeJITStack, s.ptr)
+}
+
+// Free releases the underlying C resources. The stack must not be
+// freed while it is still assigned to a Matcher.
+func (s *JITStack) Free() error {
+	if s == nil || s.ptr == nil {
+		return nil
+	}
+	s.cleanup.Stop()
+	freeJITStack(s.ptr)
+	s.ptr = nil
+	return nil
+}
+
+// SetJITStack assigns a JIT stack to the matcher, which is used by all
+// subsequent JIT matches. A nil stack restores the default stack.
+// It replaces any pool set with SetJITStackPool.
+func (m *Matcher) SetJITStack(s *JITStack) {
+	m.jitPool = nil
+	m.jitStack = s
+	if m.private != nil {
+		m.private.SetJITStack(s)
+	}
+}
+
+// JITStackPool is a concurrency-safe pool of JIT stacks of the same size.
+// This is the pattern recommended by the PCRE2 JIT documentation for
+// multithreaded programs: every concurrent match gets a stack of its own,
+// without allocating a new stack for each match. The pool is backed by a
+// sync.Pool, which keeps stacks local to the running thread; unused
+// stacks are released by the garbage collector.
+type JITStackPool struct {
+	pool      sync.Pool
+	startSize int
+	maxSize   int
+}
+
+// NewJITStackPool creates a pool of JIT stacks which start at startSize
+// bytes and may grow up to maxSize bytes.
+func NewJITStackPool(startSize, maxSize int) (*JITStackPool, error) {
+	if startSize <= 0 || maxSize < startSize {
+		return nil, errors.New("invalid JIT stack size")
+	}
+	return &JITStackPool{startSize: startSize, maxSize: maxSize}, nil
+}
+
+// Get takes a stack from the pool, creating one if necessary.
+func (p *JITStackPool) Get() (*JITStack, error) {
+	if s, ok := p.pool.Get().(*JITStack); ok {
+		return s, nil
+	}
+	s, err := NewJITStack(p.startSize, p.maxSize)
+	if err != nil {
+		return nil, err
+	}
+	// The pool drops unused stacks.
+	s.own()
+	return s, nil
+}
+
+// Put returns a stack obtained by Get to the pool.
+func (p *JITStackPool) Put(s *JITStack) {
+	if s != nil && s.ptr != nil {
+		p.pool.Put(s)
+	}
+}
+
+// SetJITStackPool makes the matcher take a JIT stack from the pool for
+// each match, and return it afterwards. This allows many matchers to
+// share a few stacks. A nil pool restores the default stack.
+// It replaces any stack set with SetJITStack.
+func (m *Matcher) SetJITStackPool(p *JITStackPool) {
+	m.SetJITStack(nil)
+	m.jitPool = p
+}
+
+// acquireJITStack assigns a stack from the pool of m for a single match.
+// If no stack can be allocated, the default stack is used.
+func (m *Matcher) acquireJITStack() *JITStack {
+	s, err := m.jitPool.Get()
+	if err != nil {
+		return nil
+	}
+	m.privateContext().SetJITStack(s)
+	return s
+}
+
+func (m *Matcher) releaseJITStack(s *JITStack) {
+	if s != nil {
+		m.private.SetJITStack(nil)
+		m.jitPool.Put(s)
+	}
+}
+
+// JITFreeUnusedMemory returns executable memory of the JIT compiler
+// which is no longer needed to the system. Long-running services can
+// call this periodically, e.g. after freeing many JIT compiled patterns.
+func JITFreeUnusedMemory() {
+	C.pcre2_jit_free_unused_memory(nil)
+}
+
+// autoJIT holds the state of lazy JIT compilation for a Regexp.
+type autoJIT struct {
+	threshold int64
+	matches   atomic.Int64
+	done      atomic.Bool
+	// Matches hold a read lock until the JIT compilation is done,
+	// because the code must not be JIT compiled while it is in use.
+	mu sync.RWMutex
+}
+
+// CompileAutoJIT compiles the pattern like Compile, and enables AutoJIT
+// on the result.
+func CompileAutoJIT(pattern string, flags uint32, threshold int) (*Regexp, error) {
+	re, err := Compile(pattern, flags)
+	if err == nil {
+		re.AutoJIT(threshold)
+	}
+	return re, err
+}
+
+// AutoJIT makes the Regexp count its matches and transparently JIT
+// compile itself once it has been used more than threshold times.
+// This gives JIT performance for hot patterns, without paying the
+// cost of JIT compilation for patterns which are only used once.
+// If JIT compilation fails, matching continues without JIT.
+// AutoJIT must be called before the Regexp is used for matching.
+func (re *Regexp) AutoJIT(threshold int) {
+	re.autoJIT = &autoJIT{threshold: int64(threshold)}
+}
+
+// count records a match of re, and JIT compiles it when the threshold
+// is exceeded. The returned function must be called after the match.
+func (aj *autoJIT) count(re *Regexp) func() {
+	if aj.matches.Add(1) > aj.threshold {
+		aj.mu.Lock()
+		if !aj.done.Load() {
+			// Logged once, as the fallback rather than a failure.
+			if err := re.jitCompile(JIT_COMPLETE); err != nil {
+				logEvent(slog.LevelInfo, "pcre2: AutoJIT falls back to the interpreter",
+					slog.String("pattern", re.Pattern), slog.Any("error", err))
+			}
+			aj.done.Store(true)
+		}
+		aj.mu.Unlock()
+		return func() {}
+	}
+	aj.mu.RLock()
+	return aj.mu.RUnlock
+}
@@ -0,0 +1,125 @@
+package pcre2
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJITStack(t *testing.T) {
+	re, err := CompileJIT(`^(a|b)*$`, 0, JIT_COMPLETE)
+	if err != nil {
+		t.Skip("JIT not available:", err)
+	}
+	defer re.Free()
+	subject := bytes.Repeat([]byte("ab"), 200000)
+
+	m := re.NewMatcher()
+	assert.Equal(t, ERROR_JIT_STACKLIMIT, m.Exec(subject, 0))
+
+	s, err := NewJITStack(32*1024, 16*1024*1024)
+	if !assert.NoError(t, err) {
+		return
+	}
+	m.SetJITStack(s)
+	assert.True(t, m.Match(subject, 0))
+
+	m.SetJITStack(nil)
+	assert.Equal(t, ERROR_JIT_STACKLIMIT, m.Exec(subject, 0))
+	assert.NoError(t, s.Free())
+
+	_, err = NewJITStack(1024, 512)
+	assert.Error(t, err)
+}
+
+func TestJITStackPool(t *testing.T) {
+	re, err := CompileJIT(`^(a|b)*$`, 0, JIT_COMPLETE)
+	if err != nil {
+		t.Skip("JIT not available:", err)
+	}
+	defer re.Free()
+	subject := bytes.Repeat([]byte("ab"), 200000)
+
+	pool, err := NewJITStackPool(32*1024, 16*1024*1024)
+	if !assert.NoError(t, err) {
+		return
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m := re.NewMatcher()
+			defer m.Free()
+			m.SetJITStackPool(pool)
+			for j := 0; j < 3; j++ {
+				assert.True(t, m.Match(subject, 0))
+			}
+		}()
+	}
+	wg.Wait()
+
+	m := re.NewMatcher()
+	m.SetJITStackPool(pool)
+	m.SetJITStackPool(nil)
+	assert.Equal(t, ERROR_JIT_STACKLIMIT, m.Exec(subject, 0))
+}
+
+func TestAutoJIT(t *testing.T) {
+	if _, err := CompileJIT(`a`, 0, JIT_COMPLETE); err != nil {
+		t.Skip("JIT not available:", err)
+	}
+	re, err := CompileAutoJIT(`(\d+)-(\d+)`, 0, 2)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer re.Free()
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, "3", re.MatcherString("1-3", 0).GroupString(2))
+		assert.Zero(t, re.code.jitSize)
+	}
+	assert.Equal(t, "4", re.MatcherString("1-4", 0).GroupString(2))
+	assert.NotZero(t, re.code.jitSize)
+	assert.Equal(t, "5", re.MatcherString("1-5", 0).GroupString(2))
+}
+
+func TestAutoJITSubstitute(t *testing.T) {
+	if _, err := CompileJIT(`a`, 0, JIT_COMPLETE); err != nil {
+		t.Skip("JIT not available:", err)
+	}
+	re, err := CompileAutoJIT(`\d+`, 0, 2)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer re.Free()
+	m := re.NewMatcher()
+	defer m.Free()
+
+	for i := 0; i < 2; i++ {
+		out, _, err := m.SubstituteString("a1b22", "#", SUBSTITUTE_GLOBAL)
+		assert.NoError(t, err)
+		assert.Equal(t, "a#b#", out)
+		assert.Zero(t, re.code.jitSize)
+	}
+	out, _, err := m.SubstituteString("a1b22", "#", SUBSTITUTE_GLOBAL)
+	assert.NoError(t, err)
+	assert.Equal(t, "a#b#", out)
+	assert.NotZero(t, re.code.jitSize)
+}
+
+func TestJITFreeUnusedMemory(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		re, err := CompileJIT(`(foo|bar)+\d`, 0, JIT_COMPLETE)
+		if err != nil {
+			t.Skip("JIT not available:", err)
+		}
+		re.Free()
+	}
+	JITFreeUnusedMemory()
+	re := MustCompileJIT(`x+`, 0, JIT_COMPLETE)
+	defer re.Free()
+	assert.Equal(t, "xx", re.FindString("axxb", 0))
+}
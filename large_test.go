@@ -0,0 +1,75 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package pcre2
+
+import (
+	"math"
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// hugeSubject returns a subject of more than 2 GiB of zero bytes with
+// text written at offset. It is mapped anonymously, so only the pages
+// which are written use memory.
+func hugeSubject(t *testing.T, offset int64, text string) []byte {
+	if testing.Short() {
+		t.Skip("skipping huge subject in short mode")
+	}
+	if math.MaxInt == math.MaxInt32 {
+		t.Skip("subjects over 2 GiB need 64-bit int")
+	}
+	const size = 1<<31 + 1<<20
+	data, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		t.Skipf("cannot map %d bytes: %v", size, err)
+	}
+	t.Cleanup(func() { syscall.Munmap(data) })
+	copy(data[offset:], text)
+	return data
+}
+
+func TestHugeSubject(t *testing.T) {
+	const at = 1<<31 + 12345
+	subject := hugeSubject(t, at, "needle in a haystack")
+
+	re := MustCompile(`(ne+)dle`, 0)
+	defer re.Free()
+	assert.Equal(t, []int64{at, at + 6}, re.FindIndex64(subject, 0))
+	assert.Equal(t, []int{at, at + 6}, re.FindIndex(subject, 0))
+	str := unsafe.String(unsafe.SliceData(subject), len(subject))
+	assert.Equal(t, []int64{at, at + 6}, re.FindStringIndex64(str, 0))
+
+	m := re.Matcher(subject, 0)
+	defer m.Free()
+	assert.True(t, m.Matches())
+	assert.Equal(t, []int64{at, at + 6}, m.Index64())
+	assert.Equal(t, []int64{at, at + 3}, m.GroupIndices64(1))
+	assert.Equal(t, []int{at, at + 3}, m.GroupIndices(1))
+	start, end, ok := m.OffsetPair(1)
+	assert.True(t, ok)
+	assert.Equal(t, int64(at), start)
+	assert.Equal(t, int64(at+3), end)
+	assert.Equal(t, "nee", m.GroupString(1))
+
+	// Starting beyond 2 GiB.
+	assert.True(t, m.MatchWithOptions(subject, MatchOptions{Offset: at}))
+	assert.Equal(t, []int64{at, at + 6}, m.Index64())
+	assert.False(t, m.MatchWithOptions(subject, MatchOptions{Offset: at + 1}))
+	assert.Nil(t, m.Index64())
+
+	locs := re.FindAllIndex(subject, 0, -1)
+	assert.Equal(t, [][]int{{at, at + 6}}, locs)
+}
+
+func TestHugeSubjectLiteral(t *testing.T) {
+	const at = 1<<31 + 1<<19
+	subject := hugeSubject(t, at, "haystack")
+
+	re := MustCompile(`haystack`, 0)
+	defer re.Free()
+	assert.Equal(t, []int64{at, at + 8}, re.FindIndex64(subject, 0))
+	assert.Equal(t, []int{at, at + 8}, re.FindIndex(subject, 0))
+}
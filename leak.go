@@ -0,0 +1,127 @@
+package pcre2
+
+import (
+	"log"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Allocation describes a Regexp or Matcher tracked by leak detection.
+type Allocation struct {
+	Kind    string // "Regexp" or "Matcher"
+	Pattern string // the pattern of the Regexp
+	Stack   string // the call stack which created the object
+}
+
+var (
+	leakDetection atomic.Bool
+	leaks         struct {
+		sync.Mutex
+		report func(Allocation)
+		live   map[*Allocation]struct{}
+	}
+)
+
+// SetLeakDetector enables leak detection: Regexps and Matchers created
+// afterwards are tracked, and report is called for every one which is
+// garbage collected without having been freed or closed. Its C resources
+// are freed then, even if SetAutoCleanup disabled that. LogLeaks is a
+// ready-made report function. A nil report disables leak detection.
+//
+// Tracking records the call stack of every allocation, so it is meant
+// for tests and debugging rather than production.
+func SetLeakDetector(report func(Allocation)) {
+	leaks.Lock()
+	defer leaks.Unlock()
+	leaks.report = report
+	if report != nil && leaks.live == nil {
+		leaks.live = make(map[*Allocation]struct{})
+	}
+	leakDetection.Store(report != nil)
+}
+
+// LogLeaks reports a leaked allocation to the standard logger.
+func LogLeaks(a Allocation) {
+	log.Printf("pcre2: %s for %q was garbage collected without Free, created at:\n%s", a.Kind, a.Pattern, a.Stack)
+}
+
+// LiveAllocations returns the tracked objects which have been neither
+// freed nor garbage collected, e.g. to check for leaks at the end of a
+// test.
+func LiveAllocations() []Allocation {
+	leaks.Lock()
+	defer leaks.Unlock()
+	live := make([]Allocation, 0, len(leaks.live))
+	for a := range leaks.live {
+		live = append(live, *a)
+	}
+	return live
+}
+
+// track starts tracking a new object if leak detection is enabled, and
+// returns nil otherwise.
+func track(kind, pattern string) *Allocation {
+	if !leakDetection.Load() {
+		return nil
+	}
+	a := &Allocation{Kind: kind, Pattern: pattern, Stack: callers()}
+	leaks.Lock()
+	leaks.live[a] = struct{}{}
+	leaks.Unlock()
+	return a
+}
+
+// callers formats the call stack outside of this package.
+func callers() string {
+	pcs := make([]uintptr, 32)
+	pcs = pcs[:runtime.Callers(3, pcs)]
+	frames := runtime.CallersFrames(pcs)
+	var b strings.Builder
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, "github.com/Jemmic/go-pcre2.") || strings.HasSuffix(f.File, "_test.go") {
+			b.WriteString(f.Function + "\n\t" + f.File + ":" + strconv.Itoa(f.Line) + "\n")
+		}
+		if !more {
+			return b.String()
+		}
+	}
+}
+
+// untrack stops tracking an object which has been freed.
+func untrack(a *Allocation) {
+	if a == nil {
+		return
+	}
+	leaks.Lock()
+	delete(leaks.live, a)
+	leaks.Unlock()
+}
+
+// leaked reports an object which has been garbage collected.
+func leaked(a *Allocation) {
+	if a == nil {
+		return
+	}
+	leaks.Lock()
+	delete(leaks.live, a)
+	report := leaks.report
+	leaks.Unlock()
+	if report != nil {
+		report(*a)
+	}
+}
+
+// Close implements io.Closer by calling Free.
+func (re *Regexp) Close() error {
+	return re.Free()
+}
+
+// Close implements io.Closer by calling Free.
+func (m *Matcher) Close() error {
+	m.Free()
+	return nil
+}
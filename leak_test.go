@@ -0,0 +1,81 @@
+package pcre2
+
+import (
+	"io"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	_ io.Closer = (*Regexp)(nil)
+	_ io.Closer = (*Matcher)(nil)
+)
+
+func TestLeakDetector(t *testing.T) {
+	var mu sync.Mutex
+	var reported []Allocation
+	SetLeakDetector(func(a Allocation) {
+		mu.Lock()
+		reported = append(reported, a)
+		mu.Unlock()
+	})
+	defer SetLeakDetector(nil)
+
+	closed := MustCompile(`closed`, 0)
+	m := closed.NewMatcher()
+	assert.Len(t, LiveAllocations(), 2)
+	assert.NoError(t, m.Close())
+	assert.NoError(t, closed.Close())
+	assert.Empty(t, LiveAllocations())
+
+	func() {
+		MustCompile(`leaked`, 0)
+	}()
+	live := LiveAllocations()
+	if assert.Len(t, live, 1) {
+		assert.Equal(t, "Regexp", live[0].Kind)
+		assert.Equal(t, "leaked", live[0].Pattern)
+		assert.Contains(t, live[0].Stack, "leak_test.go")
+	}
+	assert.True(t, waitFor(func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(reported) == 1
+	}))
+	assert.Equal(t, "leaked", reported[0].Pattern)
+	assert.Empty(t, LiveAllocations())
+}
+
+func TestLeakDetectorInit(t *testing.T) {
+	var mu sync.Mutex
+	var reported []Allocation
+	SetLeakDetector(func(a Allocation) {
+		mu.Lock()
+		reported = append(reported, a)
+		mu.Unlock()
+	})
+	defer SetLeakDetector(nil)
+
+	re1 := MustCompile(`first`, 0)
+	re2 := MustCompile(`second`, 0)
+	m := re1.NewMatcher()
+	m.Init(re2)
+	var live []string
+	for _, a := range LiveAllocations() {
+		live = append(live, a.Kind+" "+a.Pattern)
+	}
+	assert.ElementsMatch(t, []string{"Regexp first", "Regexp second", "Matcher second"}, live)
+	m.Free()
+	re1.Free()
+	re2.Free()
+	assert.Empty(t, LiveAllocations())
+	runtime.GC()
+	time.Sleep(10 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Empty(t, reported)
+}
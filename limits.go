@@ -0,0 +1,103 @@
+package pcre2
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrSubjectTooLong is returned by GetError when a match was refused
+// because the subject exceeds the configured maximum length.
+var ErrSubjectTooLong = errors.New("subject exceeds maximum length")
+
+// ErrTimeout is returned by GetError when a match was abandoned because
+// its deadline or timeout passed. Like the timeouts of package net, it
+// has a Timeout method returning true.
+var ErrTimeout error = timeoutError{}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "match deadline exceeded" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// packageErrors maps the error codes of this package to their errors.
+var packageErrors = map[int]error{
+	ERROR_SUBJECT_TOO_LONG: ErrSubjectTooLong,
+	ERROR_TIMEOUT:          ErrTimeout,
+}
+
+var defaultMaxSubjectLength atomic.Int64
+
+// defaultLimits holds the limits set with SetDefaultLimits, or nil.
+var defaultLimits atomic.Pointer[MatchContext]
+
+// SetDefaultLimits sets the match, depth and heap limits, see
+// MatchContext, for all matches of Matchers without a MatchContext of
+// their own. This enforces a global budget against runaway patterns
+// without changing every call site. Zero leaves the library default of
+// a limit in place. Matches already running are not affected.
+func SetDefaultLimits(matchLimit, depthLimit, heapLimit uint32) {
+	if matchLimit == 0 && depthLimit == 0 && heapLimit == 0 {
+		defaultLimits.Store(nil)
+		return
+	}
+	// Matches may still use a replaced context.
+	mc := NewMatchContext()
+	mc.own()
+	if matchLimit != 0 {
+		mc.SetMatchLimit(matchLimit)
+	}
+	if depthLimit != 0 {
+		mc.SetDepthLimit(depthLimit)
+	}
+	if heapLimit != 0 {
+		mc.SetHeapLimit(heapLimit)
+	}
+	defaultLimits.Store(mc)
+}
+
+// matchContext returns the context for the matches of m. This is its
+// private context if it is still current or m has settings of its own,
+// and otherwise the shared context.
+func (m *Matcher) matchContext() *MatchContext {
+	base := m.sharedContext()
+	if m.jitStack != nil || m.private != nil && m.privateCurrent(base) {
+		return m.privateContext()
+	}
+	return base
+}
+
+// sharedContext returns the context set for m, which is the package
+// default if m has none.
+func (m *Matcher) sharedContext() *MatchContext {
+	if m.mctx != nil {
+		return m.mctx
+	}
+	return defaultLimits.Load()
+}
+
+// SetMaxSubjectLength sets the maximum subject length in bytes for all
+// Regexps which do not set their own limit. Matching a longer subject
+// fails immediately with ERROR_SUBJECT_TOO_LONG, instead of handing a
+// huge buffer to PCRE2. Zero, the default, means no limit.
+func SetMaxSubjectLength(n int) {
+	defaultMaxSubjectLength.Store(int64(n))
+}
+
+// SetMaxSubjectLength sets the maximum subject length in bytes for
+// matches of this Regexp, overriding the package default. Zero means
+// the package default applies. It must be called before the Regexp is
+// used for matching.
+func (re *Regexp) SetMaxSubjectLength(n int) {
+	re.maxSubjectLength = n
+}
+
+// subjectTooLong reports whether a subject of the given length exceeds
+// the maximum length for re.
+func (re *Regexp) subjectTooLong(length int) bool {
+	limit := int64(re.maxSubjectLength)
+	if limit == 0 {
+		limit = defaultMaxSubjectLength.Load()
+	}
+	return limit > 0 && int64(length) > limit
+}
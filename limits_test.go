@@ -0,0 +1,47 @@
+package pcre2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxSubjectLength(t *testing.T) {
+	re := MustCompile(`b`, 0)
+	re.SetMaxSubjectLength(3)
+	m := re.MatcherString("abcd", 0)
+	assert.False(t, m.Matches())
+	assert.True(t, m.HasError())
+	assert.Equal(t, ErrSubjectTooLong, m.GetError())
+	assert.True(t, m.MatchString("abc", 0))
+	assert.Nil(t, re.FindAllString("abcd", 0, -1))
+
+	defer SetMaxSubjectLength(0)
+	SetMaxSubjectLength(2)
+	other := MustCompile(`b`, 0)
+	assert.Equal(t, ERROR_SUBJECT_TOO_LONG, other.NewMatcher().Exec([]byte("abc"), 0))
+	assert.True(t, re.MatcherString("abc", 0).Matches())
+}
+
+func TestDefaultLimits(t *testing.T) {
+	re := MustCompile(`(a+)+$`, 0)
+	subject := "aaaaaaaaaaaaaaab"
+
+	defer SetDefaultLimits(0, 0, 0)
+	SetDefaultLimits(1000, 0, 0)
+	m := re.NewMatcher()
+	assert.False(t, m.MatchString(subject, 0))
+	assert.ErrorIs(t, m.GetError(), ErrMatchLimit)
+
+	// A context of the matcher overrides the defaults.
+	m.SetMatchContext(NewMatchContext())
+	assert.Equal(t, ERROR_NOMATCH, m.ExecString(subject, 0))
+
+	// Deadlines start from the default limits.
+	opts := MatchOptions{Timeout: time.Minute}
+	assert.Equal(t, ERROR_MATCHLIMIT, re.NewMatcher().ExecStringOptions(subject, opts))
+
+	SetDefaultLimits(0, 0, 0)
+	assert.Equal(t, ERROR_NOMATCH, re.NewMatcher().ExecString(subject, 0))
+}
@@ -0,0 +1,23 @@
+//go:build pcre2_linkstatic
+
+package pcre2
+
+// With the pcre2_linkstatic build tag the system library is linked
+// statically, and so is the whole binary. This is meant for musl based
+// systems such as Alpine, where the result runs in scratch images:
+//
+//	apk add build-base pcre2-dev
+//	go build -tags pcre2_linkstatic
+//
+// With glibc, static binaries work as well, but the linker warns about
+// the locale functions used by MakeTables. On Windows, the static
+// library of MSYS2 is used, which needs PCRE2_STATIC to be defined.
+
+/*
+#cgo pkg-config: --static libpcre2-8
+#cgo windows CFLAGS: -DPCRE2_STATIC
+#cgo LDFLAGS: -static
+*/
+import "C"
+
+const linkage = "static"
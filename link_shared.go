@@ -0,0 +1,15 @@
+//go:build !pcre2_linkstatic && !(windows && pcre2_vcpkg)
+
+package pcre2
+
+// By default the package links against the system library, found with
+// pkg-config. On Windows this is the layout of MSYS2, after
+//
+//	pacman -S mingw-w64-x86_64-pcre2 mingw-w64-x86_64-pkg-config
+
+/*
+#cgo pkg-config: libpcre2-8
+*/
+import "C"
+
+const linkage = "shared"
@@ -0,0 +1,25 @@
+//go:build windows && pcre2_vcpkg && !pcre2_linkstatic
+
+package pcre2
+
+// With the pcre2_vcpkg build tag on Windows, the package links the static
+// library installed by vcpkg in its default location for MinGW, which is
+// the toolchain cgo uses:
+//
+//	vcpkg install pcre2:x64-mingw-static
+//	go build -tags pcre2_vcpkg
+//
+// For vcpkg installations elsewhere, build without the tag and point
+// PKG_CONFIG_PATH to the lib/pkgconfig directory of the triplet.
+
+/*
+#cgo CFLAGS: -DPCRE2_STATIC
+#cgo windows,amd64 CFLAGS: -IC:/vcpkg/installed/x64-mingw-static/include
+#cgo windows,amd64 LDFLAGS: -LC:/vcpkg/installed/x64-mingw-static/lib
+#cgo windows,arm64 CFLAGS: -IC:/vcpkg/installed/arm64-mingw-static/include
+#cgo windows,arm64 LDFLAGS: -LC:/vcpkg/installed/arm64-mingw-static/lib
+#cgo LDFLAGS: -lpcre2-8
+*/
+import "C"
+
+const linkage = "static"
@@ -0,0 +1,73 @@
+package pcre2
+
+/*
+#define PCRE2_CODE_UNIT_WIDTH 8
+
+#include <pcre2.h>
+*/
+import "C"
+
+import (
+	"bytes"
+	"strings"
+	"unsafe"
+)
+
+// Matches of literal patterns, i.e. patterns compiled with LITERAL or
+// without any metacharacters, are found with strings.Index in Go,
+// instead of calling PCRE2. This avoids the cost of cgo for the common
+// case of filtering by a fixed string.
+
+// metachars are the characters which may make a pattern other than a
+// literal string. Compile flags and compile contexts are checked
+// separately.
+const metachars = `\^$.[]|()?*+{}`
+
+// isLiteral reports whether the pattern compiled with flags and cc
+// matches exactly its own text, with the same results as PCRE2. Options
+// like CASELESS or UTF, which change what matches or how subjects are
+// checked, and all compile contexts rule out the fast path.
+func isLiteral[S subject](pattern S, flags uint32, cc *CompileContext) bool {
+	if cc != nil || flags&^LITERAL != 0 {
+		return false
+	}
+	if flags&LITERAL != 0 {
+		return true
+	}
+	return !strings.ContainsAny(string(pattern), metachars)
+}
+
+// literalIndex returns the index of the first occurrence of the literal
+// pattern in subject at or after offset, or -1.
+func literalIndex[S subject](pattern string, subject S, offset int) int {
+	var i int
+	switch s := any(subject).(type) {
+	case string:
+		i = strings.Index(s[offset:], pattern)
+	case []byte:
+		i = bytes.Index(s[offset:], unsafe.Slice(unsafe.StringData(pattern), len(pattern)))
+	}
+	if i < 0 {
+		return -1
+	}
+	return offset + i
+}
+
+// canMatchLiteral reports whether a match of m can take the fast path.
+// Match flags, callouts and invalid offsets are left to PCRE2.
+func (m *Matcher) canMatchLiteral(length, offset int, flags uint32) bool {
+	return m.re.literal && flags == 0 && m.callout == nil && m.mData != nil &&
+		offset >= 0 && offset <= length
+}
+
+// execLiteral matches the literal pattern of m, filling in the ovector
+// like pcre2_match.
+func execLiteral[S subject](m *Matcher, subject S, offset int) int {
+	i := literalIndex(m.re.Pattern, subject, offset)
+	if i < 0 {
+		return ERROR_NOMATCH
+	}
+	m.mData.ovector[0] = C.PCRE2_SIZE(i)
+	m.mData.ovector[1] = C.PCRE2_SIZE(i + len(m.re.Pattern))
+	return 1
+}
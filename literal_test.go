@@ -0,0 +1,76 @@
+package pcre2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsLiteral(t *testing.T) {
+	assert.True(t, isLiteral("error: disk full", 0, nil))
+	assert.True(t, isLiteral("", 0, nil))
+	assert.True(t, isLiteral("a.b(c", LITERAL, nil))
+	assert.False(t, isLiteral("a.b", 0, nil))
+	assert.False(t, isLiteral("(*UTF)abc", 0, nil))
+	assert.False(t, isLiteral("abc", CASELESS, nil))
+	assert.False(t, isLiteral("abc", LITERAL|UTF, nil))
+	assert.False(t, isLiteral("abc", 0, NewCompileContext()))
+}
+
+func TestLiteralFastPath(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		flags   uint32
+		subject string
+	}{
+		{"needle", 0, "haystack with a needle in it"},
+		{"needle", 0, "haystack"},
+		{"a+b", LITERAL, "aab a+b"},
+		{"", 0, "abc"},
+		{"abc", 0, ""},
+	} {
+		fast := MustCompile(tc.pattern, tc.flags)
+		assert.True(t, fast.literal, tc.pattern)
+		slow := MustCompile(tc.pattern, tc.flags)
+		slow.literal = false
+
+		assert.Equal(t, slow.FindStringIndex(tc.subject, 0), fast.FindStringIndex(tc.subject, 0))
+		assert.Equal(t, slow.FindIndex([]byte(tc.subject), 0), fast.FindIndex([]byte(tc.subject), 0))
+		assert.Equal(t, slow.FindAllString(tc.subject, 0, -1), fast.FindAllString(tc.subject, 0, -1))
+
+		fm, sm := fast.NewMatcher(), slow.NewMatcher()
+		for offset := 0; offset <= len(tc.subject)+1; offset++ {
+			opts := MatchOptions{Offset: offset}
+			assert.Equal(t, sm.ExecStringOptions(tc.subject, opts), fm.ExecStringOptions(tc.subject, opts))
+			assert.Equal(t, sm.Index(), fm.Index())
+		}
+		if assert.Equal(t, sm.MatchString(tc.subject, 0), fm.MatchString(tc.subject, 0)) && fm.Matches() {
+			assert.Equal(t, sm.GroupString(0), fm.GroupString(0))
+		}
+	}
+}
+
+func TestLiteralFastPathLimits(t *testing.T) {
+	re := MustCompile("b", 0)
+	re.SetMaxSubjectLength(2)
+	assert.Nil(t, re.FindStringIndex("abc", 0))
+	assert.Equal(t, ERROR_SUBJECT_TOO_LONG, re.NewMatcher().ExecString("abc", 0))
+	assert.Equal(t, []int{1, 2}, re.FindStringIndex("ab", 0))
+}
+
+func BenchmarkLiteral(b *testing.B) {
+	re := MustCompile("needle", 0)
+	subject := "a long haystack with a needle somewhere in the middle of it"
+	b.Run("fast", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			re.FindStringIndex(subject, 0)
+		}
+	})
+	slow := MustCompile("needle", 0)
+	slow.literal = false
+	b.Run("pcre2", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			slow.FindStringIndex(subject, 0)
+		}
+	})
+}
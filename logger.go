@@ -0,0 +1,73 @@
+package pcre2
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+var logger atomic.Pointer[slog.Logger]
+
+// SetLogger sets the logger which receives the operational events of
+// the package, so that issues like missing JIT support on a host become
+// visible. A nil logger, the default, disables logging. The events are:
+//
+//   - "pcre2: compile failed" (warning): a pattern did not compile.
+//     Patterns compiled by SafeCompile or checked by ValidatePattern
+//     come from untrusted users and are not logged.
+//   - "pcre2: JIT compile failed" (warning): JITCompile failed, e.g.
+//     because the library was built without JIT support.
+//   - "pcre2: AutoJIT falls back to the interpreter" (info): a Regexp
+//     with AutoJIT could not be JIT compiled and keeps matching without.
+//     This replaces the "JIT compile failed" event.
+//   - "pcre2: limit hit" (warning): a match was abandoned because of a
+//     match, depth, heap or JIT stack limit, a timeout or the maximum
+//     subject length.
+//   - "pcre2: freed by garbage collector" (debug): the C resources of a
+//     Regexp or Matcher which was not freed were released by its
+//     cleanup, see SetAutoCleanup.
+//
+// The events carry the pattern and error as attributes where known.
+func SetLogger(l *slog.Logger) {
+	logger.Store(l)
+}
+
+// logEvent logs an event if a logger is set.
+func logEvent(level slog.Level, msg string, attrs ...slog.Attr) {
+	if l := logger.Load(); l != nil {
+		l.LogAttrs(context.Background(), level, msg, attrs...)
+	}
+}
+
+// logCompile logs the compilation of pattern if it failed.
+func logCompile(pattern string, err *error) {
+	if *err != nil {
+		logEvent(slog.LevelWarn, "pcre2: compile failed",
+			slog.String("pattern", pattern), slog.Any("error", *err))
+	}
+}
+
+// limitHit reports whether rc means that a match was abandoned because
+// of a limit.
+func limitHit(rc int) bool {
+	// ERROR_HEAPLIMIT is zero with old headers, see pcre2_fallback.h.
+	if rc >= 0 {
+		return false
+	}
+	switch rc {
+	case ERROR_MATCHLIMIT, ERROR_DEPTHLIMIT, ERROR_HEAPLIMIT, ERROR_JIT_STACKLIMIT,
+		ERROR_TIMEOUT, ERROR_SUBJECT_TOO_LONG:
+		return true
+	}
+	return false
+}
+
+// logLimit logs the match of re if it hit a limit.
+func logLimit(re *Regexp, offset int, rc *int) {
+	if limitHit(*rc) {
+		logEvent(slog.LevelWarn, "pcre2: limit hit",
+			slog.String("pattern", re.Pattern),
+			slog.Int("offset", offset),
+			slog.Any("error", matchError(*rc)))
+	}
+}
@@ -0,0 +1,130 @@
+package pcre2
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordHandler is a slog.Handler which keeps the records it receives.
+type recordHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *recordHandler) WithAttrs([]slog.Attr) slog.Handler       { return h }
+func (h *recordHandler) WithGroup(string) slog.Handler            { return h }
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r)
+	return nil
+}
+
+// find returns the attributes of the first record with the message.
+func (h *recordHandler) find(msg string) (map[string]any, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, r := range h.records {
+		if r.Message == msg {
+			attrs := map[string]any{"level": r.Level}
+			r.Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a.Value.Any()
+				return true
+			})
+			return attrs, true
+		}
+	}
+	return nil, false
+}
+
+func TestSetLogger(t *testing.T) {
+	h := &recordHandler{}
+	SetLogger(slog.New(h))
+	defer SetLogger(nil)
+
+	_, err := Compile(`a(`, 0)
+	attrs, ok := h.find("pcre2: compile failed")
+	if assert.True(t, ok) {
+		assert.Equal(t, slog.LevelWarn, attrs["level"])
+		assert.Equal(t, "a(", attrs["pattern"])
+		assert.Equal(t, err, attrs["error"])
+	}
+
+	re := MustCompile(`(a+)+$`, 0)
+	defer re.Free()
+	err = re.JITCompile(1 << 20)
+	assert.Error(t, err)
+	attrs, ok = h.find("pcre2: JIT compile failed")
+	if assert.True(t, ok) {
+		assert.Equal(t, err, attrs["error"])
+	}
+
+	m := re.NewMatcher()
+	defer m.Free()
+	mc := NewMatchContext()
+	defer mc.Free()
+	mc.SetMatchLimit(1)
+	m.SetMatchContext(mc)
+	assert.False(t, m.MatchString("aaaaaaaaaaaaaaaaaaaab", 0))
+	attrs, ok = h.find("pcre2: limit hit")
+	if assert.True(t, ok) {
+		assert.Equal(t, `(a+)+$`, attrs["pattern"])
+		assert.ErrorIs(t, attrs["error"].(error), ErrMatchLimit)
+	}
+
+	func() {
+		re := MustCompile(`garbage`, 0)
+		re.NewMatcher()
+	}()
+	assert.True(t, waitFor(func() bool {
+		_, ok := h.find("pcre2: freed by garbage collector")
+		return ok
+	}))
+
+	SetLogger(nil)
+	Compile(`(b`, 0)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, r := range h.records {
+		r.Attrs(func(a slog.Attr) bool {
+			assert.NotEqual(t, "(b", a.Value.String())
+			return true
+		})
+	}
+}
+
+func TestLoggerUntrustedPatterns(t *testing.T) {
+	h := &recordHandler{}
+	SetLogger(slog.New(h))
+	defer SetLogger(nil)
+
+	_, err := SafeCompile(`a(`, 0)
+	assert.Error(t, err)
+	assert.Error(t, ValidatePattern(`a(`, 0, DefaultLimits))
+	_, ok := h.find("pcre2: compile failed")
+	assert.False(t, ok)
+}
+
+func TestLoggerAutoJITFallback(t *testing.T) {
+	if JITSupported() {
+		t.Skip("JIT compilation does not fail")
+	}
+	h := &recordHandler{}
+	SetLogger(slog.New(h))
+	defer SetLogger(nil)
+
+	re := MustCompile(`a+`, 0)
+	defer re.Free()
+	re.AutoJIT(0)
+	assert.Equal(t, "a", re.FindString("a", 0))
+	_, ok := h.find("pcre2: AutoJIT falls back to the interpreter")
+	assert.True(t, ok)
+	_, ok = h.find("pcre2: JIT compile failed")
+	assert.False(t, ok)
+}
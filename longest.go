@@ -0,0 +1,57 @@
+package pcre2
+
+/*
+#define PCRE2_CODE_UNIT_WIDTH 8
+
+#include <pcre2.h>
+*/
+import "C"
+
+import "unsafe"
+
+// Sizes of the workspace of pcre2_dfa_match, in ints. The workspace is
+// doubled whenever it is too small, up to the maximum.
+const (
+	dfaWorkspaceSize    = 1000
+	maxDFAWorkspaceSize = 1 << 20
+)
+
+// Longest makes future searches of re leftmost-longest: of the matches
+// starting at the leftmost position, the longest one is chosen, as in
+// POSIX and regexp.Regexp.Longest, instead of the first one found by
+// backtracking. It must be called before re is used for matching.
+//
+// Longest matching runs the alternative DFA algorithm of PCRE2, which
+// does not support capture groups: all groups but the whole match are
+// unset. Patterns with back references, recursion or conditions on
+// groups fail to match with one of the ERROR_DFA_* errors.
+func (re *Regexp) Longest() {
+	re.longest = true
+}
+
+// matchLongest calls pcre2_dfa_match and leaves only the longest match
+// in the ovector.
+func (m *Matcher) matchLongest(subjectptr *C.char, length, offset int, flags uint32, mc *MatchContext) int {
+	if m.dfaWorkspace == nil {
+		m.dfaWorkspace = make([]C.int, dfaWorkspaceSize)
+	}
+	for {
+		rc := C.pcre2_dfa_match(m.re.ptr, C.PCRE2_SPTR(unsafe.Pointer(subjectptr)), C.PCRE2_SIZE(length),
+			C.PCRE2_SIZE(offset), C.uint32_t(flags), m.mData.md, mc.pointer(),
+			&m.dfaWorkspace[0], C.PCRE2_SIZE(len(m.dfaWorkspace)))
+		if rc == ERROR_DFA_WSSIZE && len(m.dfaWorkspace) < maxDFAWorkspaceSize {
+			m.dfaWorkspace = make([]C.int, 2*len(m.dfaWorkspace))
+			continue
+		}
+		if rc < 0 {
+			return int(rc)
+		}
+		// The other pairs hold shorter matches at the same position,
+		// or nothing if the ovector was too small for all of them.
+		ovector := m.mData.ovector
+		for i := 2; i < len(ovector); i++ {
+			ovector[i] = UNSET
+		}
+		return 1
+	}
+}
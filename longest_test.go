@@ -0,0 +1,50 @@
+package pcre2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLongest(t *testing.T) {
+	re := MustCompile(`a(|b)`, 0)
+	assert.Equal(t, "a", re.FindString("ab", 0))
+	re.Longest()
+	assert.Equal(t, "ab", re.FindString("ab", 0))
+
+	re = MustCompile(`(a|ab)(c|bcd)`, 0)
+	re.Longest()
+	assert.Equal(t, []int{0, 4}, re.FindStringIndex("abcd", 0))
+	m := re.MatcherString("xabcd", 0)
+	assert.True(t, m.Matches())
+	assert.Equal(t, "abcd", m.GroupString(0))
+	assert.False(t, m.Present(1), "groups are unset")
+	assert.False(t, m.Present(2))
+
+	assert.Equal(t, []string{"abcd", "ac"}, re.FindAllString("abcd ac", 0, -1))
+	assert.False(t, re.MatcherString("xyz", 0).Matches())
+
+	clone, err := re.Clone()
+	assert.NoError(t, err)
+	assert.Equal(t, "abcd", clone.FindString("abcd", 0))
+}
+
+func TestLongestUnsupported(t *testing.T) {
+	re := MustCompile(`(a)\1`, 0)
+	re.Longest()
+	m := re.MatcherString("aa", 0)
+	assert.False(t, m.Matches())
+	assert.Equal(t, ERROR_DFA_UITEM, m.rc)
+}
+
+func TestLongestWorkspace(t *testing.T) {
+	// Many parallel paths need a large workspace.
+	re := MustCompile(`(?:a|aa|aaa|aaaa)*b`, 0)
+	re.Longest()
+	subject := make([]byte, 5000)
+	for i := range subject {
+		subject[i] = 'a'
+	}
+	subject = append(subject, 'b')
+	assert.Equal(t, []int{0, len(subject)}, re.FindIndex(subject, 0))
+}
@@ -0,0 +1,220 @@
+package pcre2
+
+/*
+#define PCRE2_CODE_UNIT_WIDTH 8
+
+#include <pcre2.h>
+*/
+import "C"
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// binaryVersion starts the binary form of a Regexp. It is followed by
+// the compile flags, the pattern and the optional serialized code.
+const binaryVersion = 1
+
+var errBadBinary = errors.New("invalid binary Regexp")
+
+var marshalCode atomic.Bool
+
+func init() {
+	marshalCode.Store(true)
+}
+
+// SetMarshalCode controls whether MarshalBinary includes the serialized
+// compiled code, which is the default. The code makes UnmarshalBinary
+// faster, but is only usable with the same PCRE2 version and
+// architecture; otherwise the pattern is compiled again.
+func SetMarshalCode(enabled bool) {
+	marshalCode.Store(enabled)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. The result holds
+// the pattern, its compile flags and, unless disabled with
+// SetMarshalCode, the serialized compiled code. Settings made with a
+// CompileContext are not preserved.
+func (re *Regexp) MarshalBinary() ([]byte, error) {
+	rptr, err := re.validRegexpPtr()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(binaryVersion)
+	buf.Write(binary.AppendUvarint(nil, uint64(re.ArgOptions())))
+	writeString(&buf, re.Pattern)
+	if marshalCode.Load() {
+		code, err := serialize([]*C.pcre2_code{rptr})
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(code)
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. It replaces
+// the compiled pattern of re, which must have been allocated on its own,
+// e.g. with new or as the target of a *Regexp field. Settings of re,
+// like AutoJIT or SetMaxSubjectLength, are reset and must be applied
+// afterwards. The data must come from a trusted source, see
+// DeserializeRegexp.
+func (re *Regexp) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 || data[0] != binaryVersion {
+		return errBadBinary
+	}
+	r := bytes.NewReader(data[1:])
+	flags, err := binary.ReadUvarint(r)
+	if err != nil {
+		return errBadBinary
+	}
+	pattern, err := readString(r)
+	if err != nil {
+		return errBadBinary
+	}
+	var decoded *Regexp
+	if r.Len() > 0 {
+		// The code may come from another PCRE2 version, in which
+		// case it is rejected and the pattern is compiled again.
+		decoded, _ = DeserializeRegexp(data[len(data)-r.Len():])
+	}
+	if decoded == nil {
+		if decoded, err = Compile(pattern, uint32(flags)); err != nil {
+			return err
+		}
+	}
+	decoded.Pattern = pattern
+	re.replace(decoded)
+	return nil
+}
+
+// replace makes re take over the compiled pattern of other, with all
+// its settings, freeing the pattern held by re before. Settings made on
+// re before, like AutoJIT or SetMaxSubjectLength, are not kept.
+func (re *Regexp) replace(other *Regexp) {
+	re.Free()
+	other.cleanup.Stop()
+	*re = *other
+	re.addCleanup(false)
+}
+
+// Compile flags which can be written as inline option letters, in the
+// order used by MarshalText.
+var textFlags = []struct {
+	letter byte
+	flag   uint32
+}{
+	{'i', CASELESS},
+	{'m', MULTILINE},
+	{'s', DOTALL},
+	{'x', EXTENDED},
+	{'n', NO_AUTO_CAPTURE},
+	{'U', UNGREEDY},
+	{'J', DUPNAMES},
+}
+
+// Compile flags which can be written as leading verbs.
+var textVerbs = []struct {
+	verb string
+	flag uint32
+}{
+	{"(*UTF)", UTF},
+	{"(*UCP)", UCP},
+}
+
+// MarshalText implements encoding.TextMarshaler, so that a Regexp can be
+// stored in JSON or YAML configuration. The text is the pattern with its
+// compile flags as a prefix of PCRE2 syntax, e.g. "(*UTF)(?im)abc". Flags
+// without such a syntax, e.g. ANCHORED, cannot be marshaled.
+func (re *Regexp) MarshalText() ([]byte, error) {
+	if _, err := re.validRegexpPtr(); err != nil {
+		return nil, err
+	}
+	flags := re.ArgOptions()
+	var buf bytes.Buffer
+	for _, v := range textVerbs {
+		if flags&v.flag != 0 {
+			buf.WriteString(v.verb)
+			flags &^= v.flag
+		}
+	}
+	var letters []byte
+	for _, f := range textFlags {
+		if flags&f.flag != 0 {
+			letters = append(letters, f.letter)
+			flags &^= f.flag
+		}
+	}
+	if flags != 0 {
+		return nil, fmt.Errorf("compile flags %#x cannot be marshaled as text", flags)
+	}
+	if len(letters) > 0 {
+		buf.WriteString("(?")
+		buf.Write(letters)
+		buf.WriteByte(')')
+	}
+	buf.WriteString(re.Pattern)
+	return buf.Bytes(), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. It compiles the
+// text as written by MarshalText, the prefix being turned into compile
+// flags again. Like UnmarshalBinary, it replaces the compiled pattern
+// of re, which must have been allocated on its own.
+func (re *Regexp) UnmarshalText(text []byte) error {
+	pattern, flags := parseTextFlags(string(text))
+	decoded, err := Compile(pattern, flags)
+	if err != nil {
+		return err
+	}
+	re.replace(decoded)
+	return nil
+}
+
+// parseTextFlags splits the flags prefix written by MarshalText from the
+// pattern. The prefix means the same to PCRE2 as the flags, so text
+// written by hand is compiled as expected, too.
+func parseTextFlags(text string) (pattern string, flags uint32) {
+	for found := true; found; {
+		found = false
+		for _, v := range textVerbs {
+			if strings.HasPrefix(text, v.verb) {
+				text = text[len(v.verb):]
+				flags |= v.flag
+				found = true
+			}
+		}
+	}
+	if !strings.HasPrefix(text, "(?") {
+		return text, flags
+	}
+	end := strings.IndexByte(text, ')')
+	if end < 3 {
+		return text, flags
+	}
+	var letterFlags uint32
+	for _, c := range []byte(text[2:end]) {
+		flag := letterFlag(c)
+		if flag == 0 {
+			return text, flags
+		}
+		letterFlags |= flag
+	}
+	return text[end+1:], flags | letterFlags
+}
+
+// letterFlag returns the compile flag of an inline option letter, or 0.
+func letterFlag(c byte) uint32 {
+	for _, f := range textFlags {
+		if f.letter == c {
+			return f.flag
+		}
+	}
+	return 0
+}
@@ -0,0 +1,113 @@
+package pcre2
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalBinary(t *testing.T) {
+	re := MustCompile(`^(a+)b$`, CASELESS)
+	defer re.Free()
+	data, err := re.MarshalBinary()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	decoded := new(Regexp)
+	if !assert.NoError(t, decoded.UnmarshalBinary(data)) {
+		return
+	}
+	defer decoded.Free()
+	assert.Equal(t, re.Pattern, decoded.Pattern)
+	assert.True(t, decoded.MatcherString("AAB", 0).Matches())
+
+	// Without code, the pattern is compiled again.
+	SetMarshalCode(false)
+	short, err := re.MarshalBinary()
+	SetMarshalCode(true)
+	assert.NoError(t, err)
+	assert.Less(t, len(short), len(data))
+	assert.NoError(t, decoded.UnmarshalBinary(short))
+	assert.True(t, decoded.MatcherString("aab", 0).Matches())
+
+	// Unusable code falls back to the pattern as well.
+	data = append(short, make([]byte, 64)...)
+	assert.NoError(t, decoded.UnmarshalBinary(data))
+	assert.True(t, decoded.MatcherString("aAb", 0).Matches())
+
+	// Settings of the target are reset.
+	decoded.SetMaxSubjectLength(1)
+	decoded.AutoJIT(1)
+	assert.NoError(t, decoded.UnmarshalBinary(short))
+	assert.Zero(t, decoded.maxSubjectLength)
+	assert.Nil(t, decoded.autoJIT)
+
+	assert.Equal(t, errBadBinary, decoded.UnmarshalBinary([]byte{42}))
+}
+
+func TestGobRegexp(t *testing.T) {
+	type config struct {
+		Name string
+		Re   *Regexp
+	}
+	var buf bytes.Buffer
+	in := config{"digits", MustCompile(`\d+`, 0)}
+	if !assert.NoError(t, gob.NewEncoder(&buf).Encode(in)) {
+		return
+	}
+	var out config
+	if !assert.NoError(t, gob.NewDecoder(&buf).Decode(&out)) {
+		return
+	}
+	assert.Equal(t, `\d+`, out.Re.Pattern)
+	assert.Equal(t, []int{1, 3}, out.Re.FindIndex([]byte("a42"), 0))
+}
+
+func TestMarshalText(t *testing.T) {
+	re := MustCompile(`^a.c$`, CASELESS|DOTALL|UTF)
+	defer re.Free()
+	text, err := re.MarshalText()
+	assert.NoError(t, err)
+	assert.Equal(t, "(*UTF)(?is)^a.c$", string(text))
+
+	var decoded Regexp
+	if !assert.NoError(t, decoded.UnmarshalText(text)) {
+		return
+	}
+	defer decoded.Free()
+	assert.Equal(t, `^a.c$`, decoded.Pattern)
+	assert.True(t, decoded.MatcherString("A\nC", 0).Matches())
+	again, err := decoded.MarshalText()
+	assert.NoError(t, err)
+	assert.Equal(t, text, again)
+
+	// Not a flags prefix: kept in the pattern.
+	for _, p := range []string{`(?:ab)`, `(?i:ab)`, `(?<n>a)`, `(?)`} {
+		pattern, flags := parseTextFlags(p)
+		assert.Equal(t, p, pattern)
+		assert.Zero(t, flags)
+	}
+
+	_, err = MustCompile(`a`, ANCHORED).MarshalText()
+	assert.Error(t, err)
+	assert.Error(t, decoded.UnmarshalText([]byte("(")))
+}
+
+func TestJSONRegexp(t *testing.T) {
+	type config struct {
+		Match *Regexp `json:"match"`
+	}
+	var c config
+	if !assert.NoError(t, json.Unmarshal([]byte(`{"match":"(?i)^abc$"}`), &c)) {
+		return
+	}
+	assert.Equal(t, `^abc$`, c.Match.Pattern)
+	assert.True(t, c.Match.MatcherString("ABC", 0).Matches())
+	data, err := json.Marshal(c)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"match":"(?i)^abc$"}`, string(data))
+}
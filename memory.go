@@ -0,0 +1,165 @@
+package pcre2
+
+/*
+#define PCRE2_CODE_UNIT_WIDTH 8
+
+#include <stdint.h>
+#include <stdlib.h>
+#include <pcre2.h>
+
+extern void *goMalloc(size_t, uintptr_t);
+extern void goFree(void *, uintptr_t);
+
+// Every block starts with a header recording its size, so that the free
+// callback can subtract it again, and the allocator it came from. The
+// header keeps the alignment malloc guarantees.
+#define MY_ALLOC_HEADER 16
+
+typedef struct {
+	PCRE2_SIZE size;
+	uintptr_t allocator; // handle of a Go Allocator, or 0 for malloc
+} myAllocHeader;
+
+static int64_t myCBytes;
+static uintptr_t myAllocator;
+
+static void mySetAllocator(uintptr_t handle) {
+	__atomic_store_n(&myAllocator, handle, __ATOMIC_RELEASE);
+}
+
+static void *myCountingMalloc(PCRE2_SIZE size, void *data) {
+	uintptr_t allocator = __atomic_load_n(&myAllocator, __ATOMIC_ACQUIRE);
+	char *block;
+	if (allocator == 0) {
+		block = malloc(size + MY_ALLOC_HEADER);
+	} else {
+		block = goMalloc(size + MY_ALLOC_HEADER, allocator);
+	}
+	if (block == NULL) {
+		return NULL;
+	}
+	myAllocHeader *header = (myAllocHeader *) block;
+	header->size = size;
+	header->allocator = allocator;
+	__atomic_add_fetch(&myCBytes, (int64_t) size, __ATOMIC_RELAXED);
+	return block + MY_ALLOC_HEADER;
+}
+
+static void myCountingFree(void *ptr, void *data) {
+	if (ptr == NULL) {
+		return;
+	}
+	char *block = (char *) ptr - MY_ALLOC_HEADER;
+	myAllocHeader *header = (myAllocHeader *) block;
+	__atomic_sub_fetch(&myCBytes, (int64_t) header->size, __ATOMIC_RELAXED);
+	if (header->allocator == 0) {
+		free(block);
+	} else {
+		goFree(block, header->allocator);
+	}
+}
+
+static pcre2_general_context *myCountingContext(void) {
+	return pcre2_general_context_create(myCountingMalloc, myCountingFree, NULL);
+}
+
+static int64_t myCBytesAllocated(void) {
+	return __atomic_load_n(&myCBytes, __ATOMIC_RELAXED);
+}
+*/
+import "C"
+
+import (
+	"runtime/cgo"
+	"sync"
+	"unsafe"
+)
+
+// generalContext makes PCRE2 allocate through counting wrappers of
+// malloc and free, or of the Allocator installed with SetAllocator. It
+// is passed wherever the package creates contexts, match data, JIT
+// stacks or decoded patterns, and compiled patterns inherit it from
+// their compile context.
+var generalContext = C.myCountingContext()
+
+// defaultCompileContext is used to compile patterns for which no
+// CompileContext is given, so that their memory is counted, too. It is
+// never modified.
+var defaultCompileContext = C.pcre2_compile_context_create(generalContext)
+
+// LiveRegexps returns the number of compiled patterns which have not
+// been freed yet.
+func LiveRegexps() int64 {
+	return statLiveRegexps.Load()
+}
+
+// LiveMatchData returns the number of match data blocks, i.e. matchers,
+// which have not been freed yet.
+func LiveMatchData() int64 {
+	return statLiveMatchData.Load()
+}
+
+// TotalCBytesAllocated returns the number of bytes of C memory which
+// PCRE2 currently holds on behalf of this package: compiled patterns,
+// match data, contexts, JIT stacks and heap frames used while matching.
+// JIT compiled code is mapped separately and reported by Snapshot.
+func TotalCBytesAllocated() int64 {
+	return int64(C.myCBytesAllocated())
+}
+
+// Allocator provides the memory PCRE2 allocates for compiled patterns,
+// match data, contexts, JIT stacks and matching, e.g. from an arena or
+// with accounting in embedded environments. JIT compiled code is always
+// mapped by PCRE2 itself.
+//
+// Malloc must return memory which is suitably aligned for any type, like
+// C malloc, and is not managed by the Go garbage collector, or nil if
+// none is available. Free releases a block returned by Malloc. Both are
+// called from C, possibly from several goroutines at the same time, and
+// must not panic.
+type Allocator interface {
+	Malloc(size uintptr) unsafe.Pointer
+	Free(ptr unsafe.Pointer)
+}
+
+// CAllocator allocates with C malloc and free, which is what PCRE2 uses
+// unless another Allocator is installed. Allocators which account for
+// memory can delegate to it.
+var CAllocator Allocator = cAllocator{}
+
+type cAllocator struct{}
+
+func (cAllocator) Malloc(size uintptr) unsafe.Pointer {
+	return C.malloc(C.size_t(size))
+}
+
+func (cAllocator) Free(ptr unsafe.Pointer) {
+	C.free(ptr)
+}
+
+// allocators maps the installed allocators to their handles. Handles
+// are never deleted, because blocks are returned to the allocator which
+// provided them, even after another one has been installed.
+var (
+	allocatorsMu sync.Mutex
+	allocators   = map[Allocator]cgo.Handle{}
+)
+
+// SetAllocator installs the allocator for all further allocations of
+// PCRE2, or restores C malloc if a is nil. Memory allocated before is
+// still released to the allocator it came from. The allocator must be
+// comparable, e.g. a pointer.
+func SetAllocator(a Allocator) {
+	if a == nil || a == CAllocator {
+		C.mySetAllocator(0)
+		return
+	}
+	allocatorsMu.Lock()
+	defer allocatorsMu.Unlock()
+	h, ok := allocators[a]
+	if !ok {
+		h = cgo.NewHandle(a)
+		allocators[a] = h
+	}
+	C.mySetAllocator(C.uintptr_t(h))
+}
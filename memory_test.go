@@ -0,0 +1,65 @@
+package pcre2
+
+import (
+	"sync/atomic"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingAllocator struct {
+	mallocs, frees atomic.Int64
+}
+
+func (a *countingAllocator) Malloc(size uintptr) unsafe.Pointer {
+	a.mallocs.Add(1)
+	return CAllocator.Malloc(size)
+}
+
+func (a *countingAllocator) Free(ptr unsafe.Pointer) {
+	a.frees.Add(1)
+	CAllocator.Free(ptr)
+}
+
+func TestSetAllocator(t *testing.T) {
+	// Allocated with malloc, but freed while the allocator is installed.
+	old := MustCompile(`x`, 0)
+
+	a := &countingAllocator{}
+	SetAllocator(a)
+	re := MustCompile(`(\w+)@(\w+)`, 0)
+	m := re.MatcherString("user@example", 0)
+	SetAllocator(nil)
+	assert.Equal(t, "example", m.GroupString(2))
+	assert.True(t, a.mallocs.Load() >= 2, "pattern and match data")
+
+	// Memory goes back to the allocator it came from.
+	SetAllocator(a)
+	old.Free()
+	SetAllocator(nil)
+	assert.Zero(t, a.frees.Load())
+	m.Free()
+	re.Free()
+	assert.Equal(t, a.mallocs.Load(), a.frees.Load())
+
+	SetAllocator(CAllocator)
+	re = MustCompile(`y`, 0)
+	re.Free()
+	assert.Equal(t, a.mallocs.Load(), a.frees.Load())
+}
+
+type failingAllocator struct{}
+
+func (*failingAllocator) Malloc(uintptr) unsafe.Pointer { return nil }
+func (*failingAllocator) Free(unsafe.Pointer)           {}
+
+func TestAllocatorOutOfMemory(t *testing.T) {
+	re := MustCompile(`(a)`, 0)
+	defer re.Free()
+	SetAllocator(&failingAllocator{})
+	defer SetAllocator(nil)
+	_, err := re.NewMatcherErr()
+	assert.Equal(t, ErrNoMemory, err)
+	assert.PanicsWithValue(t, ErrNoMemory, func() { re.NewMatcher() })
+}
@@ -0,0 +1,245 @@
+package pcre2
+
+import (
+	"cmp"
+	"encoding/json"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics receives instrumentation events of the package, so that
+// operators can see which patterns are expensive in production. The
+// methods are called synchronously, possibly from many goroutines at
+// once, and must be fast and safe for concurrent use. PatternMetrics is
+// a ready-made implementation.
+type Metrics interface {
+	// Compiled is called after a pattern was compiled, with the time
+	// compiling took and the error, if it failed.
+	Compiled(pattern string, elapsed time.Duration, err error)
+	// JITCompiled is called after re was JIT compiled.
+	JITCompiled(re *Regexp, elapsed time.Duration, err error)
+	// Matched is called after each match call of re, with the return
+	// code of the match, see Matcher.ReturnCode.
+	Matched(re *Regexp, elapsed time.Duration, rc int)
+}
+
+// metricsHook holds the installed Metrics, so that it can be stored
+// atomically.
+type metricsHook struct {
+	Metrics
+}
+
+var metrics atomic.Pointer[metricsHook]
+
+// SetMetrics installs m to receive the events of all patterns, or
+// removes the installed Metrics if m is nil. Without Metrics, the cost
+// of instrumentation is a single atomic load per call.
+func SetMetrics(m Metrics) {
+	if m == nil {
+		metrics.Store(nil)
+		return
+	}
+	metrics.Store(&metricsHook{m})
+}
+
+func observeCompile(h *metricsHook, pattern string, start time.Time, err *error) {
+	h.Compiled(pattern, time.Since(start), *err)
+}
+
+func observeJITCompile(h *metricsHook, re *Regexp, start time.Time, err *error) {
+	h.JITCompiled(re, time.Since(start), *err)
+}
+
+func observeMatch(h *metricsHook, re *Regexp, start time.Time, rc *int) {
+	h.Matched(re, time.Since(start), *rc)
+}
+
+// DefaultLatencyBuckets are the upper bounds of the match latency
+// histogram of NewPatternMetrics.
+var DefaultLatencyBuckets = []time.Duration{
+	time.Microsecond,
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+}
+
+// PatternStats holds the counters of a single pattern collected by
+// PatternMetrics.
+type PatternStats struct {
+	Pattern       string        `json:"pattern"`
+	Compiles      int64         `json:"compiles"`
+	CompileErrors int64         `json:"compile_errors"`
+	CompileTime   time.Duration `json:"compile_time_ns"` // including JIT compiling
+	JITCompiles   int64         `json:"jit_compiles"`
+	JITErrors     int64         `json:"jit_errors"`
+	Matches       int64         `json:"matches"`       // match calls
+	MatchErrors   int64         `json:"match_errors"`  // failed other than by not matching
+	MatchTime     time.Duration `json:"match_time_ns"` // total time of all match calls
+	// Latency counts the match calls per bucket of Buckets; the last
+	// element counts the calls slower than the largest bound.
+	Latency []int64 `json:"latency"`
+}
+
+// PatternMetrics is a Metrics which collects counters and a histogram
+// of the match latency per pattern. Only the patterns passed to Track
+// are counted, so that the memory used stays bounded however many
+// patterns are compiled, e.g. when a server validates patterns supplied
+// by users. Regexps compiled from the same pattern share their
+// counters, which are updated atomically. It implements expvar.Var, so
+// it can be published with expvar.Publish, or its Stats exported to
+// another monitoring system.
+type PatternMetrics struct {
+	buckets []time.Duration
+	mu      sync.Mutex // serializes Track and Reset
+	// patterns is replaced, not modified, when patterns are added, so
+	// that the events can look up their counters without locking.
+	patterns atomic.Pointer[map[string]*patternCounters]
+}
+
+// patternCounters are the counters of a single pattern.
+type patternCounters struct {
+	compiles, compileErrors, compileTime atomic.Int64
+	jitCompiles, jitErrors               atomic.Int64
+	matches, matchErrors, matchTime      atomic.Int64
+	latency                              []atomic.Int64
+}
+
+// NewPatternMetrics returns a PatternMetrics which tracks no patterns
+// yet, with the given latency bucket bounds, in increasing order, or
+// DefaultLatencyBuckets if there are none.
+func NewPatternMetrics(buckets ...time.Duration) *PatternMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	pm := &PatternMetrics{buckets: slices.Clone(buckets)}
+	pm.patterns.Store(&map[string]*patternCounters{})
+	return pm
+}
+
+// Buckets returns the upper bounds of the latency buckets.
+func (pm *PatternMetrics) Buckets() []time.Duration {
+	return slices.Clone(pm.buckets)
+}
+
+// Track starts counting the events of the patterns. Patterns which are
+// already tracked keep their counters.
+func (pm *PatternMetrics) Track(patterns ...string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	m := maps.Clone(*pm.patterns.Load())
+	for _, pattern := range patterns {
+		if m[pattern] == nil {
+			m[pattern] = pm.newCounters()
+		}
+	}
+	pm.patterns.Store(&m)
+}
+
+func (pm *PatternMetrics) newCounters() *patternCounters {
+	return &patternCounters{latency: make([]atomic.Int64, len(pm.buckets)+1)}
+}
+
+// counters returns the counters of the pattern, or nil if it is not
+// tracked.
+func (pm *PatternMetrics) counters(pattern string) *patternCounters {
+	return (*pm.patterns.Load())[pattern]
+}
+
+// Compiled implements Metrics.
+func (pm *PatternMetrics) Compiled(pattern string, elapsed time.Duration, err error) {
+	c := pm.counters(pattern)
+	if c == nil {
+		return
+	}
+	c.compiles.Add(1)
+	c.compileTime.Add(int64(elapsed))
+	if err != nil {
+		c.compileErrors.Add(1)
+	}
+}
+
+// JITCompiled implements Metrics.
+func (pm *PatternMetrics) JITCompiled(re *Regexp, elapsed time.Duration, err error) {
+	c := pm.counters(re.Pattern)
+	if c == nil {
+		return
+	}
+	c.jitCompiles.Add(1)
+	c.compileTime.Add(int64(elapsed))
+	if err != nil {
+		c.jitErrors.Add(1)
+	}
+}
+
+// Matched implements Metrics.
+func (pm *PatternMetrics) Matched(re *Regexp, elapsed time.Duration, rc int) {
+	c := pm.counters(re.Pattern)
+	if c == nil {
+		return
+	}
+	bucket, _ := slices.BinarySearch(pm.buckets, elapsed)
+	c.matches.Add(1)
+	c.matchTime.Add(int64(elapsed))
+	c.latency[bucket].Add(1)
+	if rc < 0 && rc != ERROR_NOMATCH && rc != ERROR_PARTIAL {
+		c.matchErrors.Add(1)
+	}
+}
+
+// Stats returns the counters of all tracked patterns, the ones which
+// spent the most time matching first. The counters of a pattern are
+// read one by one, while events may still be counted.
+func (pm *PatternMetrics) Stats() []PatternStats {
+	m := *pm.patterns.Load()
+	result := make([]PatternStats, 0, len(m))
+	for pattern, c := range m {
+		s := PatternStats{
+			Pattern:       pattern,
+			Compiles:      c.compiles.Load(),
+			CompileErrors: c.compileErrors.Load(),
+			CompileTime:   time.Duration(c.compileTime.Load()),
+			JITCompiles:   c.jitCompiles.Load(),
+			JITErrors:     c.jitErrors.Load(),
+			Matches:       c.matches.Load(),
+			MatchErrors:   c.matchErrors.Load(),
+			MatchTime:     time.Duration(c.matchTime.Load()),
+			Latency:       make([]int64, len(c.latency)),
+		}
+		for i := range c.latency {
+			s.Latency[i] = c.latency[i].Load()
+		}
+		result = append(result, s)
+	}
+	slices.SortFunc(result, func(a, b PatternStats) int {
+		return cmp.Or(cmp.Compare(b.MatchTime, a.MatchTime), cmp.Compare(a.Pattern, b.Pattern))
+	})
+	return result
+}
+
+// Reset discards the counters collected so far. The patterns stay
+// tracked.
+func (pm *PatternMetrics) Reset() {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	m := make(map[string]*patternCounters, len(*pm.patterns.Load()))
+	for pattern := range *pm.patterns.Load() {
+		m[pattern] = pm.newCounters()
+	}
+	pm.patterns.Store(&m)
+}
+
+// String returns the bucket bounds and the Stats encoded as JSON, which
+// makes PatternMetrics an expvar.Var.
+func (pm *PatternMetrics) String() string {
+	b, _ := json.Marshal(struct {
+		Buckets  []time.Duration `json:"buckets_ns"`
+		Patterns []PatternStats  `json:"patterns"`
+	}{pm.buckets, pm.Stats()})
+	return string(b)
+}
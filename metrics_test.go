@@ -0,0 +1,126 @@
+package pcre2
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatternMetrics(t *testing.T) {
+	pm := NewPatternMetrics(time.Hour)
+	pm.Track(`(a+)+$`, `(`, `abc`)
+	SetMetrics(pm)
+	defer SetMetrics(nil)
+
+	re := MustCompile(`(a+)+$`, 0)
+	defer re.Free()
+	_, err := Compile(`(`, 0)
+	assert.Error(t, err)
+	jitErr := re.JITCompile(JIT_COMPLETE)
+
+	m := re.NewMatcher()
+	defer m.Free()
+	assert.True(t, m.MatchString("xaa", 0))
+	assert.False(t, m.MatchString("xyz", 0))
+	mc := NewMatchContext()
+	defer mc.Free()
+	mc.SetMatchLimit(1)
+	m.SetMatchContext(mc)
+	assert.False(t, m.MatchString("aaaaaaaaaaaaaaaaaaaab", 0))
+	assert.ErrorIs(t, m.GetError(), ErrMatchLimit)
+
+	// Literal patterns are matched without PCRE2, and still counted.
+	lit := MustCompile(`abc`, 0)
+	defer lit.Free()
+	assert.Equal(t, []int{1, 4}, lit.FindStringIndex("xabc", 0))
+
+	// Untracked patterns are not counted.
+	assert.NoError(t, ValidatePattern(`untracked`, 0, DefaultLimits))
+	other := MustCompile(`other`, 0)
+	defer other.Free()
+	other.FindStringIndex("other", 0)
+
+	SetMetrics(nil)
+	m.MatchString("aa", 0)
+
+	stats := pm.Stats()
+	require.Len(t, stats, 3)
+	byPattern := make(map[string]PatternStats)
+	for _, s := range stats {
+		byPattern[s.Pattern] = s
+	}
+
+	s := byPattern[`(a+)+$`]
+	assert.Equal(t, int64(1), s.Compiles)
+	assert.Zero(t, s.CompileErrors)
+	assert.Equal(t, int64(1), s.JITCompiles)
+	assert.Equal(t, jitErr != nil, s.JITErrors == 1)
+	assert.Equal(t, int64(3), s.Matches)
+	assert.Equal(t, int64(1), s.MatchErrors)
+	assert.Equal(t, []int64{3, 0}, s.Latency)
+
+	assert.Equal(t, int64(1), byPattern[`(`].CompileErrors)
+	assert.Zero(t, byPattern[`(`].Matches)
+	assert.Equal(t, int64(1), byPattern[`abc`].Matches)
+
+	var v struct {
+		Buckets  []int64        `json:"buckets_ns"`
+		Patterns []PatternStats `json:"patterns"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(pm.String()), &v))
+	assert.Equal(t, []int64{int64(time.Hour)}, v.Buckets)
+	assert.Equal(t, stats, v.Patterns)
+
+	pm.Reset()
+	stats = pm.Stats()
+	assert.Len(t, stats, 3)
+	for _, s := range stats {
+		assert.Zero(t, s.Compiles+s.Matches, s.Pattern)
+	}
+}
+
+func TestPatternMetricsConcurrent(t *testing.T) {
+	pm := NewPatternMetrics()
+	re := MustCompile(`x`, 0)
+	defer re.Free()
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pm.Track(`x`, fmt.Sprint(i))
+			for range 1000 {
+				pm.Matched(re, time.Microsecond, 0)
+			}
+		}()
+	}
+	wg.Wait()
+	// Tracking more patterns keeps the counters of x.
+	stats := pm.Stats()
+	assert.Len(t, stats, 9)
+	assert.Equal(t, `x`, stats[0].Pattern)
+	assert.Equal(t, int64(8000), stats[0].Matches)
+}
+
+func TestPatternMetricsBuckets(t *testing.T) {
+	pm := NewPatternMetrics(time.Millisecond, time.Second)
+	pm.Track(`x`)
+	re := MustCompile(`x`, 0)
+	defer re.Free()
+	pm.Matched(re, time.Millisecond, 0)
+	pm.Matched(re, 2*time.Millisecond, 0)
+	pm.Matched(re, time.Minute, ERROR_MATCHLIMIT)
+	pm.Matched(re, 0, ERROR_NOMATCH)
+
+	s := pm.Stats()[0]
+	assert.Equal(t, []int64{2, 1, 1}, s.Latency)
+	assert.Equal(t, int64(4), s.Matches)
+	assert.Equal(t, int64(1), s.MatchErrors)
+	assert.Equal(t, time.Minute+3*time.Millisecond, s.MatchTime)
+	assert.Equal(t, DefaultLatencyBuckets, NewPatternMetrics().Buckets())
+}
@@ -0,0 +1,42 @@
+package pcre2
+
+import "os"
+
+// MatchFile reports whether the contents of the file match the pattern.
+// The file is mapped into memory and the mapping is passed to PCRE2
+// directly, so files of many gigabytes can be matched without reading
+// them into the Go heap. The mapping is released before MatchFile
+// returns. The file must not be truncated while it is matched: on most
+// systems, reading the mapping beyond the new end raises SIGBUS, which
+// crashes the program. The error is that of opening or mapping the file, or of the
+// match, e.g. when a limit is hit.
+func (re *Regexp) MatchFile(path string, flags uint32) (bool, error) {
+	data, unmap, err := mapFile(path)
+	if err != nil {
+		return false, err
+	}
+	defer unmap()
+	m := re.NewMatcher()
+	defer m.Free()
+	matched := m.Match(data, flags)
+	return matched, m.matchError()
+}
+
+// FindAllIndexFile is like FindAllIndex, but matches the contents of
+// the file, which is mapped into memory like by MatchFile. Like for
+// MatchFile, the error is also that of a failed match, e.g. when a
+// limit is hit; the matches found before it are returned along with it.
+func (re *Regexp) FindAllIndexFile(path string, flags uint32, n int) ([][]int, error) {
+	data, unmap, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer unmap()
+	return findAllIndex(re, data, flags, n)
+}
+
+// readFile is the fallback of mapFile for systems without mmap.
+func readFile(path string) ([]byte, func() error, error) {
+	data, err := os.ReadFile(path)
+	return data, func() error { return nil }, err
+}
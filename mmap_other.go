@@ -0,0 +1,8 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package pcre2
+
+// mapFile reads the file, because this system has no mmap.
+func mapFile(path string) ([]byte, func() error, error) {
+	return readFile(path)
+}
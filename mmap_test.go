@@ -0,0 +1,52 @@
+package pcre2
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "log")
+	content := strings.Repeat("INFO ok\n", 100000) + "ERROR disk full\nINFO ok\nERROR again\n"
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	re := MustCompile(`(?m)^ERROR.*$`, 0)
+	matched, err := re.MatchFile(path, 0)
+	assert.NoError(t, err)
+	assert.True(t, matched)
+
+	locs, err := re.FindAllIndexFile(path, 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, re.FindAllIndex([]byte(content), 0, -1), locs)
+
+	matched, err = MustCompile(`FATAL`, 0).MatchFile(path, 0)
+	assert.NoError(t, err)
+	assert.False(t, matched)
+
+	empty := filepath.Join(dir, "empty")
+	assert.NoError(t, os.WriteFile(empty, nil, 0o644))
+	matched, err = MustCompile(`^$`, 0).MatchFile(empty, 0)
+	assert.NoError(t, err)
+	assert.True(t, matched)
+
+	_, err = re.MatchFile(filepath.Join(dir, "missing"), 0)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestFindAllIndexFileLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	assert.NoError(t, os.WriteFile(path, []byte("ab "+strings.Repeat("a", 30)+"!"), 0o644))
+
+	SetDefaultLimits(1000, 0, 0)
+	defer SetDefaultLimits(0, 0, 0)
+	re := MustCompile(`a?b|(a+)+$`, 0)
+	defer re.Free()
+	locs, err := re.FindAllIndexFile(path, 0, -1)
+	assert.ErrorIs(t, err, ErrMatchLimit)
+	assert.Equal(t, [][]int{{0, 2}}, locs)
+}
@@ -0,0 +1,49 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package pcre2
+
+/*
+#include <sys/mman.h>
+
+static void myAdviseSequential(void *addr, size_t length) {
+	madvise(addr, length, MADV_SEQUENTIAL);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// mapFile maps the file into memory read-only. unmap releases the
+// mapping; the data must not be used afterwards.
+func mapFile(path string) (data []byte, unmap func() error, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	size := fi.Size()
+	if size == 0 || !fi.Mode().IsRegular() {
+		// Empty files cannot be mapped, and pipes or devices need not
+		// have a size.
+		return readFile(path)
+	}
+	if int64(int(size)) != size {
+		return nil, nil, fmt.Errorf("%s: file too large to map", path)
+	}
+	data, err = syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, &os.PathError{Op: "mmap", Path: path, Err: err}
+	}
+	// PCRE2 scans the subject from start to end.
+	C.myAdviseSequential(unsafe.Pointer(unsafe.SliceData(data)), C.size_t(len(data)))
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
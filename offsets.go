@@ -0,0 +1,92 @@
+package pcre2
+
+// Span is the position of a match or capture group in the subject.
+type Span struct {
+	Start int // byte offset of the first byte
+	End   int // byte offset just past the last byte
+}
+
+// Len returns the length of the span in bytes.
+func (s Span) Len() int {
+	return s.End - s.Start
+}
+
+// OffsetMatcher is a restricted variant of Matcher for pipelines which
+// must not duplicate subject data, such as scanners handling secrets.
+// It only reports offsets into the subject: it has no methods returning
+// captured bytes or strings, and it drops its reference to the subject
+// as soon as a match attempt is finished.
+type OffsetMatcher struct {
+	m Matcher
+}
+
+// NewOffsetMatcher creates a new OffsetMatcher for the given Regexp.
+func (re *Regexp) NewOffsetMatcher() *OffsetMatcher {
+	om := new(OffsetMatcher)
+	om.m.Init(re)
+	return om
+}
+
+// Match tries to match the subject and returns true on success.
+func (om *OffsetMatcher) Match(subject []byte, flags uint32) bool {
+	defer om.forget()
+	return om.m.Match(subject, flags)
+}
+
+// MatchString is like Match, but with a string subject.
+func (om *OffsetMatcher) MatchString(subject string, flags uint32) bool {
+	defer om.forget()
+	return om.m.MatchString(subject, flags)
+}
+
+func (om *OffsetMatcher) forget() {
+	om.m.subjects = ""
+	om.m.subjectb = nil
+}
+
+// Matches returns true if the previous match attempt succeeded.
+func (om *OffsetMatcher) Matches() bool {
+	return om.m.matches
+}
+
+// Groups returns the number of capture groups in the pattern.
+func (om *OffsetMatcher) Groups() int {
+	return om.m.groups
+}
+
+// Span returns the position of the numbered capture group in the last
+// match. Group 0 is the whole match. The second return value is false
+// if there was no match or the group did not participate in it.
+func (om *OffsetMatcher) Span(group int) (Span, bool) {
+	if !om.m.matches || group < 0 || group > om.m.groups {
+		return Span{}, false
+	}
+	start, end, ok := om.m.mData.offsets(group)
+	if !ok {
+		return Span{}, false
+	}
+	return Span{Start: int(start), End: int(end)}, true
+}
+
+// Spans returns the positions of the whole match and all capture groups.
+// Groups which did not participate are reported as Span{-1, -1}.
+// If there was no match then nil is returned.
+func (om *OffsetMatcher) Spans() []Span {
+	if !om.m.matches {
+		return nil
+	}
+	spans := make([]Span, om.m.groups+1)
+	for i := range spans {
+		if s, ok := om.Span(i); ok {
+			spans[i] = s
+		} else {
+			spans[i] = Span{-1, -1}
+		}
+	}
+	return spans
+}
+
+// Free releases the underlying C resources.
+func (om *OffsetMatcher) Free() {
+	om.m.Free()
+}
@@ -0,0 +1,31 @@
+package pcre2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOffsetMatcher(t *testing.T) {
+	re := MustCompile(`key=(\w+)(;)?`, 0)
+	om := re.NewOffsetMatcher()
+	defer om.Free()
+
+	secret := []byte("xx key=hunter2 yy")
+	assert.True(t, om.Match(secret, 0))
+	assert.Nil(t, om.m.subjectb)
+	assert.Equal(t, 2, om.Groups())
+
+	s, ok := om.Span(1)
+	assert.True(t, ok)
+	assert.Equal(t, Span{7, 14}, s)
+	assert.Equal(t, 7, s.Len())
+	_, ok = om.Span(2)
+	assert.False(t, ok)
+	_, ok = om.Span(3)
+	assert.False(t, ok)
+	assert.Equal(t, []Span{{3, 14}, {7, 14}, {-1, -1}}, om.Spans())
+
+	assert.False(t, om.MatchString("nothing", 0))
+	assert.Nil(t, om.Spans())
+}
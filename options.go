@@ -0,0 +1,251 @@
+package pcre2
+
+/*
+#define PCRE2_CODE_UNIT_WIDTH 8
+
+#include <pcre2.h>
+*/
+import "C"
+
+import (
+	"time"
+)
+
+// CompileOptions bundles the flags passed to Compile with the settings
+// which can only be expressed through a CompileContext. Zero values
+// select the defaults of the library.
+type CompileOptions struct {
+	Flags            uint32  // compile flags, e.g. CASELESS|UTF
+	Newline          uint32  // one of the NEWLINE_* constants
+	BSR              uint32  // BSR_UNICODE or BSR_ANYCRLF
+	ExtraOptions     uint32  // combination of the EXTRA_* constants
+	MaxPatternLength int     // see CompileContext.SetMaxPatternLength
+	ParensNestLimit  uint32  // see CompileContext.SetParensNestLimit
+	Tables           *Tables // character tables, see MakeTables
+	// JIT requests JIT compilation with the given JIT_* flags, e.g.
+	// JIT_COMPLETE. Zero skips JIT compilation.
+	JIT uint32
+}
+
+// compileContext returns a compile context holding the settings of
+// opts, or nil if none of them differs from the defaults.
+func (opts *CompileOptions) compileContext() (*CompileContext, error) {
+	if opts.Newline == 0 && opts.BSR == 0 && opts.ExtraOptions == 0 &&
+		opts.MaxPatternLength == 0 && opts.ParensNestLimit == 0 &&
+		opts.Tables == nil {
+		return nil, nil
+	}
+	cc := NewCompileContext()
+	if opts.Newline != 0 {
+		if err := cc.SetNewline(opts.Newline); err != nil {
+			cc.Free()
+			return nil, err
+		}
+	}
+	if opts.BSR != 0 {
+		if err := cc.SetBSR(opts.BSR); err != nil {
+			cc.Free()
+			return nil, err
+		}
+	}
+	if opts.ExtraOptions != 0 {
+		cc.SetExtraOptions(opts.ExtraOptions)
+	}
+	if opts.MaxPatternLength != 0 {
+		cc.SetMaxPatternLength(opts.MaxPatternLength)
+	}
+	if opts.ParensNestLimit != 0 {
+		cc.SetParensNestLimit(opts.ParensNestLimit)
+	}
+	if opts.Tables != nil {
+		cc.SetCharacterTables(opts.Tables)
+	}
+	return cc, nil
+}
+
+// CompileWithOptions compiles the pattern with all settings of opts.
+// If compilation fails, the error is a *CompileError. If JIT compilation
+// was requested and fails, the pattern is freed and that error is
+// returned instead.
+func CompileWithOptions(pattern string, opts CompileOptions) (*Regexp, error) {
+	return compileWithOptions(pattern, opts, true)
+}
+
+// compileWithOptions is like CompileWithOptions, but logs a compile
+// failure only if logFailure is set. Patterns supplied by untrusted
+// users are expected to fail now and then, which is not worth a warning.
+func compileWithOptions(pattern string, opts CompileOptions, logFailure bool) (*Regexp, error) {
+	cc, err := opts.compileContext()
+	if err != nil {
+		return nil, err
+	}
+	defer cc.Free()
+	re, err := compileWithContext(pattern, opts.Flags, cc, logFailure)
+	if err != nil {
+		return nil, err
+	}
+	if opts.JIT != 0 {
+		if err := re.JITCompile(opts.JIT); err != nil {
+			re.Free()
+			return nil, err
+		}
+	}
+	return re, nil
+}
+
+// MatchOptions bundles the parameters of a single match. Zero values
+// select the defaults, so new settings can be added without changing
+// the signatures of the functions taking them.
+type MatchOptions struct {
+	Flags   uint32        // match flags, e.g. NOTBOL|PARTIAL_SOFT
+	Offset  int           // offset in the subject at which to start
+	Context *MatchContext // overrides the context of the Matcher
+	// Deadline bounds the wall-clock time of the match. When it passes,
+	// the match fails with ERROR_TIMEOUT. Matches with a callout are
+	// only checked before they start.
+	Deadline time.Time
+	// Timeout is like Deadline, but relative to the start of the call.
+	// If both are set, the earlier one applies.
+	Timeout time.Duration
+}
+
+// WithTimeout returns a copy of opts with the given Timeout.
+func (opts MatchOptions) WithTimeout(d time.Duration) MatchOptions {
+	opts.Timeout = d
+	return opts
+}
+
+// WithDeadline returns a copy of opts with the given Deadline.
+func (opts MatchOptions) WithDeadline(t time.Time) MatchOptions {
+	opts.Deadline = t
+	return opts
+}
+
+// deadline returns the effective deadline of opts, or the zero time.
+func (opts *MatchOptions) deadline() time.Time {
+	if opts.Timeout <= 0 {
+		return opts.Deadline
+	}
+	d := time.Now().Add(opts.Timeout)
+	if !opts.Deadline.IsZero() && opts.Deadline.Before(d) {
+		return opts.Deadline
+	}
+	return d
+}
+
+// deadlineMatchStep is the match limit of the first attempt of a match
+// which can be abandoned. Each further attempt doubles the limit.
+const deadlineMatchStep = 1 << 14
+
+// ExecOptions tries to match the specified byte slice to the current
+// pattern with the given options. It returns the raw PCRE2 error code.
+func (m *Matcher) ExecOptions(subject []byte, opts MatchOptions) int {
+	if m.re.ptr == nil {
+		panic("Matcher.ExecOptions: uninitialized")
+	}
+	return execOptions(m, subject, opts)
+}
+
+// ExecStringOptions is like ExecOptions, but for a subject string.
+func (m *Matcher) ExecStringOptions(subject string, opts MatchOptions) int {
+	if m.re.ptr == nil {
+		panic("Matcher.ExecStringOptions: uninitialized")
+	}
+	return execOptions(m, subject, opts)
+}
+
+// MatchWithOptions is like Match, but with the given options instead of
+// just flags.
+func (m *Matcher) MatchWithOptions(subject []byte, opts MatchOptions) bool {
+	if m.re.ptr == nil {
+		panic("Matcher.MatchWithOptions: uninitialized")
+	}
+	return m.record(execOptions(m, subject, opts))
+}
+
+// MatchStringWithOptions is like MatchString, but with the given options
+// instead of just flags.
+func (m *Matcher) MatchStringWithOptions(subject string, opts MatchOptions) bool {
+	if m.re.ptr == nil {
+		panic("Matcher.MatchStringWithOptions: uninitialized")
+	}
+	return m.record(execOptions(m, subject, opts))
+}
+
+func execOptions[S subject](m *Matcher, subject S, opts MatchOptions) int {
+	defer m.applyOptions(opts)()
+	return execAt(m, subject, opts.Offset, opts.Flags)
+}
+
+// applyOptions sets the context and deadline of opts for the next match
+// of m, and returns a function restoring the previous settings.
+func (m *Matcher) applyOptions(opts MatchOptions) (restore func()) {
+	mctx := m.mctx
+	if opts.Context != nil {
+		m.mctx = opts.Context
+	}
+	m.deadline = opts.deadline()
+	return func() {
+		if opts.Context != nil {
+			m.mctx = mctx
+		}
+		m.deadline = time.Time{}
+	}
+}
+
+// execBounded runs a match which can be abandoned, because it has a
+// deadline or is monitored by a watchdog.
+func (m *Matcher) execBounded(subjectptr *C.char, length, offset int, flags uint32, w *Watchdog) int {
+	return m.bounded(length, w, func() int {
+		return m.match(subjectptr, length, offset, flags)
+	})
+}
+
+// bounded calls run, which matches or substitutes with the match context
+// of m, such that it can be abandoned. PCRE2 cannot be interrupted, so
+// run is called with a small match limit which is doubled on every
+// attempt, checking for expiry in between. The total work is at most
+// about twice that of a single unbounded attempt. The match limit of the
+// context is still respected. With a callout, run is called only once,
+// as a restart would show the callouts the same events again.
+func (m *Matcher) bounded(length int, w *Watchdog, run func() int) int {
+	var entry *watchEntry
+	if w != nil {
+		entry = w.begin(m, length)
+		defer w.end(entry)
+	}
+	if m.callout != nil {
+		if !m.deadline.IsZero() && !time.Now().Before(m.deadline) {
+			return ERROR_TIMEOUT
+		}
+		return run()
+	}
+	mc := m.privateContext()
+	limit := mc.matchLimit
+	if limit == 0 {
+		limit = defaultMatchLimit
+	}
+	defer C.pcre2_set_match_limit(mc.ptr, C.uint32_t(limit))
+	step := uint32(deadlineMatchStep)
+	for {
+		if !m.deadline.IsZero() && !time.Now().Before(m.deadline) {
+			return ERROR_TIMEOUT
+		}
+		if entry != nil && entry.interrupted.Load() {
+			return ERROR_TIMEOUT
+		}
+		if step > limit {
+			step = limit
+		}
+		C.pcre2_set_match_limit(mc.ptr, C.uint32_t(step))
+		rc := run()
+		if rc != ERROR_MATCHLIMIT || step == limit {
+			return rc
+		}
+		if step > limit/2 {
+			step = limit
+		} else {
+			step *= 2
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package pcre2
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileWithOptions(t *testing.T) {
+	re, err := CompileWithOptions(`^b\R`, CompileOptions{
+		Flags:   MULTILINE,
+		Newline: NEWLINE_CR,
+		BSR:     BSR_ANYCRLF,
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer re.Free()
+	assert.Equal(t, uint32(NEWLINE_CR), re.Newline())
+	assert.Equal(t, uint32(BSR_ANYCRLF), re.BSR())
+	assert.Equal(t, []int{2, 4}, re.FindIndex([]byte("a\rb\n"), 0))
+
+	_, err = CompileWithOptions(`abcdef`, CompileOptions{MaxPatternLength: 3})
+	if assert.IsType(t, &CompileError{}, err) {
+		assert.Equal(t, ERROR_PATTERN_STRING_TOO_LONG, err.(*CompileError).ErrorNum)
+	}
+	_, err = CompileWithOptions(`((a))`, CompileOptions{ParensNestLimit: 1})
+	if assert.IsType(t, &CompileError{}, err) {
+		assert.Equal(t, ERROR_PARENTHESES_NEST_TOO_DEEP, err.(*CompileError).ErrorNum)
+	}
+	_, err = CompileWithOptions(`a`, CompileOptions{Newline: 99})
+	assert.Error(t, err)
+}
+
+func TestCompileWithOptionsJIT(t *testing.T) {
+	re, err := CompileWithOptions(`a+`, CompileOptions{JIT: JIT_COMPLETE})
+	if err != nil {
+		t.Skip("JIT not available:", err)
+	}
+	defer re.Free()
+	assert.NotZero(t, re.code.jitSize)
+}
+
+func TestExecOptions(t *testing.T) {
+	re := MustCompile(`b+`, 0)
+	defer re.Free()
+	m := re.NewMatcher()
+	defer m.Free()
+
+	assert.True(t, m.MatchStringWithOptions("abbab", MatchOptions{Offset: 3}))
+	assert.Equal(t, []int{4, 5}, m.Index())
+	assert.Equal(t, ERROR_NOMATCH, m.ExecOptions([]byte("abbab"), MatchOptions{Flags: ANCHORED}))
+
+	mc := NewMatchContext()
+	defer mc.Free()
+	mc.SetMatchLimit(1)
+	assert.Equal(t, ERROR_MATCHLIMIT, m.ExecStringOptions("abb", MatchOptions{Context: mc}))
+	assert.Nil(t, m.mctx, "context is only used for the call")
+}
+
+func TestExecOptionsDeadline(t *testing.T) {
+	re := MustCompile(`^(a+)+$`, 0)
+	defer re.Free()
+	m := re.NewMatcher()
+	defer m.Free()
+	subject := strings.Repeat("a", 40) + "b"
+
+	start := time.Now()
+	assert.False(t, m.MatchStringWithOptions(subject, MatchOptions{Deadline: start.Add(50 * time.Millisecond)}))
+	assert.Equal(t, ErrTimeout, m.GetError())
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	rc := m.ExecStringOptions("aaaa", MatchOptions{Deadline: time.Now().Add(time.Minute)})
+	assert.Equal(t, 2, rc)
+
+	mc := NewMatchContext()
+	defer mc.Free()
+	mc.SetMatchLimit(1000)
+	m.SetMatchContext(mc)
+	rc = m.ExecStringOptions(subject, MatchOptions{Deadline: time.Now().Add(time.Minute)})
+	assert.Equal(t, ERROR_MATCHLIMIT, rc)
+}
+
+func TestExecOptionsTimeout(t *testing.T) {
+	re := MustCompile(`^(a+)+$`, 0)
+	defer re.Free()
+	m := re.NewMatcher()
+	defer m.Free()
+	subject := strings.Repeat("a", 40) + "b"
+
+	opts := MatchOptions{}.WithTimeout(20 * time.Millisecond)
+	assert.False(t, m.MatchStringWithOptions(subject, opts))
+	err := m.GetError()
+	assert.Equal(t, ErrTimeout, err)
+	if te, ok := err.(interface{ Timeout() bool }); assert.True(t, ok) {
+		assert.True(t, te.Timeout())
+	}
+
+	// The earlier of deadline and timeout applies.
+	opts = opts.WithTimeout(time.Hour).WithDeadline(time.Now().Add(-time.Second))
+	assert.Equal(t, ERROR_TIMEOUT, m.ExecStringOptions("aaa", opts))
+	assert.True(t, m.MatchStringWithOptions("aaa", MatchOptions{Timeout: time.Hour}))
+}
+
+func TestExecOptionsDeadlineCallout(t *testing.T) {
+	re := MustCompile(`^(a|aa)+$`, AUTO_CALLOUT|NO_JIT)
+	defer re.Free()
+	m := re.NewMatcher()
+	defer m.Free()
+	calls := 0
+	m.SetCallout(func(*CalloutBlock) int {
+		calls++
+		return 0
+	})
+	subject := strings.Repeat("a", 18) + "b"
+
+	assert.Equal(t, ERROR_NOMATCH, m.ExecString(subject, 0))
+	want := calls
+	assert.Greater(t, want, deadlineMatchStep, "enough work for several slices")
+
+	// The match is not restarted, which would repeat the callouts.
+	calls = 0
+	assert.Equal(t, ERROR_NOMATCH, m.ExecStringOptions(subject, MatchOptions{Timeout: time.Hour}))
+	assert.Equal(t, want, calls)
+	assert.Equal(t, ERROR_TIMEOUT, m.ExecStringOptions(subject, MatchOptions{Deadline: time.Now().Add(-time.Second)}))
+}
@@ -0,0 +1,243 @@
+package pcre2
+
+/*
+#define PCRE2_CODE_UNIT_WIDTH 8
+
+#include <pcre2.h>
+*/
+import "C"
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// PatternSpec describes a pattern of a PatternSet.
+type PatternSpec struct {
+	ID      string // user-supplied identifier, may be empty
+	Pattern string
+	Flags   uint32 // compile flags
+}
+
+// PatternSet is a list of compiled patterns which is compiled, serialized
+// and loaded as one unit. Reload replaces all patterns atomically, so
+// concurrent lookups see either the old or the new set.
+type PatternSet struct {
+	data atomic.Pointer[patternSetData]
+}
+
+type patternSetData struct {
+	specs   []PatternSpec
+	regexps []*Regexp
+	byID    map[string]int
+}
+
+// patternSetMagic starts the serialized form of a PatternSet. It is
+// followed by the number of patterns, their specs and the PCRE2 blob.
+var patternSetMagic = []byte("PCRE2SET\x01")
+
+// CompilePatternSet compiles all patterns into a PatternSet. If one of
+// them fails, the error names it.
+func CompilePatternSet(specs []PatternSpec) (*PatternSet, error) {
+	d := &patternSetData{
+		specs:   append([]PatternSpec(nil), specs...),
+		regexps: make([]*Regexp, 0, len(specs)),
+	}
+	for i, spec := range specs {
+		re, err := Compile(spec.Pattern, spec.Flags)
+		if err != nil {
+			d.free()
+			return nil, fmt.Errorf("pattern %d (%q): %w", i, spec.ID, err)
+		}
+		re.own()
+		d.regexps = append(d.regexps, re)
+	}
+	if err := d.index(); err != nil {
+		d.free()
+		return nil, err
+	}
+	s := new(PatternSet)
+	s.data.Store(d)
+	return s, nil
+}
+
+// LoadPatternSet recreates a PatternSet from data created by Serialize.
+// Like DeserializeRegexp, it must only be given trusted data.
+func LoadPatternSet(data []byte) (*PatternSet, error) {
+	d, err := decodePatternSet(data)
+	if err != nil {
+		return nil, err
+	}
+	s := new(PatternSet)
+	s.data.Store(d)
+	return s, nil
+}
+
+// index builds the ID lookup table.
+func (d *patternSetData) index() error {
+	d.byID = make(map[string]int, len(d.specs))
+	for i, spec := range d.specs {
+		if spec.ID == "" {
+			continue
+		}
+		if _, dup := d.byID[spec.ID]; dup {
+			return fmt.Errorf("duplicate pattern ID %q", spec.ID)
+		}
+		d.byID[spec.ID] = i
+	}
+	return nil
+}
+
+func (d *patternSetData) free() {
+	for _, re := range d.regexps {
+		re.Free()
+	}
+}
+
+// Len returns the number of patterns in the set.
+func (s *PatternSet) Len() int {
+	return len(s.data.Load().regexps)
+}
+
+// Get returns the compiled pattern at index i.
+func (s *PatternSet) Get(i int) *Regexp {
+	return s.data.Load().regexps[i]
+}
+
+// Spec returns the description of the pattern at index i.
+func (s *PatternSet) Spec(i int) PatternSpec {
+	return s.data.Load().specs[i]
+}
+
+// Lookup returns the compiled pattern with the given ID.
+func (s *PatternSet) Lookup(id string) (*Regexp, bool) {
+	d := s.data.Load()
+	i, ok := d.byID[id]
+	if !ok {
+		return nil, false
+	}
+	return d.regexps[i], true
+}
+
+// Serialize encodes all patterns into a single blob, which can be loaded
+// with LoadPatternSet or Reload.
+func (s *PatternSet) Serialize() ([]byte, error) {
+	d := s.data.Load()
+	var buf bytes.Buffer
+	buf.Write(patternSetMagic)
+	buf.Write(binary.AppendUvarint(nil, uint64(len(d.specs))))
+	for _, spec := range d.specs {
+		writeString(&buf, spec.ID)
+		writeString(&buf, spec.Pattern)
+		buf.Write(binary.AppendUvarint(nil, uint64(spec.Flags)))
+	}
+	if len(d.regexps) > 0 {
+		codes := make([]*C.pcre2_code, len(d.regexps))
+		for i, re := range d.regexps {
+			rptr, err := re.validRegexpPtr()
+			if err != nil {
+				return nil, err
+			}
+			codes[i] = rptr
+		}
+		blob, err := serialize(codes)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(blob)
+	}
+	return buf.Bytes(), nil
+}
+
+// Reload atomically replaces the patterns of the set with those in data,
+// as created by Serialize. Patterns obtained from the set before remain
+// valid; they are released by the garbage collector.
+func (s *PatternSet) Reload(data []byte) error {
+	d, err := decodePatternSet(data)
+	if err != nil {
+		return err
+	}
+	s.data.Store(d)
+	return nil
+}
+
+// Free releases the C resources of all patterns in the set. The
+// patterns must no longer be in use.
+func (s *PatternSet) Free() error {
+	s.data.Load().free()
+	return nil
+}
+
+var errBadPatternSet = errors.New("invalid serialized pattern set")
+
+func decodePatternSet(data []byte) (*patternSetData, error) {
+	if !bytes.HasPrefix(data, patternSetMagic) {
+		return nil, errBadPatternSet
+	}
+	r := bytes.NewReader(data[len(patternSetMagic):])
+	n, err := binary.ReadUvarint(r)
+	if err != nil || n > uint64(r.Len()) {
+		return nil, errBadPatternSet
+	}
+	d := &patternSetData{specs: make([]PatternSpec, n)}
+	for i := range d.specs {
+		spec := &d.specs[i]
+		if spec.ID, err = readString(r); err != nil {
+			return nil, errBadPatternSet
+		}
+		if spec.Pattern, err = readString(r); err != nil {
+			return nil, errBadPatternSet
+		}
+		flags, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, errBadPatternSet
+		}
+		spec.Flags = uint32(flags)
+	}
+	if err := d.index(); err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return d, nil
+	}
+	codes, err := deserialize(data[len(data)-r.Len():])
+	if err != nil {
+		return nil, err
+	}
+	d.regexps = make([]*Regexp, len(codes))
+	for i, ptr := range codes {
+		// As in CompilePatternSet, the set owns the patterns, which
+		// are released by the garbage collector after a Reload.
+		d.regexps[i] = newRegexp("", ptr)
+		d.regexps[i].own()
+	}
+	if len(codes) != len(d.specs) {
+		d.free()
+		return nil, errBadPatternSet
+	}
+	for i, re := range d.regexps {
+		re.Pattern = d.specs[i].Pattern
+	}
+	return d, nil
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	buf.Write(binary.AppendUvarint(nil, uint64(len(s))))
+	buf.WriteString(s)
+}
+
+func readString(r *bytes.Reader) (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	if n > uint64(r.Len()) {
+		return "", errBadPatternSet
+	}
+	b := make([]byte, n)
+	r.Read(b)
+	return string(b), nil
+}
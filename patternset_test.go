@@ -0,0 +1,91 @@
+package pcre2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatternSet(t *testing.T) {
+	specs := []PatternSpec{
+		{ID: "email", Pattern: `^\w+@\w+$`},
+		{Pattern: `^\d+$`},
+		{ID: "word", Pattern: `^abc$`, Flags: CASELESS},
+	}
+	s, err := CompilePatternSet(specs)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer s.Free()
+	assert.Equal(t, 3, s.Len())
+	re, ok := s.Lookup("email")
+	assert.True(t, ok)
+	assert.True(t, re.MatcherString("a@b", 0).Matches())
+	_, ok = s.Lookup("missing")
+	assert.False(t, ok)
+
+	data, err := s.Serialize()
+	if !assert.NoError(t, err) {
+		return
+	}
+	loaded, err := LoadPatternSet(data)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer loaded.Free()
+	assert.Equal(t, specs[2], loaded.Spec(2))
+	assert.Equal(t, `^\d+$`, loaded.Get(1).Pattern)
+	assert.True(t, loaded.Get(1).MatcherString("123", 0).Matches())
+	re, _ = loaded.Lookup("word")
+	assert.True(t, re.MatcherString("ABC", 0).Matches())
+
+	// Reload replaces the patterns, old ones stay usable.
+	other, err := CompilePatternSet([]PatternSpec{{ID: "x", Pattern: `x`}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	data, err = other.Serialize()
+	assert.NoError(t, err)
+	old := loaded.Get(0)
+	assert.NoError(t, loaded.Reload(data))
+	assert.Equal(t, 1, loaded.Len())
+	_, ok = loaded.Lookup("x")
+	assert.True(t, ok)
+	assert.True(t, old.MatcherString("a@b", 0).Matches())
+}
+
+func TestPatternSetErrors(t *testing.T) {
+	_, err := CompilePatternSet([]PatternSpec{{ID: "ok", Pattern: `a`}, {ID: "bad", Pattern: `(`}})
+	assert.ErrorContains(t, err, `pattern 1 ("bad")`)
+	_, err = CompilePatternSet([]PatternSpec{{ID: "a", Pattern: `a`}, {ID: "a", Pattern: `b`}})
+	assert.ErrorContains(t, err, "duplicate")
+
+	_, err = LoadPatternSet([]byte("garbage"))
+	assert.Equal(t, errBadPatternSet, err)
+	_, err = LoadPatternSet(append(append([]byte(nil), patternSetMagic...), 0xff))
+	assert.Equal(t, errBadPatternSet, err)
+
+	empty, err := CompilePatternSet(nil)
+	assert.NoError(t, err)
+	data, err := empty.Serialize()
+	assert.NoError(t, err)
+	empty, err = LoadPatternSet(data)
+	assert.NoError(t, err)
+	assert.Zero(t, empty.Len())
+}
+
+func TestPatternSetReloadNoLeaks(t *testing.T) {
+	SetLeakDetector(func(Allocation) {})
+	defer SetLeakDetector(nil)
+
+	s, err := CompilePatternSet([]PatternSpec{{ID: "a", Pattern: `a`}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer s.Free()
+	data, err := s.Serialize()
+	assert.NoError(t, err)
+	assert.NoError(t, s.Reload(data))
+	assert.NoError(t, s.Reload(data))
+	assert.Empty(t, LiveAllocations(), "patterns of the set are not leaks")
+}
@@ -54,7 +54,6 @@
 package pcre2
 
 /*
-#cgo pkg-config: libpcre2-8
 #define PCRE2_CODE_UNIT_WIDTH 8
 
 #include <pcre2.h>
@@ -98,9 +97,11 @@ import "C"
 import (
 	"errors"
 	"fmt"
-	"reflect"
+	"log/slog"
 	"runtime"
+	"strings"
 	"sync"
+	"time"
 	"unsafe"
 )
 
@@ -159,6 +160,9 @@ const (
 	EXTRA_BAD_ESCAPE_IS_LITERAL   = C.PCRE2_EXTRA_BAD_ESCAPE_IS_LITERAL   /* C */
 	EXTRA_MATCH_WORD              = C.PCRE2_EXTRA_MATCH_WORD              /* C */
 	EXTRA_MATCH_LINE              = C.PCRE2_EXTRA_MATCH_LINE              /* C */
+	// EXTRA_ALLOW_LOOKAROUND_BSK needs FeatureLookaroundBSK; older
+	// libraries fail to compile patterns with it.
+	EXTRA_ALLOW_LOOKAROUND_BSK = C.PCRE2_EXTRA_ALLOW_LOOKAROUND_BSK /* C */
 )
 
 // These are for JITCompile()
@@ -189,11 +193,14 @@ const (
 // These are additional options for Substitute(), which passes any others
 // through to Match().
 const (
-	SUBSTITUTE_GLOBAL          = C.PCRE2_SUBSTITUTE_GLOBAL
-	SUBSTITUTE_EXTENDED        = C.PCRE2_SUBSTITUTE_EXTENDED
-	SUBSTITUTE_UNSET_EMPTY     = C.PCRE2_SUBSTITUTE_UNSET_EMPTY
-	SUBSTITUTE_UNKNOWN_UNSET   = C.PCRE2_SUBSTITUTE_UNKNOWN_UNSET
-	SUBSTITUTE_OVERFLOW_LENGTH = C.PCRE2_SUBSTITUTE_OVERFLOW_LENGTH
+	SUBSTITUTE_GLOBAL           = C.PCRE2_SUBSTITUTE_GLOBAL
+	SUBSTITUTE_EXTENDED         = C.PCRE2_SUBSTITUTE_EXTENDED
+	SUBSTITUTE_UNSET_EMPTY      = C.PCRE2_SUBSTITUTE_UNSET_EMPTY
+	SUBSTITUTE_UNKNOWN_UNSET    = C.PCRE2_SUBSTITUTE_UNKNOWN_UNSET
+	SUBSTITUTE_OVERFLOW_LENGTH  = C.PCRE2_SUBSTITUTE_OVERFLOW_LENGTH
+	SUBSTITUTE_LITERAL          = C.PCRE2_SUBSTITUTE_LITERAL
+	SUBSTITUTE_MATCHED          = C.PCRE2_SUBSTITUTE_MATCHED
+	SUBSTITUTE_REPLACEMENT_ONLY = C.PCRE2_SUBSTITUTE_REPLACEMENT_ONLY
 )
 
 // A further option for Match(), not allowed for DfaMatch(), ignored for JITMatch().
@@ -399,6 +406,7 @@ const (
 	ERROR_NOUNIQUESUBSTRING = C.PCRE2_ERROR_NOUNIQUESUBSTRING
 	ERROR_NULL              = C.PCRE2_ERROR_NULL
 	ERROR_RECURSELOOP       = C.PCRE2_ERROR_RECURSELOOP
+	ERROR_DEPTHLIMIT        = C.PCRE2_ERROR_DEPTHLIMIT
 	ERROR_RECURSIONLIMIT    = C.PCRE2_ERROR_RECURSIONLIMIT /* Obsolete synonym */
 	ERROR_UNAVAILABLE       = C.PCRE2_ERROR_UNAVAILABLE
 	ERROR_UNSET             = C.PCRE2_ERROR_UNSET
@@ -414,6 +422,14 @@ const (
 	ERROR_INTERNAL_DUPMATCH = C.PCRE2_ERROR_INTERNAL_DUPMATCH
 )
 
+// Error codes for conditions detected by this package rather than by
+// PCRE2. They are outside the range used by PCRE2, and GetError returns
+// the corresponding Err* value for them.
+const (
+	ERROR_SUBJECT_TOO_LONG = -1001
+	ERROR_TIMEOUT          = -1002
+)
+
 // Request types for PatternInfo()
 const (
 	INFO_ALLOPTIONS     = C.PCRE2_INFO_ALLOPTIONS
@@ -448,6 +464,7 @@ const (
 // Request types for Config().
 const (
 	CONFIG_BSR               = C.PCRE2_CONFIG_BSR
+	CONFIG_DEPTHLIMIT        = C.PCRE2_CONFIG_DEPTHLIMIT
 	CONFIG_JIT               = C.PCRE2_CONFIG_JIT
 	CONFIG_JITTARGET         = C.PCRE2_CONFIG_JITTARGET
 	CONFIG_LINKSIZE          = C.PCRE2_CONFIG_LINKSIZE
@@ -471,6 +488,19 @@ const (
 	UNSET           = C.PCRE2_UNSET
 )
 
+// Flags in CalloutBlock.Flags
+const (
+	CALLOUT_STARTMATCH = C.PCRE2_CALLOUT_STARTMATCH
+	CALLOUT_BACKTRACK  = C.PCRE2_CALLOUT_BACKTRACK
+)
+
+// errorMessage returns the PCRE2 message text for an error code.
+func errorMessage(code int) string {
+	rawbytes := C.MY_pcre2_get_error_message(C.int(code))
+	defer C.free(unsafe.Pointer(rawbytes))
+	return C.GoString((*C.char)(rawbytes))
+}
+
 // Constants used to determine the right size of the matchData structure
 var (
 	pcre2Size             int
@@ -478,25 +508,101 @@ var (
 	contextSize           int
 )
 
+// Whether the linked library was built with Unicode support
+var unicodeSupported bool
+
+// The match limit of the library, used when a context does not set one
+var defaultMatchLimit uint32
+
 func init() {
 	C.myInitSizes()
 	pcre2Size = int(C.myPcre2Size)
 	myStaticMatchDataSize = int(C.myStaticMatchDataSize)
 	contextSize = int(C.myContextSize)
+
+	var unicode C.uint32_t
+	C.pcre2_config(CONFIG_UNICODE, unsafe.Pointer(&unicode))
+	unicodeSupported = unicode != 0
+
+	var matchLimit C.uint32_t
+	C.pcre2_config(CONFIG_MATCHLIMIT, unsafe.Pointer(&matchLimit))
+	defaultMatchLimit = uint32(matchLimit)
 }
 
 var (
 	// ErrInvalidRegexp is returned when the provided Regexp is
 	// not backed by a proper C pointer to pcre2_code
 	ErrInvalidRegexp = errors.New("invalid regexp")
+
+	// ErrNoMemory is returned when the C library fails to allocate memory
+	ErrNoMemory = errors.New("out of memory")
+
+	// ErrUnicodeUnavailable is returned when compiling with UTF or UCP
+	// against a PCRE2 library that was built without Unicode support
+	ErrUnicodeUnavailable = errors.New("PCRE2 library was built without Unicode support: " +
+		"rebuild it with --enable-unicode, or compile without UTF and UCP")
+
+	// ErrNoMatch is returned when a subject does not match the pattern
+	ErrNoMatch = errors.New("no match")
+
+	// ErrMatcherFreed is returned when a Matcher is used after Free
+	ErrMatcherFreed = errors.New("matcher used after free")
+
+	// ErrGroupOutOfRange is returned for capture group numbers which
+	// do not exist in the pattern
+	ErrGroupOutOfRange = errors.New("capture group out of range")
+
+	// ErrGroupNotPresent is returned when a capture group which did not
+	// participate in the match is converted, see GroupInt
+	ErrGroupNotPresent = errors.New("capture group not present")
 )
 
 // Regexp holds a reference to a compiled regular expression.
 // Use Compile or MustCompile to create such objects.
 type Regexp struct {
 	Pattern string
+	ptr     *C.pcre2_code // code.ptr, or nil once freed
+	code    *regexpCode
+	cleanup runtime.Cleanup
+	autoJIT *autoJIT
+	// maximum subject length, see SetMaxSubjectLength
+	maxSubjectLength int
+	// the pattern matches its own text, see literal.go
+	literal bool
+	longest bool // see Longest
+}
+
+// regexpCode holds the C resources of a Regexp. It is separate from the
+// Regexp, so that the cleanup run when the Regexp is garbage collected
+// can free them without keeping the Regexp reachable.
+type regexpCode struct {
 	ptr     *C.pcre2_code
-	cleanup sync.Once
+	pattern string      // for logging
+	size    int64       // compiled size, as accounted in Snapshot
+	jitSize int64       // JIT compiled size, as accounted in Snapshot
+	tables  *Tables     // external character tables, or nil
+	alloc   *Allocation // leak detection, or nil
+	once    sync.Once
+}
+
+// collect is the cleanup of a Regexp which was not freed.
+func (c *regexpCode) collect() {
+	leaked(c.alloc)
+	logEvent(slog.LevelDebug, "pcre2: freed by garbage collector",
+		slog.String("kind", "Regexp"), slog.String("pattern", c.pattern))
+	c.free()
+}
+
+func (c *regexpCode) free() {
+	c.once.Do(func() {
+		C.pcre2_code_free(c.ptr)
+		c.ptr = nil
+		statLiveRegexps.Add(-1)
+		statCompiledBytes.Add(-c.size)
+		statJITBytes.Add(-c.jitSize)
+		c.tables.release()
+		c.tables = nil
+	})
 }
 
 // Number of bytes in the compiled pattern
@@ -505,6 +611,24 @@ func pcreSize(ptr *C.pcre2_code) (size C.PCRE2_SIZE) {
 	return
 }
 
+// Number of bytes in the JIT compiled code, or zero
+func pcreJITSize(ptr *C.pcre2_code) (size C.size_t) {
+	C.pcre2_pattern_info(ptr, INFO_JITSIZE, unsafe.Pointer(&size))
+	return
+}
+
+// Compile options, including those set within the pattern
+func pcreOptions(ptr *C.pcre2_code) (options C.uint32_t) {
+	C.pcre2_pattern_info(ptr, INFO_ALLOPTIONS, unsafe.Pointer(&options))
+	return
+}
+
+// Newline convention of the pattern
+func pcreNewline(ptr *C.pcre2_code) (newline C.uint32_t) {
+	C.pcre2_pattern_info(ptr, INFO_NEWLINE, unsafe.Pointer(&newline))
+	return
+}
+
 // Number of capture groups
 func pcreGroups(ptr *C.pcre2_code) (count C.PCRE2_SIZE) {
 	C.pcre2_pattern_info(ptr, INFO_CAPTURECOUNT, unsafe.Pointer(&count))
@@ -514,16 +638,37 @@ func pcreGroups(ptr *C.pcre2_code) (count C.PCRE2_SIZE) {
 type matchData struct {
 	md      *C.pcre2_match_data
 	ovector []C.PCRE2_SIZE
-	cleanup sync.Once
+	cleanup runtime.Cleanup
+	alloc   *Allocation // leak detection, or nil
+}
+
+func freeMatchData(md *C.pcre2_match_data) {
+	C.pcre2_match_data_free(md)
+	statLiveMatchData.Add(-1)
+}
+
+// matchDataCleanup holds what the cleanup of a matchData needs, without
+// referring to it.
+type matchDataCleanup struct {
+	md    *C.pcre2_match_data
+	alloc *Allocation
 }
 
-func finalizeMatchData(m *matchData) {
-	if m != nil && m.md != nil {
-		m.cleanup.Do(func() {
-			m.ovector = []C.PCRE2_SIZE{}
-			C.pcre2_match_data_free(m.md)
-			m.md = nil
-		})
+func (c matchDataCleanup) collect() {
+	leaked(c.alloc)
+	logEvent(slog.LevelDebug, "pcre2: freed by garbage collector",
+		slog.String("kind", "Matcher"))
+	freeMatchData(c.md)
+}
+
+// free releases the match data, which must not be used afterwards.
+func (m *matchData) free() {
+	if m.md != nil {
+		m.cleanup.Stop()
+		untrack(m.alloc)
+		freeMatchData(m.md)
+		m.md = nil
+		m.ovector = nil
 	}
 }
 
@@ -533,63 +678,159 @@ func (md *matchData) ensureNotFreed() {
 	}
 }
 
+// offsets returns the offsets of the numbered capture group. All ovector
+// access goes through here: the entries are unsigned PCRE2_SIZE values,
+// which are only compared with UNSET before converting them to int64.
+// ok is false for groups which are unset.
+//
+// Offsets are kept as int64 up to the API, so that they are never
+// truncated; a subject held in Go memory cannot be longer than the
+// maximum int, so converting them to int for the []int API is safe.
+func (md *matchData) offsets(group int) (start, end int64, ok bool) {
+	md.ensureNotFreed()
+	s, e := md.ovector[2*group], md.ovector[2*group+1]
+	if s == UNSET || e == UNSET {
+		return -1, -1, false
+	}
+	return int64(s), int64(e), true
+}
+
 // We don't use pcre2_match_data_create, because we want this to be in Go memory.
 // This way it's garbage collected.
-func (re *Regexp) matchDataCreate() (result *matchData) {
+// It fails with ErrNoMemory if the allocator returns no memory.
+func (re *Regexp) matchDataCreate() (result *matchData, err error) {
 	result = &matchData{}
 	oveccount := re.Groups() + 1
 
-	result.md = C.pcre2_match_data_create_from_pattern(re.ptr, nil)
-	povec := C.pcre2_get_ovector_pointer(result.md)
-	ovecHead := reflect.SliceHeader{
-		Data: uintptr(unsafe.Pointer(povec)),
-		Len:  2 * oveccount,
-		Cap:  2 * oveccount,
+	result.md = C.pcre2_match_data_create_from_pattern(re.ptr, generalContext)
+	if result.md == nil {
+		return nil, ErrNoMemory
+	}
+	result.ovector = unsafe.Slice(C.pcre2_get_ovector_pointer(result.md), 2*oveccount)
+	statLiveMatchData.Add(1)
+	result.alloc = track("Matcher", re.Pattern)
+	if autoCleanup.Load() || result.alloc != nil {
+		result.cleanup = runtime.AddCleanup(result, matchDataCleanup.collect,
+			matchDataCleanup{result.md, result.alloc})
 	}
-	result.ovector = *(*[]C.PCRE2_SIZE)(unsafe.Pointer(&ovecHead))
-	runtime.SetFinalizer(result, finalizeMatchData)
 	return
 }
 
 // Compile the pattern and return a compiled regexp.
-// If compilation fails, the second return value holds a *CompileError.
+// If compilation fails, the second return value holds a *CompileError,
+// or ErrUnicodeUnavailable if UTF or UCP is requested from a library
+// without Unicode support.
 func Compile(pattern string, flags uint32) (*Regexp, error) {
-	pattern1 := C.CString(pattern)
-	defer C.free(unsafe.Pointer(pattern1))
-	if clen := int(C.strlen(pattern1)); clen != len(pattern) {
+	return CompileWithContext(pattern, flags, nil)
+}
+
+// CompileWithContext is like Compile, but applies the settings of the
+// compile context. A nil context uses the default settings.
+func CompileWithContext(pattern string, flags uint32, cc *CompileContext) (*Regexp, error) {
+	return compileWithContext(pattern, flags, cc, true)
+}
+
+// compileWithContext is like CompileWithContext, but logs a failure
+// only if logFailure is set.
+func compileWithContext(pattern string, flags uint32, cc *CompileContext, logFailure bool) (*Regexp, error) {
+	if i := strings.IndexByte(pattern, 0); i >= 0 {
 		return nil, &CompileError{
 			Pattern: pattern,
 			Message: "NUL byte in pattern",
-			Offset:  clen,
+			Offset:  i,
 		}
 	}
+	return compile(pattern, flags, cc, logFailure)
+}
+
+// CompileBytes is like Compile, but takes the pattern as a byte slice,
+// which is passed to PCRE2 by length without copying. Unlike Compile it
+// accepts NUL bytes, which are literal characters in the pattern.
+func CompileBytes(pattern []byte, flags uint32) (*Regexp, error) {
+	return compile(pattern, flags, nil, true)
+}
+
+func compile[S subject](pattern S, flags uint32, cc *CompileContext, logFailure bool) (_ *Regexp, err error) {
+	if h := metrics.Load(); h != nil {
+		defer observeCompile(h, string(pattern), time.Now(), &err)
+	}
+	if logFailure && logger.Load() != nil {
+		defer logCompile(string(pattern), &err)
+	}
+	if !unicodeSupported && flags&(UTF|UCP) != 0 {
+		return nil, ErrUnicodeUnavailable
+	}
+	if cc.patternTooLong(len(pattern)) {
+		return nil, &CompileError{
+			Pattern:  string(pattern),
+			Message:  errorMessage(ERROR_PATTERN_STRING_TOO_LONG),
+			ErrorNum: ERROR_PATTERN_STRING_TOO_LONG,
+		}
+	}
+	patternptr := dataPtr(pattern)
 	var errnum C.int
 	var erroffset C.PCRE2_SIZE
 	ptr := C.pcre2_compile(
-		C.PCRE2_SPTR(unsafe.Pointer(pattern1)),
+		C.PCRE2_SPTR(unsafe.Pointer(patternptr)),
 		C.size_t(len(pattern)),
 		C.uint32_t(flags),
 		&errnum,
 		&erroffset,
-		nil,
+		cc.pointer(),
 	)
 	if ptr == nil {
-		rawbytes := C.MY_pcre2_get_error_message(errnum)
-		msg := C.GoString((*C.char)(rawbytes))
-		C.free(unsafe.Pointer(rawbytes))
-
 		return nil, &CompileError{
-			Pattern: pattern,
-			Message: msg,
-			Offset:  int(erroffset),
+			Pattern:  string(pattern),
+			Message:  errorMessage(int(errnum)),
+			Offset:   int(erroffset),
+			ErrorNum: int(errnum),
 		}
 	}
+	re := newRegexp(string(pattern), ptr)
+	re.literal = isLiteral(pattern, flags, cc)
+	if cc != nil && cc.res.tables != nil {
+		re.code.tables = cc.res.tables
+		re.code.tables.acquire()
+	}
+	return re, nil
+}
+
+// newRegexp wraps a freshly allocated pcre2_code and takes ownership of it.
+func newRegexp(pattern string, ptr *C.pcre2_code) *Regexp {
 	re := &Regexp{
 		Pattern: pattern,
 		ptr:     ptr,
+		code: &regexpCode{
+			ptr:     ptr,
+			pattern: pattern,
+			size:    int64(pcreSize(ptr)),
+			alloc:   track("Regexp", pattern),
+		},
 	}
-	runtime.SetFinalizer(re, finalizeRegex)
-	return re, nil
+	statLiveRegexps.Add(1)
+	statCompiledBytes.Add(re.code.size)
+	re.addCleanup(false)
+	return re
+}
+
+// addCleanup arranges for the C resources of re to be freed when re is
+// garbage collected, unless disabled with SetAutoCleanup and not
+// forced. It replaces any cleanup arranged before.
+func (re *Regexp) addCleanup(force bool) {
+	re.cleanup.Stop()
+	re.cleanup = runtime.Cleanup{}
+	if force || autoCleanup.Load() || re.code.alloc != nil {
+		re.cleanup = runtime.AddCleanup(re, (*regexpCode).collect, re.code)
+	}
+}
+
+// own makes re owned by the package, e.g. by the pattern cache: it is
+// not reported as a leak, and its C resources are freed once it is
+// garbage collected, even if automatic cleanup is disabled.
+func (re *Regexp) own() {
+	untrack(re.code.alloc)
+	re.code.alloc = nil
+	re.addCleanup(true)
 }
 
 // CompileJIT is a combination of Compile and Study. It first compiles
@@ -628,20 +869,34 @@ func MustCompileJIT(pattern string, comFlags, jitFlags uint32) (re *Regexp) {
 // The returned value from JITCompile() is nil on success, or an error otherwise.
 // If JIT support is not available, a call to JITCompile() does nothing and returns ERROR_JIT_BADOPTION.
 func (re *Regexp) JITCompile(flags uint32) error {
+	err := re.jitCompile(flags)
+	if jerr, ok := err.(*JITError); ok {
+		logEvent(slog.LevelWarn, "pcre2: JIT compile failed",
+			slog.String("pattern", re.Pattern), slog.Any("error", jerr))
+	}
+	return err
+}
+
+// jitCompile is like JITCompile, but does not log failures.
+func (re *Regexp) jitCompile(flags uint32) (err error) {
 	rptr, err := re.validRegexpPtr()
 	if err != nil {
 		return err
 	}
+	if h := metrics.Load(); h != nil {
+		defer observeJITCompile(h, re, time.Now(), &err)
+	}
 	res := C.pcre2_jit_compile(rptr, C.uint(flags))
 	if res != 0 {
-		rawbytes := C.MY_pcre2_get_error_message(res)
-		msg := C.GoString((*C.char)(rawbytes))
-		C.free(unsafe.Pointer(rawbytes))
 		return &JITError{
 			ErrorNum: int(res),
-			Message:  msg,
+			Message:  errorMessage(int(res)),
 		}
 	}
+	// JIT compiling again for other modes grows the existing code.
+	jitSize := int64(pcreJITSize(rptr))
+	statJITBytes.Add(jitSize - re.code.jitSize)
+	re.code.jitSize = jitSize
 	return nil
 }
 
@@ -656,25 +911,86 @@ func (re *Regexp) validRegexpPtr() (*C.pcre2_code, error) {
 	return nil, ErrInvalidRegexp
 }
 
-func finalizeRegex(r *Regexp) {
-	if r != nil && r.ptr != nil {
-		r.cleanup.Do(func() {
-			C.pcre2_code_free(r.ptr)
-			r.ptr = nil
-		})
-	}
-}
-
 // Free releases the underlying C resources
 func (re *Regexp) Free() error {
 	if re == nil || re.ptr == nil {
 		return nil
 	}
-	finalizeRegex(re)
-	runtime.SetFinalizer(re, nil)
+	re.cleanup.Stop()
+	untrack(re.code.alloc)
+	re.code.free()
+	re.ptr = nil
 	return nil
 }
 
+// Clone returns an independent copy of the compiled pattern, without
+// parsing the pattern text again. The copy can be used and freed separately
+// from the original. JIT compiled code is not copied; call JITCompile on
+// the clone if needed.
+func (re *Regexp) Clone() (*Regexp, error) {
+	rptr, err := re.validRegexpPtr()
+	if err != nil {
+		return nil, err
+	}
+	if err := Require(FeatureCodeCopy); err != nil {
+		return nil, err
+	}
+	ptr := C.pcre2_code_copy(rptr)
+	if ptr == nil {
+		return nil, ErrNoMemory
+	}
+	clone := newRegexp(re.Pattern, ptr)
+	clone.literal = re.literal
+	clone.longest = re.longest
+	if tables := re.code.tables; tables != nil {
+		clone.code.tables = tables
+		tables.acquire()
+	}
+	return clone, nil
+}
+
+// CloneWithTables is like Clone, but the copy also gets its own copy of
+// the character tables used by the pattern. The clone therefore remains
+// valid after the original Regexp, and any external tables it was
+// compiled with, have been freed.
+func (re *Regexp) CloneWithTables() (*Regexp, error) {
+	rptr, err := re.validRegexpPtr()
+	if err != nil {
+		return nil, err
+	}
+	if err := Require(FeatureCodeCopyWithTables); err != nil {
+		return nil, err
+	}
+	ptr := C.pcre2_code_copy_with_tables(rptr)
+	if ptr == nil {
+		return nil, ErrNoMemory
+	}
+	clone := newRegexp(re.Pattern, ptr)
+	clone.literal = re.literal
+	clone.longest = re.longest
+	return clone, nil
+}
+
+// Newline returns the newline convention of the compiled pattern,
+// one of the NEWLINE_* constants.
+func (re *Regexp) Newline() uint32 {
+	if re.ptr == nil {
+		panic("Regexp.Newline: uninitialized")
+	}
+	return uint32(pcreNewline(re.ptr))
+}
+
+// BSR returns the characters matched by \R in the compiled pattern,
+// either BSR_UNICODE or BSR_ANYCRLF.
+func (re *Regexp) BSR() uint32 {
+	if re.ptr == nil {
+		panic("Regexp.BSR: uninitialized")
+	}
+	var bsr C.uint32_t
+	C.pcre2_pattern_info(re.ptr, INFO_BSR, unsafe.Pointer(&bsr))
+	return uint32(bsr)
+}
+
 // Groups returns the number of capture groups in the compiled pattern.
 func (re *Regexp) Groups() int {
 	if re.ptr == nil {
@@ -690,20 +1006,48 @@ type Matcher struct {
 	re       *Regexp
 	groups   int
 	mData    *matchData
-	matches  bool   // last match was successful
-	partial  bool   // was the last match a partial match?
-	rc       int    // return code of the match function, useful to know if there was an error
-	subjects string // one of these fields is set to record the subject,
-	subjectb []byte // so that Group/GroupString can return slices
+	mctx     *MatchContext // set with SetMatchContext, or nil
+	callout  calloutFunc
+	jitPool  *JITStackPool
+	jitStack *JITStack // set with SetJITStack, or nil
+	matches  bool      // last match was successful
+	partial  bool      // was the last match a partial match?
+	rc       int       // return code of the match function, useful to know if there was an error
+	subjects string    // one of these fields is set to record the subject,
+	subjectb []byte    // so that Group/GroupString can return slices
+	deadline time.Time // deadline of the current match, see MatchOptions
+	// copySubjects is one of copyDefault, copyAlways and copyNever,
+	// see SetCopySubjects
+	copySubjects int8
+	dfaWorkspace []C.int // see matchLongest
+	// substituteCallout is invoked for every replacement of Substitute
+	substituteCallout substituteCalloutFunc
+	// private is a copy of the shared context privateBase, as of its
+	// change privateGen, see privateContext
+	private     *MatchContext
+	privateBase *MatchContext
+	privateGen  uint64
 }
 
 // NewMatcher creates a new matcher object for the given Regexp.
+// It panics with ErrNoMemory if the match data cannot be allocated.
 func (re *Regexp) NewMatcher() (m *Matcher) {
 	m = new(Matcher)
 	m.Init(re)
 	return
 }
 
+// NewMatcherErr is like NewMatcher, but returns ErrInvalidRegexp instead
+// of panicking if re is nil or has been freed, and ErrNoMemory if the
+// match data cannot be allocated.
+func (re *Regexp) NewMatcherErr() (*Matcher, error) {
+	m := new(Matcher)
+	if err := m.InitErr(re); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // Matcher creates a new matcher object, with the byte slice as subject.
 // It also starts a first match on subject. Test for success with Matches().
 func (re *Regexp) Matcher(subject []byte, flags uint32) (m *Matcher) {
@@ -735,20 +1079,49 @@ func (m *Matcher) ResetString(re *Regexp, subject string, flags uint32) bool {
 }
 
 // Init binds an existing Matcher object to the given Regexp.
+// It panics with ErrNoMemory if the match data cannot be allocated.
 func (m *Matcher) Init(re *Regexp) {
 	if re.ptr == nil {
 		panic("Matcher.Init: uninitialized")
 	}
-	m.matches = false
+	if err := m.init(re); err != nil {
+		panic(err)
+	}
+}
+
+func (m *Matcher) init(re *Regexp) error {
+	if alwaysZeroize {
+		m.Zeroize()
+	}
+	m.matches, m.partial, m.rc = false, false, 0
 	if m.re != nil && m.re.ptr != nil && m.re.ptr == re.ptr {
 		// Skip group count extraction if the matcher has
 		// already been initialized with the same regular
 		// expression.
-		return
+		return nil
+	}
+	mData, err := re.matchDataCreate()
+	if err != nil {
+		return err
 	}
 	m.re = re
 	m.groups = re.Groups()
-	m.mData = re.matchDataCreate()
+	// The match data is sized for the groups of the old Regexp.
+	if m.mData != nil {
+		m.mData.free()
+	}
+	m.mData = mData
+	return nil
+}
+
+// InitErr is like Init, but returns ErrInvalidRegexp instead of
+// panicking if re is nil or has been freed, and ErrNoMemory if the match
+// data cannot be allocated.
+func (m *Matcher) InitErr(re *Regexp) error {
+	if _, err := re.validRegexpPtr(); err != nil {
+		return err
+	}
+	return m.init(re)
 }
 
 var nullbyte = []byte{0}
@@ -760,11 +1133,7 @@ func (m *Matcher) Match(subject []byte, flags uint32) bool {
 	if m.re.ptr == nil {
 		panic("Matcher.Match: uninitialized")
 	}
-	rc := m.Exec(subject, flags)
-	m.rc = rc
-	m.matches = matched(rc)
-	m.partial = (rc == ERROR_PARTIAL)
-	return m.matches
+	return m.record(m.Exec(subject, flags))
 }
 
 // MatchString tries to match the specified subject string to
@@ -774,7 +1143,11 @@ func (m *Matcher) MatchString(subject string, flags uint32) bool {
 	if m.re.ptr == nil {
 		panic("Matcher.MatchString: uninitialized")
 	}
-	rc := m.ExecString(subject, flags)
+	return m.record(m.ExecString(subject, flags))
+}
+
+// record stores the outcome of a match with return code rc.
+func (m *Matcher) record(rc int) bool {
 	m.rc = rc
 	m.matches = matched(rc)
 	m.partial = (rc == ERROR_PARTIAL)
@@ -787,14 +1160,7 @@ func (m *Matcher) Exec(subject []byte, flags uint32) int {
 	if m.re.ptr == nil {
 		panic("Matcher.Exec: uninitialized")
 	}
-	length := len(subject)
-	m.subjects = ""
-	m.subjectb = subject
-	if length == 0 {
-		subject = nullbyte // make first character addressable
-	}
-	subjectptr := (*C.char)(unsafe.Pointer(&subject[0]))
-	return m.exec(subjectptr, length, flags)
+	return execAt(m, subject, 0, flags)
 }
 
 // ExecString tries to match the specified subject string to
@@ -803,30 +1169,49 @@ func (m *Matcher) ExecString(subject string, flags uint32) int {
 	if m.re.ptr == nil {
 		panic("Matcher.ExecString: uninitialized")
 	}
-	length := len(subject)
-	m.subjects = subject
-	m.subjectb = nil
-	if length == 0 {
-		subject = "\000" // make first character addressable
+	return execAt(m, subject, 0, flags)
+}
+
+func (m *Matcher) exec(subjectptr *C.char, length, offset int, flags uint32) int {
+	if m.callout != nil {
+		defer m.removeCallout(m.installCallout())
+	}
+	if m.jitPool != nil {
+		defer m.releaseJITStack(m.acquireJITStack())
+	}
+	if aj := m.re.autoJIT; aj != nil && !aj.done.Load() {
+		defer aj.count(m.re)()
+	}
+	if w := m.watchdog(); w != nil || !m.deadline.IsZero() {
+		return m.execBounded(subjectptr, length, offset, flags, w)
 	}
-	// The following is a non-portable kludge to avoid a copy
-	subjectptr := *(**C.char)(unsafe.Pointer(&subject))
-	return m.exec(subjectptr, length, flags)
+	return m.match(subjectptr, length, offset, flags)
 }
 
-func (m *Matcher) exec(subjectptr *C.char, length int, flags uint32) int {
+// match calls the C match function.
+func (m *Matcher) match(subjectptr *C.char, length, offset int, flags uint32) int {
+	mc := m.matchContext()
+	if m.re.longest {
+		defer runtime.KeepAlive(mc)
+		return m.matchLongest(subjectptr, length, offset, flags, mc)
+	}
 	rc := C.pcre2_match(m.re.ptr, C.PCRE2_SPTR(unsafe.Pointer(subjectptr)), C.PCRE2_SIZE(length),
-		0, C.uint32_t(flags), m.mData.md, nil)
+		C.PCRE2_SIZE(offset), C.uint32_t(flags), m.mData.md, mc.pointer())
+	runtime.KeepAlive(mc)
 	return int(rc)
 }
 
 // Free releases the underlying C resources
 func (m *Matcher) Free() {
+	if alwaysZeroize {
+		m.Zeroize()
+	}
 	if m.mData != nil {
-		runtime.SetFinalizer(m.mData, nil)
-		finalizeMatchData(m.mData)
+		m.mData.free()
 		m.mData = nil
 	}
+	m.private.Free()
+	m.private, m.privateBase = nil, nil
 }
 
 // HasError returns whether the matcher encountered an error condition.
@@ -839,12 +1224,17 @@ func (m *Matcher) GetError() error {
 	if matched(m.rc) {
 		return nil
 	}
-	rawbytes := C.MY_pcre2_get_error_message(C.int(m.rc))
-	msg := C.GoString((*C.char)(rawbytes))
-	C.free(unsafe.Pointer(rawbytes))
+	return matchError(m.rc)
+}
+
+// matchError returns the error for the return code of a failed match.
+func matchError(rc int) error {
+	if err, ok := packageErrors[rc]; ok {
+		return err
+	}
 	return &MatchError{
-		ErrorNum: m.rc,
-		Message:  msg,
+		ErrorNum: rc,
+		Message:  errorMessage(rc),
 	}
 }
 
@@ -873,13 +1263,111 @@ func (m *Matcher) Groups() int {
 	return m.groups
 }
 
+// ReturnCode returns the return code of the last match: one more than
+// the number of the highest capture group which was set, 0 if the
+// ovector was too small, or one of the ERROR_* codes, like
+// ERROR_NOMATCH and ERROR_PARTIAL. Before the first match, it is 0 as
+// well.
+func (m *Matcher) ReturnCode() int {
+	return m.rc
+}
+
+// CaptureCount returns the number of the highest capture group which
+// was set by the last match, so that groups up to it which are not
+// Present did not participate, while groups after it were not reached.
+// If the ovector was too small to hold all groups, it returns
+// Groups()+1. It returns 0 if there was no match, or a partial match,
+// which only sets group 0.
+func (m *Matcher) CaptureCount() int {
+	switch {
+	case !m.matches || m.partial:
+		return 0
+	case m.rc == 0:
+		return m.groups + 1
+	}
+	return m.rc - 1
+}
+
 // Present returns true if the numbered capture group is present in the last
 // match (performed by Matcher, MatcherString, Reset, ResetString,
 // Match, or MatchString).  Group numbers start at 1.  A capture group
 // can be present and match the empty string.
 func (m *Matcher) Present(group int) bool {
+	_, _, ok := m.mData.offsets(group)
+	return ok
+}
+
+// OffsetPair returns the start and end offsets of the numbered capture
+// group in the last match. ok is false if there was no match, the group
+// does not exist, or it did not participate in the match.
+func (m *Matcher) OffsetPair(group int) (start, end int64, ok bool) {
+	if !m.matches || group < 0 || group > m.groups {
+		return -1, -1, false
+	}
+	return m.mData.offsets(group)
+}
+
+// Mark returns the name of the last (*MARK:NAME), (*PRUNE:NAME) or
+// (*THEN:NAME) passed on the matching path of the last match, e.g. to
+// tell which branch of an alternation matched. PCRE2 also reports the
+// last name passed before a partial match or a failed match. ok is false
+// if no name was passed, no match was attempted, or the match failed
+// with an error.
+func (m *Matcher) Mark() (name string, ok bool) {
+	if !m.matches && m.rc != ERROR_NOMATCH {
+		return "", false
+	}
+	if m.re.literal || m.re.longest {
+		// Literal patterns have no marks, and the DFA matching of
+		// Longest does not record them.
+		return "", false
+	}
+	m.mData.ensureNotFreed()
+	mark := C.pcre2_get_mark(m.mData.md)
+	if mark == nil {
+		return "", false
+	}
+	return C.GoString((*C.char)(unsafe.Pointer(mark))), true
+}
+
+// OVector returns a copy of the offset vector of the last match: the
+// start and end offsets of group 0 and each capture group in turn, with
+// -1 for groups which did not participate. Unlike the other accessors,
+// it returns the offsets unchanged even if \K in an assertion set the
+// start of the match after its end. It returns nil if there was no
+// match.
+func (m *Matcher) OVector() []int64 {
+	if !m.matches {
+		return nil
+	}
+	m.mData.ensureNotFreed()
+	ovector := make([]int64, len(m.mData.ovector))
+	for i, v := range m.mData.ovector {
+		if v == UNSET {
+			ovector[i] = -1
+		} else {
+			ovector[i] = int64(v)
+		}
+	}
+	return ovector
+}
+
+// StartChar returns the offset in the subject at which the last match,
+// or partial match, started, or -1 if there is none. It differs from
+// the start of the match if \K set the start later, so that loops over
+// successive matches of such patterns can tell where scanning began.
+func (m *Matcher) StartChar() int {
+	if !m.matches {
+		return -1
+	}
+	if m.re.literal || m.re.longest {
+		// \K is not supported by either, so the match starts at the
+		// start char.
+		start, _, _ := m.mData.offsets(0)
+		return int(start)
+	}
 	m.mData.ensureNotFreed()
-	return m.mData.ovector[2*group] >= 0 && m.mData.ovector[2*group] != UNSET
+	return int(C.pcre2_get_startchar(m.mData.md))
 }
 
 // Group returns the numbered capture group of the last match (performed by
@@ -888,16 +1376,33 @@ func (m *Matcher) Present(group int) bool {
 // the first actual capture group is numbered 1.  Capture groups which
 // are not present return a nil slice.
 func (m *Matcher) Group(group int) []byte {
-	m.mData.ensureNotFreed()
-	start := m.mData.ovector[2*group]
-	end := m.mData.ovector[2*group+1]
-	if start >= 0 {
-		if m.subjectb != nil {
-			return m.subjectb[start:end]
-		}
-		return []byte(m.subjects[start:end])
+	start, end, ok := m.mData.offsets(group)
+	if !ok {
+		return nil
 	}
-	return nil
+	if m.subjectb != nil {
+		return m.subjectb[start:end]
+	}
+	return []byte(m.subjects[start:end])
+}
+
+// AppendGroup appends the numbered capture group of the last match to
+// dst and returns the extended buffer, like the strconv.Append
+// functions. Unlike Group, it does not allocate for string subjects, so
+// hot paths can extract captures into a reused buffer. dst is returned
+// unchanged if the group is not present.
+func (m *Matcher) AppendGroup(dst []byte, group int) []byte {
+	if !m.matches {
+		return dst
+	}
+	start, end, ok := m.mData.offsets(group)
+	if !ok {
+		return dst
+	}
+	if m.subjectb != nil {
+		return append(dst, m.subjectb[start:end]...)
+	}
+	return append(dst, m.subjects[start:end]...)
 }
 
 // Extract returns a slice of byte slices for a single match.
@@ -911,10 +1416,11 @@ func (m *Matcher) Extract() [][]byte {
 	m.mData.ensureNotFreed()
 	extract := make([][]byte, m.groups+1)
 	extract[0] = m.subjectb
+	if m.subjectb == nil {
+		extract[0] = []byte(m.subjects)
+	}
 	for i := 1; i <= m.groups; i++ {
-		x0 := m.mData.ovector[2*i]
-		x1 := m.mData.ovector[2*i+1]
-		extract[i] = m.subjectb[x0:x1]
+		extract[i] = m.Group(i)
 	}
 	return extract
 }
@@ -930,10 +1436,11 @@ func (m *Matcher) ExtractString() []string {
 	m.mData.ensureNotFreed()
 	extract := make([]string, m.groups+1)
 	extract[0] = m.subjects
+	if m.subjectb != nil {
+		extract[0] = string(m.subjectb)
+	}
 	for i := 1; i <= m.groups; i++ {
-		x0 := m.mData.ovector[2*i]
-		x1 := m.mData.ovector[2*i+1]
-		extract[i] = m.subjects[x0:x1]
+		extract[i] = m.GroupString(i)
 	}
 	return extract
 }
@@ -944,13 +1451,20 @@ func (m *Matcher) ExtractString() []string {
 // the whole pattern; the first actual capture group is numbered 1.
 // Capture groups which are not present return a nil slice.
 func (m *Matcher) GroupIndices(group int) []int {
-	m.mData.ensureNotFreed()
-	start := m.mData.ovector[2*group]
-	end := m.mData.ovector[2*group+1]
-	if start >= 0 {
-		return []int{int(start), int(end)}
+	start, end, ok := m.mData.offsets(group)
+	if !ok {
+		return nil
 	}
-	return nil
+	return []int{int(start), int(end)}
+}
+
+// GroupIndices64 is like GroupIndices, but returns the offsets as int64.
+func (m *Matcher) GroupIndices64(group int) []int64 {
+	start, end, ok := m.mData.offsets(group)
+	if !ok {
+		return nil
+	}
+	return []int64{start, end}
 }
 
 // GroupString returns the numbered capture group as a string.  Group 0
@@ -958,16 +1472,14 @@ func (m *Matcher) GroupIndices(group int) []int {
 // actual capture group is numbered 1.  Capture groups which are not
 // present return an empty string.
 func (m *Matcher) GroupString(group int) string {
-	m.mData.ensureNotFreed()
-	start := m.mData.ovector[2*group]
-	end := m.mData.ovector[2*group+1]
-	if start >= 0 {
-		if m.subjectb != nil {
-			return string(m.subjectb[start:end])
-		}
-		return m.subjects[start:end]
+	start, end, ok := m.mData.offsets(group)
+	if !ok {
+		return ""
 	}
-	return ""
+	if m.subjectb != nil {
+		return string(m.subjectb[start:end])
+	}
+	return m.subjects[start:end]
 }
 
 // Index returns the start and end of the first match, if a previous
@@ -977,22 +1489,34 @@ func (m *Matcher) Index() (loc []int) {
 	if !m.matches {
 		return nil
 	}
-	m.mData.ensureNotFreed()
-	loc = []int{int(m.mData.ovector[0]), int(m.mData.ovector[1])}
-	return
+	start, end, _ := m.mData.offsets(0)
+	return []int{int(start), int(end)}
+}
+
+// Index64 is like Index, but returns the offsets as int64.
+func (m *Matcher) Index64() []int64 {
+	if !m.matches {
+		return nil
+	}
+	return m.GroupIndices64(0)
 }
 
 // name2index converts a group name to its group index number.
 func (m *Matcher) name2index(name string) (int, error) {
-	if m.re.ptr == nil {
+	return m.re.name2index(name)
+}
+
+// name2index converts a group name to its group index number.
+func (re *Regexp) name2index(name string) (int, error) {
+	if re.ptr == nil {
 		return 0, fmt.Errorf("Matcher.Named: uninitialized")
 	}
 	name1 := C.CString(name)
 	defer C.free(unsafe.Pointer(name1))
 	group := int(C.pcre2_substring_number_from_name(
-		m.re.ptr, C.PCRE2_SPTR(unsafe.Pointer(name1))))
+		re.ptr, C.PCRE2_SPTR(unsafe.Pointer(name1))))
 	if group < 0 {
-		return group, fmt.Errorf("Matcher.Named: unknown name: " + name)
+		return group, fmt.Errorf("Matcher.Named: unknown name: %s", name)
 	}
 	return group, nil
 }
@@ -1019,6 +1543,20 @@ func (m *Matcher) NamedString(group string) (string, error) {
 	return m.GroupString(groupNum), nil
 }
 
+// NamedIndices returns the start and end offsets of the named capture
+// group in the last match, or nil if the group is not present.
+// If the name does not refer to a group then error is non-nil.
+func (m *Matcher) NamedIndices(group string) ([]int, error) {
+	groupNum, err := m.name2index(group)
+	if err != nil {
+		return nil, err
+	}
+	if !m.matches {
+		return nil, nil
+	}
+	return m.GroupIndices(groupNum), nil
+}
+
 // NamedPresent returns true if the named capture group is present.
 // If the name does not refer to a group then error is non-nil.
 func (m *Matcher) NamedPresent(group string) (bool, error) {
@@ -1032,17 +1570,15 @@ func (m *Matcher) NamedPresent(group string) (bool, error) {
 // FindIndex returns the start and end of the first match,
 // or nil if no match.  loc[0] is the start and loc[1] is the end.
 func (re *Regexp) FindIndex(bytes []byte, flags uint32) (loc []int) {
-	m := re.Matcher(bytes, flags)
-	defer m.Free()
-	if m.Matches() {
-		loc = []int{int(m.mData.ovector[0]), int(m.mData.ovector[1])}
-		return
-	}
-	return nil
+	return findIndex(re, bytes, flags)
 }
 
 // ReplaceAll returns a copy of a byte slice
 // where all pattern matches are replaced by repl.
+// After each match, the search goes on in the rest of the subject as a
+// new subject, so anchors and lookbehind assertions do not see the text
+// before it. Patterns which can match the empty string, or use \K, may
+// make it loop forever; use ReplaceAllSafe for them.
 func (re *Regexp) ReplaceAll(bytes, repl []byte, flags uint32) []byte {
 	m := re.Matcher(bytes, flags)
 	defer m.Free()
@@ -1060,14 +1596,88 @@ func (re *Regexp) ReplaceAllString(in, repl string, flags uint32) string {
 	return string(re.ReplaceAll([]byte(in), []byte(repl), flags))
 }
 
+// ReplaceAllSafe is like ReplaceAll, but with the matches of Iterate:
+// the whole subject is searched, so that lookbehind assertions and
+// anchors see the text before each match, and after an empty match the
+// search advances by one character, or CRLF pair, as pcre2demo does.
+// It therefore terminates for all patterns, and e.g. `^a` only replaces
+// the first "a" of "aaa". repl is inserted literally; see Substitute for
+// replacements referring to capture groups.
+func (re *Regexp) ReplaceAllSafe(bytes, repl []byte, flags uint32) []byte {
+	return replaceAll(re, bytes, flags, func(r []byte, _ *Matcher) []byte {
+		return append(r, repl...)
+	})
+}
+
+// ReplaceAllSafeString is equivalent to ReplaceAllSafe with string
+// return type.
+func (re *Regexp) ReplaceAllSafeString(in, repl string, flags uint32) string {
+	return string(replaceAll(re, in, flags, func(r []byte, _ *Matcher) []byte {
+		return append(r, repl...)
+	}))
+}
+
+// ReplaceAllSubmatchFunc is like ReplaceAllSafe, but the replacement of each
+// match is returned by repl, which receives the match and its capture
+// groups, so that it can be computed from them, e.g. to reformat dates.
+// groups[0] is the match, and groups which did not participate are nil.
+// The slices refer to src, and are only valid during the call.
+func (re *Regexp) ReplaceAllSubmatchFunc(src []byte, repl func(groups [][]byte) []byte, flags uint32) []byte {
+	var groups [][]byte
+	return replaceAll(re, src, flags, func(r []byte, m *Matcher) []byte {
+		groups = groups[:0]
+		for i := 0; i <= m.groups; i++ {
+			groups = append(groups, m.Group(i))
+		}
+		return append(r, repl(groups)...)
+	})
+}
+
+// ReplaceAllStringSubmatchFunc is like ReplaceAllSubmatchFunc, but for
+// strings. Groups which did not participate are empty.
+func (re *Regexp) ReplaceAllStringSubmatchFunc(src string, repl func(groups []string) string, flags uint32) string {
+	var groups []string
+	return string(replaceAll(re, src, flags, func(r []byte, m *Matcher) []byte {
+		groups = groups[:0]
+		for i := 0; i <= m.groups; i++ {
+			groups = append(groups, m.GroupString(i))
+		}
+		return append(r, repl(groups)...)
+	}))
+}
+
+// replaceAll returns a copy of the subject in which every match is
+// replaced by what appendRepl appends for it.
+func replaceAll[S subject](re *Regexp, subject S, flags uint32, appendRepl func(r []byte, m *Matcher) []byte) []byte {
+	r := []byte{}
+	last := 0
+	iterate(re, subject, flags, func(m *Matcher) bool {
+		start, end, _ := m.mData.offsets(0)
+		if start > end {
+			// \K in an assertion set the start after the end, which
+			// cannot be replaced. The match is skipped, keeping its
+			// text, and the search goes on.
+			return true
+		}
+		// \K in a lookbehind can set the start before the end of the
+		// previous match.
+		start = max(start, int64(last))
+		r = appendRepl(append(r, subject[last:start]...), m)
+		last = int(end)
+		return true
+	})
+	return append(r, subject[last:]...)
+}
+
 // CompileError holds details about a compilation error,
 // as returned by the Compile function. The offset is
 // the byte position in the pattern string at which the
 // error was detected.
 type CompileError struct {
-	Pattern string // The failed pattern
-	Message string // The error message
-	Offset  int    // Byte position of error
+	Pattern  string // The failed pattern
+	Message  string // The error message
+	Offset   int    // Byte position of error
+	ErrorNum int    // The PCRE2 error number, or zero
 }
 
 // Error converts a compile error to a string
@@ -0,0 +1,351 @@
+// Package pcre mirrors the API of github.com/glenn-brown/golang-pkg-pcre,
+// a binding of the old PCRE library, on top of package pcre2. Programs
+// using that package can move to PCRE2 by changing the import path:
+//
+//	import "github.com/Jemmic/go-pcre2/pcre"
+//
+// The flag constants have their PCRE1 names and values and are
+// translated to PCRE2. Newline and \R conventions can only be set when
+// compiling, as in PCRE2; at match time they are ignored. Patterns are
+// compiled by PCRE2, whose syntax differs slightly from PCRE1, see the
+// pcre2 documentation.
+package pcre
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/Jemmic/go-pcre2"
+)
+
+// Flags for Compile and Match functions.
+const (
+	ANCHORED          = 0x00000010
+	BSR_ANYCRLF       = 0x00800000
+	BSR_UNICODE       = 0x01000000
+	NEWLINE_ANY       = 0x00400000
+	NEWLINE_ANYCRLF   = 0x00500000
+	NEWLINE_CR        = 0x00100000
+	NEWLINE_CRLF      = 0x00300000
+	NEWLINE_LF        = 0x00200000
+	NO_START_OPTIMIZE = 0x04000000
+	NO_UTF8_CHECK     = 0x00002000
+)
+
+// Flags for Compile.
+const (
+	CASELESS          = 0x00000001
+	DOLLAR_ENDONLY    = 0x00000020
+	DOTALL            = 0x00000004
+	DUPNAMES          = 0x00080000
+	EXTENDED          = 0x00000008
+	EXTRA             = 0x00000040 // always in effect in PCRE2
+	FIRSTLINE         = 0x00040000
+	JAVASCRIPT_COMPAT = 0x02000000
+	MULTILINE         = 0x00000002
+	NO_AUTO_CAPTURE   = 0x00001000
+	UNGREEDY          = 0x00000200
+	UTF8              = 0x00000800
+	UCP               = 0x20000000
+)
+
+// Flags for Match functions.
+const (
+	NOTBOL           = 0x00000080
+	NOTEOL           = 0x00000100
+	NOTEMPTY         = 0x00000400
+	NOTEMPTY_ATSTART = 0x10000000
+	PARTIAL_HARD     = 0x08000000
+	PARTIAL_SOFT     = 0x00008000
+)
+
+const (
+	newlineMask = 0x00700000
+	bsrMask     = BSR_ANYCRLF | BSR_UNICODE
+)
+
+// flagMap maps PCRE1 option bits to PCRE2 option bits.
+var flagMap = []struct {
+	pcre1 int
+	pcre2 uint32
+}{
+	{ANCHORED, pcre2.ANCHORED},
+	{NO_START_OPTIMIZE, pcre2.NO_START_OPTIMIZE},
+	{NO_UTF8_CHECK, pcre2.NO_UTF_CHECK},
+	{CASELESS, pcre2.CASELESS},
+	{DOLLAR_ENDONLY, pcre2.DOLLAR_ENDONLY},
+	{DOTALL, pcre2.DOTALL},
+	{DUPNAMES, pcre2.DUPNAMES},
+	{EXTENDED, pcre2.EXTENDED},
+	{FIRSTLINE, pcre2.FIRSTLINE},
+	{JAVASCRIPT_COMPAT, pcre2.ALT_BSUX | pcre2.ALLOW_EMPTY_CLASS | pcre2.MATCH_UNSET_BACKREF},
+	{MULTILINE, pcre2.MULTILINE},
+	{NO_AUTO_CAPTURE, pcre2.NO_AUTO_CAPTURE},
+	{UNGREEDY, pcre2.UNGREEDY},
+	{UTF8, pcre2.UTF},
+	{UCP, pcre2.UCP},
+	{NOTBOL, pcre2.NOTBOL},
+	{NOTEOL, pcre2.NOTEOL},
+	{NOTEMPTY, pcre2.NOTEMPTY},
+	{NOTEMPTY_ATSTART, pcre2.NOTEMPTY_ATSTART},
+	{PARTIAL_HARD, pcre2.PARTIAL_HARD},
+	{PARTIAL_SOFT, pcre2.PARTIAL_SOFT},
+}
+
+// translate converts PCRE1 option bits to PCRE2 option bits. The
+// newline and \R conventions are dropped.
+func translate(flags int) uint32 {
+	var result uint32
+	for _, f := range flagMap {
+		if flags&f.pcre1 != 0 {
+			result |= f.pcre2
+		}
+	}
+	return result
+}
+
+// newlines maps the PCRE1 newline conventions to PCRE2.
+var newlines = map[int]uint32{
+	NEWLINE_CR:      pcre2.NEWLINE_CR,
+	NEWLINE_LF:      pcre2.NEWLINE_LF,
+	NEWLINE_CRLF:    pcre2.NEWLINE_CRLF,
+	NEWLINE_ANY:     pcre2.NEWLINE_ANY,
+	NEWLINE_ANYCRLF: pcre2.NEWLINE_ANYCRLF,
+}
+
+// compileContext returns a compile context with the newline and \R
+// conventions of flags, or nil for the defaults.
+func compileContext(flags int) (*pcre2.CompileContext, error) {
+	newline, bsr := flags&newlineMask, flags&bsrMask
+	if newline == 0 && bsr == 0 {
+		return nil, nil
+	}
+	cc := pcre2.NewCompileContext()
+	if nl, ok := newlines[newline]; ok {
+		if err := cc.SetNewline(nl); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	switch bsr {
+	case BSR_ANYCRLF:
+		err = cc.SetBSR(pcre2.BSR_ANYCRLF)
+	case BSR_UNICODE:
+		err = cc.SetBSR(pcre2.BSR_UNICODE)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cc, nil
+}
+
+// Regexp holds a reference to a compiled regular expression.
+// Use Compile or MustCompile to create such objects.
+type Regexp struct {
+	re *pcre2.Regexp
+}
+
+// CompileError holds details about a compilation error,
+// as returned by the Compile function.
+type CompileError struct {
+	Pattern string // The failed pattern
+	Message string // The error message
+	Offset  int    // Byte position of error
+}
+
+// Error converts a compile error to a string.
+func (e *CompileError) Error() string {
+	return e.Pattern + " (" + strconv.Itoa(e.Offset) + "): " + e.Message
+}
+
+// Compile the pattern and return a compiled regexp.
+// If compilation fails, the second return value holds a *CompileError.
+func Compile(pattern string, flags int) (Regexp, *CompileError) {
+	cc, err := compileContext(flags)
+	if err != nil {
+		return Regexp{}, &CompileError{Pattern: pattern, Message: err.Error()}
+	}
+	re, err := pcre2.CompileWithContext(pattern, translate(flags), cc)
+	if err != nil {
+		ce := &CompileError{Pattern: pattern, Message: err.Error()}
+		var e *pcre2.CompileError
+		if errors.As(err, &e) {
+			ce.Message, ce.Offset = e.Message, e.Offset
+		}
+		return Regexp{}, ce
+	}
+	return Regexp{re}, nil
+}
+
+// MustCompile compiles the pattern. If compilation fails, panic.
+func MustCompile(pattern string, flag int) Regexp {
+	re, err := Compile(pattern, flag)
+	if err != nil {
+		panic(err)
+	}
+	return re
+}
+
+// Groups returns the number of capture groups in the compiled pattern.
+func (re Regexp) Groups() int {
+	if re.re == nil {
+		panic("Regexp.Groups: uninitialized")
+	}
+	return re.re.Groups()
+}
+
+// Matcher objects provide a place for storing match results.
+// They can be created by the Matcher and MatcherString functions,
+// or they can be initialized with Reset or ResetString.
+type Matcher struct {
+	m *pcre2.Matcher
+}
+
+// Matcher returns a new matcher object, with the byte slice as subject.
+func (re Regexp) Matcher(subject []byte, flags int) *Matcher {
+	m := new(Matcher)
+	m.Reset(re, subject, flags)
+	return m
+}
+
+// MatcherString returns a new matcher object, with the specified
+// subject string.
+func (re Regexp) MatcherString(subject string, flags int) *Matcher {
+	m := new(Matcher)
+	m.ResetString(re, subject, flags)
+	return m
+}
+
+// Reset switches the matcher object to the specified pattern and
+// subject.
+func (m *Matcher) Reset(re Regexp, subject []byte, flags int) {
+	if re.re == nil {
+		panic("Matcher.Reset: uninitialized")
+	}
+	m.init(re)
+	m.Match(subject, flags)
+}
+
+// ResetString switches the matcher object to the specified pattern and
+// subject string.
+func (m *Matcher) ResetString(re Regexp, subject string, flags int) {
+	if re.re == nil {
+		panic("Matcher.ResetString: uninitialized")
+	}
+	m.init(re)
+	m.MatchString(subject, flags)
+}
+
+func (m *Matcher) init(re Regexp) {
+	if m.m == nil {
+		m.m = re.re.NewMatcher()
+	} else {
+		m.m.Init(re.re)
+	}
+}
+
+// Match tries to match the specified byte slice to the current pattern.
+// It returns true if the match succeeds.
+func (m *Matcher) Match(subject []byte, flags int) bool {
+	return m.m.Match(subject, translate(flags))
+}
+
+// MatchString tries to match the specified subject string to the
+// current pattern. It returns true if the match succeeds.
+func (m *Matcher) MatchString(subject string, flags int) bool {
+	return m.m.MatchString(subject, translate(flags))
+}
+
+// Matches returns true if a previous call to Matcher, MatcherString,
+// Reset, ResetString, Match or MatchString succeeded.
+func (m *Matcher) Matches() bool {
+	return m.m.Matches()
+}
+
+// Groups returns the number of groups in the current pattern.
+func (m *Matcher) Groups() int {
+	return m.m.Groups()
+}
+
+// Present returns true if the numbered capture group is present in the
+// last match (performed by Matcher, MatcherString, Reset, ResetString,
+// Match, or MatchString). Group numbers start at 1. A capture group can
+// be present and match the empty string.
+func (m *Matcher) Present(group int) bool {
+	return m.m.Present(group)
+}
+
+// Group returns the numbered capture group of the last match (performed
+// by Matcher, MatcherString, Reset, ResetString, Match, or MatchString).
+// Group 0 is the part of the subject which matches the whole pattern;
+// the first actual capture group is numbered 1. Capture groups which
+// are not present return a nil slice.
+func (m *Matcher) Group(group int) []byte {
+	return m.m.Group(group)
+}
+
+// Extract returns a slice of byte slices for a single match.
+// The first byte slice contains the complete match.
+// Subsequent byte slices contain the captured groups.
+// If there was no match then nil is returned.
+func (m *Matcher) Extract() [][]byte {
+	return m.m.Extract()
+}
+
+// GroupString returns the numbered capture group as a string. Group 0
+// is the part of the subject which matches the whole pattern; the first
+// actual capture group is numbered 1. Capture groups which are not
+// present return an empty string.
+func (m *Matcher) GroupString(group int) string {
+	return m.m.GroupString(group)
+}
+
+// Named returns the value of the named capture group. This is a nil
+// slice if the capture group is not present. Panics if the name does
+// not refer to a group.
+func (m *Matcher) Named(group string) []byte {
+	b, err := m.m.Named(group)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// NamedString returns the value of the named capture group, or an empty
+// string if the capture group is not present. Panics if the name does
+// not refer to a group.
+func (m *Matcher) NamedString(group string) string {
+	s, err := m.m.NamedString(group)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// NamedPresent returns true if the named capture group is present.
+// Panics if the name does not refer to a group.
+func (m *Matcher) NamedPresent(group string) bool {
+	present, err := m.m.NamedPresent(group)
+	if err != nil {
+		panic(err)
+	}
+	return present
+}
+
+// FindIndex returns the start and end of the first match, or nil if no
+// match. loc[0] is the start and loc[1] is the end.
+func (re Regexp) FindIndex(bytes []byte, flags int) []int {
+	if re.re == nil {
+		panic("Regexp.FindIndex: uninitialized")
+	}
+	return re.re.FindIndex(bytes, translate(flags))
+}
+
+// ReplaceAll returns a copy of a byte slice where all pattern matches
+// are replaced by repl.
+func (re Regexp) ReplaceAll(bytes, repl []byte, flags int) []byte {
+	if re.re == nil {
+		panic("Regexp.ReplaceAll: uninitialized")
+	}
+	return re.re.ReplaceAll(bytes, repl, translate(flags))
+}
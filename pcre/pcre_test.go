@@ -0,0 +1,57 @@
+package pcre
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatcher(t *testing.T) {
+	re := MustCompile(`(?<user>\w+)@(\w+)(x)?`, CASELESS)
+	assert.Equal(t, 3, re.Groups())
+	m := re.MatcherString("mail USER@Example", 0)
+	assert.True(t, m.Matches())
+	assert.Equal(t, "USER@Example", m.GroupString(0))
+	assert.Equal(t, "Example", m.GroupString(2))
+	assert.False(t, m.Present(3))
+	assert.Equal(t, "USER", m.NamedString("user"))
+	assert.True(t, m.NamedPresent("user"))
+	assert.Equal(t, []byte("USER"), m.Named("user"))
+	assert.Panics(t, func() { m.Named("missing") })
+	assert.Len(t, m.Extract(), 4)
+
+	m.Reset(MustCompile(`b`, 0), []byte("abc"), 0)
+	assert.True(t, m.Matches())
+	assert.Equal(t, []byte("b"), m.Group(0))
+	assert.False(t, m.MatchString("xyz", 0))
+}
+
+func TestFlags(t *testing.T) {
+	assert.Equal(t, []int{2, 3}, MustCompile(`^b`, MULTILINE).FindIndex([]byte("a\nb"), 0))
+	assert.Nil(t, MustCompile(`^a`, 0).FindIndex([]byte("a"), NOTBOL))
+	assert.True(t, MustCompile(`\w`, UTF8|UCP).MatcherString("é", 0).Matches())
+
+	re := MustCompile(`^b`, MULTILINE|NEWLINE_CR)
+	assert.Nil(t, re.FindIndex([]byte("a\nb"), 0))
+	assert.Equal(t, []int{2, 3}, re.FindIndex([]byte("a\rb"), 0))
+
+	re = MustCompile(`a\R`, BSR_ANYCRLF)
+	assert.Nil(t, re.FindIndex([]byte("a\x0b"), 0))
+
+	m := MustCompile(`abc`, 0).MatcherString("ab", PARTIAL_SOFT)
+	assert.True(t, m.Matches())
+}
+
+func TestCompileError(t *testing.T) {
+	_, err := Compile(`(abc`, 0)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, 4, err.Offset)
+		assert.Equal(t, "(abc (4): missing closing parenthesis", err.Error())
+	}
+	assert.Panics(t, func() { MustCompile(`(`, 0) })
+}
+
+func TestReplaceAll(t *testing.T) {
+	re := MustCompile(`o+`, 0)
+	assert.Equal(t, []byte("f0 b0r"), re.ReplaceAll([]byte("foo boor"), []byte("0"), 0))
+}
@@ -0,0 +1,262 @@
+// Package pcre216 provides access to the 16-bit code unit library of
+// PCRE2, libpcre2-16. Patterns and subjects are sequences of uint16, so
+// UTF-16 data, e.g. from Windows APIs or JavaScript engines, can be
+// matched without transcoding it to UTF-8, and all offsets are in code
+// units.
+//
+// The API follows package pcre2, whose flag and error constants apply
+// to this package as well, e.g. pcre2.CASELESS or pcre2.ERROR_NOMATCH.
+// Use pcre2.UTF to treat patterns and subjects as UTF-16.
+package pcre216
+
+/*
+#cgo pkg-config: libpcre2-16
+#define PCRE2_CODE_UNIT_WIDTH 16
+
+#include <stdint.h>
+#include <pcre2.h>
+*/
+import "C"
+
+import (
+	"runtime"
+	"sync"
+	"unicode/utf16"
+	"unsafe"
+
+	"github.com/Jemmic/go-pcre2"
+)
+
+const errorMessageBufLen = 256
+
+// errorMessage returns the PCRE2 message text for an error code.
+func errorMessage(code int) string {
+	var buf [errorMessageBufLen]uint16
+	n := C.pcre2_get_error_message(C.int(code), (*C.PCRE2_UCHAR)(unsafe.Pointer(&buf[0])), errorMessageBufLen)
+	if n < 0 {
+		return ""
+	}
+	return string(utf16.Decode(buf[:n]))
+}
+
+// Regexp holds a compiled 16-bit pattern.
+type Regexp struct {
+	Pattern []uint16
+	ptr     *C.pcre2_code
+	cleanup sync.Once
+}
+
+// Compile compiles a pattern given as a Go string, which is encoded as
+// UTF-16 first. If compilation fails, the error is a *pcre2.CompileError
+// whose Offset counts UTF-16 code units.
+func Compile(pattern string, flags uint32) (*Regexp, error) {
+	return CompileUTF16(EncodeUTF16Subject(pattern), flags)
+}
+
+// CompileUTF16 compiles a pattern of 16-bit code units.
+func CompileUTF16(pattern []uint16, flags uint32) (*Regexp, error) {
+	var errnum C.int
+	var erroffset C.PCRE2_SIZE
+	ptr := C.pcre2_compile(
+		sptr(pattern),
+		C.PCRE2_SIZE(len(pattern)),
+		C.uint32_t(flags),
+		&errnum,
+		&erroffset,
+		nil,
+	)
+	if ptr == nil {
+		return nil, &pcre2.CompileError{
+			Pattern:  string(utf16.Decode(pattern)),
+			Message:  errorMessage(int(errnum)),
+			Offset:   int(erroffset),
+			ErrorNum: int(errnum),
+		}
+	}
+	re := &Regexp{Pattern: pattern, ptr: ptr}
+	runtime.SetFinalizer(re, finalizeRegex)
+	return re, nil
+}
+
+// MustCompile compiles the pattern. If compilation fails, panic.
+func MustCompile(pattern string, flags uint32) *Regexp {
+	re, err := Compile(pattern, flags)
+	if err != nil {
+		panic(err)
+	}
+	return re
+}
+
+// nullunit makes the first code unit of empty slices addressable.
+var nullunit = []uint16{0}
+
+// sptr returns a pointer to the first code unit of s.
+func sptr(s []uint16) C.PCRE2_SPTR {
+	if len(s) == 0 {
+		return C.PCRE2_SPTR(unsafe.Pointer(&nullunit[0]))
+	}
+	return C.PCRE2_SPTR(unsafe.Pointer(&s[0]))
+}
+
+func finalizeRegex(re *Regexp) {
+	if re != nil && re.ptr != nil {
+		re.cleanup.Do(func() {
+			C.pcre2_code_free(re.ptr)
+			re.ptr = nil
+		})
+	}
+}
+
+// Free releases the underlying C resources.
+func (re *Regexp) Free() error {
+	if re == nil || re.ptr == nil {
+		return nil
+	}
+	finalizeRegex(re)
+	runtime.SetFinalizer(re, nil)
+	return nil
+}
+
+// Groups returns the number of capture groups in the compiled pattern.
+func (re *Regexp) Groups() int {
+	if re.ptr == nil {
+		panic("Regexp.Groups: uninitialized")
+	}
+	var count C.uint32_t
+	C.pcre2_pattern_info(re.ptr, pcre2.INFO_CAPTURECOUNT, unsafe.Pointer(&count))
+	return int(count)
+}
+
+// FindIndex returns the start and end of the first match in subject,
+// or nil if there is none.
+func (re *Regexp) FindIndex(subject []uint16, flags uint32) []int {
+	m := re.NewMatcher()
+	defer m.Free()
+	if !m.Match(subject, flags) {
+		return nil
+	}
+	return m.Index()
+}
+
+// Matcher holds the results of matching a 16-bit subject.
+type Matcher struct {
+	re      *Regexp
+	md      *C.pcre2_match_data
+	ovector []C.PCRE2_SIZE
+	cleanup sync.Once
+	subject []uint16
+	rc      int
+}
+
+// NewMatcher creates a new matcher object for the given Regexp.
+func (re *Regexp) NewMatcher() *Matcher {
+	if re.ptr == nil {
+		panic("Regexp.NewMatcher: uninitialized")
+	}
+	m := &Matcher{re: re}
+	m.md = C.pcre2_match_data_create_from_pattern(re.ptr, nil)
+	m.ovector = unsafe.Slice(C.pcre2_get_ovector_pointer(m.md), 2*(re.Groups()+1))
+	runtime.SetFinalizer(m, finalizeMatcher)
+	return m
+}
+
+// Matcher creates a new matcher object and matches the subject.
+func (re *Regexp) Matcher(subject []uint16, flags uint32) *Matcher {
+	m := re.NewMatcher()
+	m.Match(subject, flags)
+	return m
+}
+
+func finalizeMatcher(m *Matcher) {
+	if m != nil && m.md != nil {
+		m.cleanup.Do(func() {
+			C.pcre2_match_data_free(m.md)
+			m.md = nil
+			m.ovector = nil
+		})
+	}
+}
+
+// Free releases the underlying C resources.
+func (m *Matcher) Free() {
+	if m == nil || m.md == nil {
+		return
+	}
+	finalizeMatcher(m)
+	runtime.SetFinalizer(m, nil)
+}
+
+// Exec matches the subject and returns the raw PCRE2 return code.
+func (m *Matcher) Exec(subject []uint16, flags uint32) int {
+	if m.md == nil || m.re.ptr == nil {
+		panic("Matcher.Exec: uninitialized")
+	}
+	m.subject = subject
+	rc := C.pcre2_match(m.re.ptr, sptr(subject), C.PCRE2_SIZE(len(subject)),
+		0, C.uint32_t(flags), m.md, nil)
+	m.rc = int(rc)
+	return m.rc
+}
+
+// Match matches the subject and reports whether it matched.
+func (m *Matcher) Match(subject []uint16, flags uint32) bool {
+	m.Exec(subject, flags)
+	return m.Matches()
+}
+
+// Matches returns true if a previous call to Match or Exec matched,
+// possibly partially.
+func (m *Matcher) Matches() bool {
+	return m.rc >= 0 || m.rc == pcre2.ERROR_PARTIAL
+}
+
+// Partial returns true if a previous call to Match or Exec resulted in
+// a partial match.
+func (m *Matcher) Partial() bool {
+	return m.rc == pcre2.ERROR_PARTIAL
+}
+
+// GetError returns nil if the last match succeeded or did not match,
+// and a *pcre2.MatchError otherwise.
+func (m *Matcher) GetError() error {
+	if m.Matches() || m.rc == pcre2.ERROR_NOMATCH {
+		return nil
+	}
+	return &pcre2.MatchError{ErrorNum: m.rc, Message: errorMessage(m.rc)}
+}
+
+// Present returns true if the numbered capture group is present in the
+// last match.
+func (m *Matcher) Present(group int) bool {
+	return m.Matches() && group <= m.re.Groups() && m.ovector[2*group] != pcre2.UNSET
+}
+
+// GroupIndices returns the start and end offsets, in code units, of the
+// capture group, or nil if it is not present.
+func (m *Matcher) GroupIndices(group int) []int {
+	if !m.Present(group) {
+		return nil
+	}
+	return []int{int(m.ovector[2*group]), int(m.ovector[2*group+1])}
+}
+
+// Group returns the code units of the capture group, or nil if it is not
+// present. The result refers to the subject.
+func (m *Matcher) Group(group int) []uint16 {
+	loc := m.GroupIndices(group)
+	if loc == nil {
+		return nil
+	}
+	return m.subject[loc[0]:loc[1]]
+}
+
+// GroupString returns the capture group decoded from UTF-16 into a Go
+// string.
+func (m *Matcher) GroupString(group int) string {
+	return string(utf16.Decode(m.Group(group)))
+}
+
+// Index returns the start and end of the match, or nil.
+func (m *Matcher) Index() []int {
+	return m.GroupIndices(0)
+}
@@ -0,0 +1,62 @@
+package pcre216
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jemmic/go-pcre2"
+)
+
+func TestMatch(t *testing.T) {
+	re := MustCompile(`(\w+)@(\w+)`, 0)
+	defer re.Free()
+	assert.Equal(t, 2, re.Groups())
+
+	m := re.Matcher(EncodeUTF16Subject("mail: user@example"), 0)
+	defer m.Free()
+	assert.True(t, m.Matches())
+	assert.Equal(t, []int{6, 18}, m.Index())
+	assert.Equal(t, "example", m.GroupString(2))
+	assert.Equal(t, EncodeUTF16Subject("user"), m.Group(1))
+	assert.NoError(t, m.GetError())
+
+	assert.False(t, m.Match(EncodeUTF16Subject("nothing"), 0))
+	assert.Nil(t, m.Group(1))
+	assert.NoError(t, m.GetError())
+	assert.False(t, m.Match(nil, 0))
+}
+
+func TestUTF16(t *testing.T) {
+	// Offsets count code units, so the surrogate pair counts twice.
+	re := MustCompile(`b.c`, pcre2.UTF)
+	defer re.Free()
+	assert.Equal(t, []int{3, 7}, re.FindIndex(EncodeUTF16Subject("a😀b😀c"), 0))
+
+	// Unpaired surrogates are rejected in UTF mode.
+	m := re.Matcher([]uint16{0xd800, 'b'}, 0)
+	assert.False(t, m.Matches())
+	assert.IsType(t, &pcre2.MatchError{}, m.GetError())
+}
+
+func TestCompileError(t *testing.T) {
+	_, err := Compile(`ä(`, 0)
+	if assert.IsType(t, &pcre2.CompileError{}, err) {
+		cerr := err.(*pcre2.CompileError)
+		assert.Equal(t, "missing closing parenthesis", cerr.Message)
+		assert.Equal(t, 2, cerr.Offset)
+	}
+}
+
+func TestUTF16Helpers(t *testing.T) {
+	wide := append(EncodeUTF16Subject("C:\\Users\\😀"), 0, 'x')
+	assert.Equal(t, "C:\\Users\\😀", DecodeUTF16(wide))
+	assert.Equal(t, "\ufffd", DecodeUTF16([]uint16{0xd800}))
+	assert.Equal(t, "", DecodeUTF16(nil))
+
+	re := MustCompile(`^C:\\Users\\[^\\]+$`, pcre2.UTF)
+	defer re.Free()
+	// The terminating NUL is not part of the subject.
+	assert.True(t, re.MatchUTF16(wide[:len(wide)-1], 0))
+	assert.False(t, re.MatchUTF16(EncodeUTF16Subject(`D:\\x`), 0))
+}
@@ -0,0 +1,34 @@
+package pcre216
+
+import "unicode/utf16"
+
+// EncodeUTF16Subject encodes a Go string as UTF-16 for use as a subject
+// or pattern. No terminating NUL is added, as PCRE2 takes the length.
+func EncodeUTF16Subject(s string) []uint16 {
+	return utf16.Encode([]rune(s))
+}
+
+// DecodeUTF16 decodes UTF-16 into a Go string. Like wide strings from
+// Windows APIs, s may be NUL terminated, and decoding stops at the first
+// NUL. Unpaired surrogates become U+FFFD.
+func DecodeUTF16(s []uint16) string {
+	for i, u := range s {
+		if u == 0 {
+			s = s[:i]
+			break
+		}
+	}
+	return string(utf16.Decode(s))
+}
+
+// MatchUTF16 reports whether the pattern matches the UTF-16 subject,
+// e.g. a wide string from a Windows API. A terminating NUL is not part
+// of the subject; it is cut off first.
+func (re *Regexp) MatchUTF16(subject []uint16, flags uint32) bool {
+	if n := len(subject); n > 0 && subject[n-1] == 0 {
+		subject = subject[:n-1]
+	}
+	m := re.NewMatcher()
+	defer m.Free()
+	return m.Match(subject, flags)
+}
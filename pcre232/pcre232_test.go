@@ -0,0 +1,49 @@
+package pcre232
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jemmic/go-pcre2"
+)
+
+func TestMatch(t *testing.T) {
+	re := MustCompile(`(\w+)@(\w+)`, 0)
+	defer re.Free()
+	assert.Equal(t, 2, re.Groups())
+
+	m := re.NewMatcher()
+	defer m.Free()
+	assert.True(t, m.MatchString("mail: user@example", 0))
+	assert.Equal(t, []int{6, 18}, m.Index())
+	assert.Equal(t, "example", m.GroupString(2))
+	assert.Equal(t, []rune("user"), m.Group(1))
+	assert.NoError(t, m.GetError())
+
+	assert.False(t, m.Match([]rune("nothing"), 0))
+	assert.Nil(t, m.Group(1))
+	assert.False(t, m.Match(nil, 0))
+	assert.True(t, m.MatchUint32([]uint32{'a', '@', 'b'}, 0))
+	assert.Equal(t, "b", m.GroupString(2))
+}
+
+func TestRuneOffsets(t *testing.T) {
+	// Offsets are rune indices, whatever the UTF-8 length of the runes.
+	re := MustCompile(`b.c`, pcre2.UTF)
+	defer re.Free()
+	assert.Equal(t, []int{2, 5}, re.FindIndex([]rune("äöbüc"), 0))
+
+	m := re.Matcher([]rune{'b', 0x110000, 'c'}, 0)
+	assert.False(t, m.Matches())
+	assert.IsType(t, &pcre2.MatchError{}, m.GetError())
+}
+
+func TestCompileError(t *testing.T) {
+	_, err := Compile(`ä(`, 0)
+	if assert.IsType(t, &pcre2.CompileError{}, err) {
+		cerr := err.(*pcre2.CompileError)
+		assert.Equal(t, "missing closing parenthesis", cerr.Message)
+		assert.Equal(t, 2, cerr.Offset)
+	}
+}
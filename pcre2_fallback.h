@@ -1,3 +1,14 @@
+/*
+ * Constants added in later versions of PCRE2 are defined as zero below,
+ * so that the package builds with the headers of PCRE2 10.20 or later.
+ * Functions and types added later are replaced by stubs at the end. See
+ * Supports for the features of the headers and the linked library.
+ */
+#ifndef GO_PCRE2_FALLBACK_H
+#define GO_PCRE2_FALLBACK_H
+
+#include <stdlib.h>
+
 #ifndef PCRE2_NEVER_UTF
 #define PCRE2_NEVER_UTF 0x0
 #endif
@@ -46,6 +57,15 @@
 #ifndef PCRE2_SUBSTITUTE_OVERFLOW_LENGTH
 #define PCRE2_SUBSTITUTE_OVERFLOW_LENGTH 0x0
 #endif
+#ifndef PCRE2_SUBSTITUTE_LITERAL
+#define PCRE2_SUBSTITUTE_LITERAL 0x0
+#endif
+#ifndef PCRE2_SUBSTITUTE_MATCHED
+#define PCRE2_SUBSTITUTE_MATCHED 0x0
+#endif
+#ifndef PCRE2_SUBSTITUTE_REPLACEMENT_ONLY
+#define PCRE2_SUBSTITUTE_REPLACEMENT_ONLY 0x0
+#endif
 #ifndef PCRE2_NO_JIT
 #define PCRE2_NO_JIT 0x0
 #endif
@@ -55,6 +75,15 @@
 #ifndef PCRE2_CONVERT_NO_UTF_CHECK
 #define PCRE2_CONVERT_NO_UTF_CHECK 0x0
 #endif
+
+/*
+ * An option for which zero would silently change the meaning of
+ * patterns gets its real value, so that older libraries reject it. See
+ * FeatureLookaroundBSK.
+ */
+#ifndef PCRE2_EXTRA_ALLOW_LOOKAROUND_BSK
+#define PCRE2_EXTRA_ALLOW_LOOKAROUND_BSK 0x00000040u
+#endif
 #ifndef PCRE2_CONVERT_POSIX_BASIC
 #define PCRE2_CONVERT_POSIX_BASIC 0x0
 #endif
@@ -385,6 +414,9 @@
 #ifndef PCRE2_ERROR_INTERNAL_DUPMATCH
 #define PCRE2_ERROR_INTERNAL_DUPMATCH 0x0
 #endif
+#ifndef PCRE2_ERROR_DEPTHLIMIT
+#define PCRE2_ERROR_DEPTHLIMIT PCRE2_ERROR_RECURSIONLIMIT
+#endif
 #ifndef PCRE2_INFO_HASBACKSLASHC
 #define PCRE2_INFO_HASBACKSLASHC 0x0
 #endif
@@ -397,6 +429,9 @@
 #ifndef PCRE2_INFO_EXTRAOPTIONS
 #define PCRE2_INFO_EXTRAOPTIONS 0x0
 #endif
+#ifndef PCRE2_CONFIG_DEPTHLIMIT
+#define PCRE2_CONFIG_DEPTHLIMIT PCRE2_CONFIG_RECURSIONLIMIT
+#endif
 #ifndef PCRE2_CONFIG_HEAPLIMIT
 #define PCRE2_CONFIG_HEAPLIMIT 0x0
 #endif
@@ -406,3 +441,124 @@
 #ifndef PCRE2_CONFIG_COMPILED_WIDTHS
 #define PCRE2_CONFIG_COMPILED_WIDTHS 0x0
 #endif
+#ifndef PCRE2_CALLOUT_STARTMATCH
+#define PCRE2_CALLOUT_STARTMATCH 0x0
+#endif
+#ifndef PCRE2_CALLOUT_BACKTRACK
+#define PCRE2_CALLOUT_BACKTRACK 0x0
+#endif
+
+#define MY_PCRE2_AT_LEAST(major, minor) \
+	(PCRE2_MAJOR > (major) || (PCRE2_MAJOR == (major) && PCRE2_MINOR >= (minor)))
+
+/*
+ * Stubs of the functions missing from older headers. They fail with
+ * PCRE2_ERROR_BADDATA or do nothing; the Go side checks Supports before
+ * relying on them.
+ */
+#if !MY_PCRE2_AT_LEAST(10, 21)
+static inline int32_t pcre2_serialize_encode(const pcre2_code **codes, int32_t number, uint8_t **bytes, PCRE2_SIZE *size, pcre2_general_context *gcontext) {
+	return PCRE2_ERROR_BADDATA;
+}
+static inline int32_t pcre2_serialize_decode(pcre2_code **codes, int32_t number, const uint8_t *bytes, pcre2_general_context *gcontext) {
+	return PCRE2_ERROR_BADDATA;
+}
+static inline int32_t pcre2_serialize_get_number_of_codes(const uint8_t *bytes) {
+	return PCRE2_ERROR_BADDATA;
+}
+static inline void pcre2_serialize_free(uint8_t *bytes) {
+}
+#endif
+
+#if !MY_PCRE2_AT_LEAST(10, 22)
+typedef struct {
+	uint32_t version;
+	PCRE2_SIZE pattern_position;
+	PCRE2_SIZE next_item_length;
+	uint32_t callout_number;
+	PCRE2_SIZE callout_string_offset;
+	PCRE2_SIZE callout_string_length;
+	PCRE2_SPTR callout_string;
+} pcre2_callout_enumerate_block;
+
+static inline int pcre2_callout_enumerate(const pcre2_code *code, int (*callback)(pcre2_callout_enumerate_block *, void *), void *data) {
+	return PCRE2_ERROR_BADDATA;
+}
+#endif
+
+#if !MY_PCRE2_AT_LEAST(10, 23)
+static inline pcre2_code *pcre2_code_copy(const pcre2_code *code) {
+	return NULL;
+}
+#endif
+
+#if !MY_PCRE2_AT_LEAST(10, 30)
+typedef struct pcre2_real_convert_context pcre2_convert_context;
+
+static inline int pcre2_set_depth_limit(pcre2_match_context *mcontext, uint32_t limit) {
+	return pcre2_set_recursion_limit(mcontext, limit);
+}
+static inline int pcre2_set_heap_limit(pcre2_match_context *mcontext, uint32_t limit) {
+	return 0;
+}
+static inline int pcre2_set_compile_extra_options(pcre2_compile_context *ccontext, uint32_t options) {
+	return 0;
+}
+static inline pcre2_convert_context *pcre2_convert_context_create(pcre2_general_context *gcontext) {
+	return NULL;
+}
+static inline void pcre2_convert_context_free(pcre2_convert_context *cvcontext) {
+}
+static inline int pcre2_set_glob_escape(pcre2_convert_context *cvcontext, uint32_t escape) {
+	return PCRE2_ERROR_BADDATA;
+}
+static inline int pcre2_set_glob_separator(pcre2_convert_context *cvcontext, uint32_t separator) {
+	return PCRE2_ERROR_BADDATA;
+}
+static inline int pcre2_pattern_convert(PCRE2_SPTR pattern, PCRE2_SIZE length, uint32_t options, PCRE2_UCHAR **buffer, PCRE2_SIZE *blength, pcre2_convert_context *cvcontext) {
+	*blength = 0;
+	return PCRE2_ERROR_BADDATA;
+}
+static inline void pcre2_converted_pattern_free(PCRE2_UCHAR *converted) {
+}
+#endif
+
+/* The flags of a callout block, which older headers do not have. */
+static inline uint32_t myCalloutFlags(pcre2_callout_block *block) {
+#if MY_PCRE2_AT_LEAST(10, 30)
+	return block->callout_flags;
+#else
+	return 0;
+#endif
+}
+
+#if !MY_PCRE2_AT_LEAST(10, 31)
+static inline pcre2_code *pcre2_code_copy_with_tables(const pcre2_code *code) {
+	return NULL;
+}
+#endif
+
+#if !MY_PCRE2_AT_LEAST(10, 33)
+typedef struct {
+	uint32_t version;
+	PCRE2_SPTR input;
+	PCRE2_SPTR output;
+	PCRE2_SIZE output_offsets[2];
+	PCRE2_SIZE *ovector;
+	uint32_t oveccount;
+	uint32_t subscount;
+} pcre2_substitute_callout_block;
+
+static inline int pcre2_set_substitute_callout(pcre2_match_context *mcontext, int (*callout)(pcre2_substitute_callout_block *, void *), void *data) {
+	return 0;
+}
+#endif
+
+#if !MY_PCRE2_AT_LEAST(10, 34)
+/* Tables made by pcre2_maketables(NULL) come from malloc. */
+static inline void pcre2_maketables_free(pcre2_general_context *gcontext, const uint8_t *tables) {
+	free((void *) tables);
+}
+#endif
+
+#endif /* GO_PCRE2_FALLBACK_H */
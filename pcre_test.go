@@ -48,6 +48,28 @@ func TestCompileFail(t *testing.T) {
 	check("a\000bc", "NUL byte in pattern", 1)
 }
 
+func TestCompileBytes(t *testing.T) {
+	re, err := CompileBytes([]byte("a\x00(b+)"), 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer re.Free()
+	assert.Equal(t, "a\x00(b+)", re.Pattern)
+	m := re.MatcherString("xa\x00bb", 0)
+	assert.True(t, m.Matches())
+	assert.Equal(t, "bb", m.GroupString(1))
+	assert.False(t, re.MatcherString("ab", 0).Matches())
+
+	_, err = CompileBytes([]byte("a\x00("), 0)
+	if assert.IsType(t, &CompileError{}, err) {
+		assert.Equal(t, 3, err.(*CompileError).Offset)
+	}
+	re, err = CompileBytes(nil, 0)
+	if assert.NoError(t, err) {
+		assert.True(t, re.MatcherString("", 0).Matches())
+	}
+}
+
 func TestJITCompile(t *testing.T) {
 	re, err := Compile(`^Hello (.+)!$`, 0)
 	if !assert.NoError(t, err, "Compile works") {
@@ -58,7 +80,7 @@ func TestJITCompile(t *testing.T) {
 	assert.NoError(t, re.JITCompile(0))
 }
 
-func strings(b [][]byte) (r []string) {
+func toStrings(b [][]byte) (r []string) {
 	r = make([]string, len(b))
 	for i, v := range b {
 		r[i] = string(v)
@@ -154,6 +176,53 @@ func TestMatcher(t *testing.T) {
 	check(`def`, "abcdefghi", "def")
 }
 
+func TestNewMatcherErr(t *testing.T) {
+	re := MustCompile(`a`, 0)
+	m, err := re.NewMatcherErr()
+	if assert.NoError(t, err) {
+		assert.True(t, m.MatchString("a", 0))
+	}
+
+	re.Free()
+	_, err = re.NewMatcherErr()
+	assert.Equal(t, ErrInvalidRegexp, err)
+	assert.Equal(t, ErrInvalidRegexp, m.InitErr(re))
+	assert.Equal(t, ErrInvalidRegexp, m.InitErr(nil))
+	_, err = (*Regexp)(nil).NewMatcherErr()
+	assert.Equal(t, ErrInvalidRegexp, err)
+
+	var m2 Matcher
+	assert.NoError(t, m2.InitErr(MustCompile(`b`, 0)))
+	assert.True(t, m2.MatchString("b", 0))
+}
+
+func TestUnsetGroups(t *testing.T) {
+	re := MustCompile(`(a)|(b)`, 0)
+	defer re.Free()
+	m := re.MatcherString("b", 0)
+	defer m.Free()
+	assert.False(t, m.Present(1))
+	assert.Nil(t, m.Group(1))
+	assert.Equal(t, "", m.GroupString(1))
+	assert.Nil(t, m.GroupIndices(1))
+	assert.Equal(t, "b", m.GroupString(2))
+
+	start, end, ok := m.OffsetPair(2)
+	assert.True(t, ok)
+	assert.Equal(t, int64(0), start)
+	assert.Equal(t, int64(1), end)
+	start, end, ok = m.OffsetPair(1)
+	assert.False(t, ok)
+	assert.Equal(t, int64(-1), start)
+	assert.Equal(t, int64(-1), end)
+	_, _, ok = m.OffsetPair(3)
+	assert.False(t, ok)
+
+	m.MatchString("c", 0)
+	_, _, ok = m.OffsetPair(0)
+	assert.False(t, ok)
+}
+
 func TestPartial(t *testing.T) {
 	re := MustCompile(`^abc`, 0)
 
@@ -219,6 +288,27 @@ func TestNamed(t *testing.T) {
 	}
 }
 
+func TestNamedIndices(t *testing.T) {
+	re := MustCompile(`(?<key>\w+)=(?<value>\d+)?`, 0)
+	defer re.Free()
+	m := re.MatcherString("x: port=", 0)
+	defer m.Free()
+
+	loc, err := m.NamedIndices("key")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3, 7}, loc)
+	loc, err = m.NamedIndices("value")
+	assert.NoError(t, err)
+	assert.Nil(t, loc)
+	_, err = m.NamedIndices("other")
+	assert.Error(t, err)
+
+	m.MatchString("none", 0)
+	loc, err = m.NamedIndices("key")
+	assert.NoError(t, err)
+	assert.Nil(t, loc)
+}
+
 func TestMatcherIndex(t *testing.T) {
 	m := MustCompile("bcd", 0).Matcher([]byte("abcdef"), 0)
 	i := m.Index()
@@ -274,4 +364,273 @@ func TestReplaceAll(t *testing.T) {
 	if string(result) != "card fight carls car" {
 		t.Error("ReplaceAll2", result)
 	}
+	// The rest of the subject is searched as a new subject.
+	re = MustCompile("^a", 0)
+	assert.Equal(t, "---", re.ReplaceAllString("aaa", "-", 0))
+	assert.Equal(t, "-aa", re.ReplaceAllSafeString("aaa", "-", 0))
+}
+
+func TestReplaceAllSubmatchFunc(t *testing.T) {
+	re := MustCompile(`(\d{4})-(\d\d)-(\d\d)(Z)?`, 0)
+	defer re.Free()
+
+	out := re.ReplaceAllStringSubmatchFunc("from 2024-05-01 to 2024-06-30Z.", func(groups []string) string {
+		s := groups[3] + "." + groups[2] + "." + groups[1]
+		if groups[4] != "" {
+			s += " UTC"
+		}
+		return s
+	}, 0)
+	assert.Equal(t, "from 01.05.2024 to 30.06.2024 UTC.", out)
+
+	var matches []string
+	b := re.ReplaceAllSubmatchFunc([]byte("2024-05-01,2024-06-30Z"), func(groups [][]byte) []byte {
+		matches = append(matches, string(groups[0]))
+		assert.Len(t, groups, 5)
+		if groups[4] == nil {
+			return []byte("local")
+		}
+		return []byte("utc")
+	}, 0)
+	assert.Equal(t, "local,utc", string(b))
+	assert.Equal(t, []string{"2024-05-01", "2024-06-30Z"}, matches)
+
+	empty := MustCompile(`(x)?`, 0)
+	defer empty.Free()
+	out = empty.ReplaceAllStringSubmatchFunc("axb", func(groups []string) string {
+		return "[" + groups[1] + "]"
+	}, 0)
+	assert.Equal(t, "[]a[x][]b[]", out)
+}
+
+func TestReplaceAllStartAfterEnd(t *testing.T) {
+	if !Supports(FeatureLookaroundBSK) {
+		t.Skip("\\K in lookarounds needs PCRE2 10.38")
+	}
+	// \K in the lookahead sets the start after the end; those matches
+	// are kept, and the later ones still replaced.
+	re, err := CompileWithOptions(`(?=ab\K)|c`, CompileOptions{ExtraOptions: EXTRA_ALLOW_LOOKAROUND_BSK})
+	assert.NoError(t, err)
+	defer re.Free()
+	assert.Equal(t, "ab-ab-", re.ReplaceAllSafeString("abcabc", "-", 0))
+	assert.Equal(t, [][]int{{2, 0}, {2, 3}, {5, 3}, {5, 6}}, re.FindAllStringIndex("abcabc", 0, -1))
+}
+
+func TestReplaceAllEmptyMatches(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		flags   uint32
+		subject string
+		want    string
+	}{
+		{`x*`, 0, "axxb", "-a--b-"},
+		{`a\K`, 0, "aaa", "a-a-a-"},
+		{`(?<=a)b`, 0, "abab", "a-a-"},
+		{`^`, MULTILINE, "a\nb", "-a\n-b"},
+		{``, 0, "\r\n", "-\r-\n-"},
+		{`(*CRLF)`, 0, "a\r\nb", "-a-\r\n-b-"},
+		{``, UTF, "äö", "-ä-ö-"},
+		{`\b`, UTF, "ab cd", "-ab- -cd-"},
+	} {
+		re := MustCompile(tc.pattern, tc.flags)
+		assert.Equal(t, tc.want, string(re.ReplaceAllSafe([]byte(tc.subject), []byte("-"), 0)), tc.pattern)
+		assert.Equal(t, tc.want, re.ReplaceAllSafeString(tc.subject, "-", 0), tc.pattern)
+		if Supports(FeatureSubstituteMatched) {
+			// PCRE2 itself agrees.
+			out, _, err := re.SubstituteString(tc.subject, "-", SUBSTITUTE_GLOBAL|SUBSTITUTE_LITERAL)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, out, tc.pattern)
+		}
+		re.Free()
+	}
+}
+
+func TestClone(t *testing.T) {
+	re := MustCompile(`^(a+)(b*)$`, CASELESS)
+	clone, err := re.Clone()
+	if !assert.NoError(t, err) {
+		return
+	}
+	re.Free()
+
+	assert.Equal(t, re.Pattern, clone.Pattern)
+	assert.Equal(t, 2, clone.Groups())
+	m := clone.MatcherString("AAb", 0)
+	assert.True(t, m.Matches())
+	assert.Equal(t, "AA", m.GroupString(1))
+	assert.NoError(t, clone.Free())
+
+	_, err = re.Clone()
+	assert.Equal(t, ErrInvalidRegexp, err)
+}
+
+func TestCloneWithTables(t *testing.T) {
+	re := MustCompile(`b+`, CASELESS)
+	clone, err := re.CloneWithTables()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer clone.Free()
+	re.Free()
+
+	assert.Equal(t, []int{1, 3}, clone.FindIndex([]byte("aBbc"), 0))
+
+	_, err = re.CloneWithTables()
+	assert.Equal(t, ErrInvalidRegexp, err)
+}
+
+func TestUnicodeUnavailable(t *testing.T) {
+	defer func(supported bool) { unicodeSupported = supported }(unicodeSupported)
+	unicodeSupported = false
+
+	_, err := Compile(`\w+`, UTF)
+	assert.Equal(t, ErrUnicodeUnavailable, err)
+	_, err = Compile(`\w+`, UCP)
+	assert.Equal(t, ErrUnicodeUnavailable, err)
+	_, err = Compile(`\w+`, CASELESS)
+	assert.NoError(t, err)
+}
+
+func TestMark(t *testing.T) {
+	re := MustCompile(`(?:a(*MARK:A)|b(*MARK:B)|c)x`, 0)
+	defer re.Free()
+	m := re.NewMatcher()
+	defer m.Free()
+
+	_, ok := m.Mark()
+	assert.False(t, ok)
+
+	assert.True(t, m.MatchString("bx", 0))
+	name, ok := m.Mark()
+	assert.True(t, ok)
+	assert.Equal(t, "B", name)
+
+	assert.True(t, m.MatchString("cx", 0))
+	_, ok = m.Mark()
+	assert.False(t, ok)
+
+	assert.True(t, m.MatchString("ax", 0))
+	m.Init(re)
+	_, ok = m.Mark()
+	assert.False(t, ok)
+
+	re2 := MustCompile(`a(*PRUNE:P)b|ac`, 0)
+	defer re2.Free()
+	m2 := re2.MatcherString("ab", 0)
+	defer m2.Free()
+	name, ok = m2.Mark()
+	assert.True(t, ok)
+	assert.Equal(t, "P", name)
+
+	// PCRE2 reports the last mark passed by a failed match, too.
+	re3 := MustCompile(`X(*MARK:A)Y|X(*MARK:B)Z`, 0)
+	defer re3.Free()
+	m3 := re3.MatcherString("XP", 0)
+	defer m3.Free()
+	name, ok = m3.Mark()
+	assert.True(t, ok)
+	assert.Equal(t, "B", name)
+}
+
+func TestStartChar(t *testing.T) {
+	re := MustCompile(`foo\Kbar`, 0)
+	defer re.Free()
+	m := re.NewMatcher()
+	defer m.Free()
+
+	assert.Equal(t, -1, m.StartChar())
+	assert.True(t, m.MatchString("xfoobar", 0))
+	assert.Equal(t, []int{4, 7}, m.Index())
+	assert.Equal(t, 1, m.StartChar())
+
+	assert.False(t, m.MatchString("foo", 0))
+	assert.Equal(t, -1, m.StartChar())
+
+	assert.True(t, m.MatchString("xxfoob", PARTIAL_HARD))
+	assert.Equal(t, 2, m.StartChar())
+
+	literal := MustCompile(`bar`, 0)
+	defer literal.Free()
+	m.Init(literal)
+	assert.True(t, m.MatchString("foobar", 0))
+	assert.Equal(t, 3, m.StartChar())
+}
+
+func TestOVector(t *testing.T) {
+	re := MustCompile(`(a)|(b)(c)?`, 0)
+	defer re.Free()
+	m := re.NewMatcher()
+	defer m.Free()
+
+	assert.Nil(t, m.OVector())
+	assert.True(t, m.MatchString("xb", 0))
+	assert.Equal(t, []int64{1, 2, -1, -1, 1, 2, -1, -1}, m.OVector())
+
+	if !Supports(FeatureLookaroundBSK) {
+		t.Skip("\\K in lookarounds needs PCRE2 10.38")
+	}
+	// \K in a lookahead sets the start after the end.
+	re2, err := CompileWithOptions(`(?=ab\K)`, CompileOptions{ExtraOptions: EXTRA_ALLOW_LOOKAROUND_BSK})
+	assert.NoError(t, err)
+	defer re2.Free()
+	m.Init(re2)
+	assert.True(t, m.MatchString("ab", 0))
+	assert.Equal(t, []int64{2, 0}, m.OVector())
+}
+
+func TestReturnCode(t *testing.T) {
+	re := MustCompile(`(a)(b)?(c)?(d)?`, 0)
+	defer re.Free()
+	m := re.NewMatcher()
+	defer m.Free()
+
+	assert.Equal(t, 0, m.CaptureCount())
+	assert.True(t, m.MatchString("xac", 0))
+	assert.Equal(t, 4, m.ReturnCode())
+	assert.Equal(t, 3, m.CaptureCount())
+	assert.False(t, m.Present(2))
+	assert.True(t, m.Present(3))
+
+	assert.True(t, m.MatchString("ab", 0))
+	assert.Equal(t, 3, m.ReturnCode())
+	assert.Equal(t, 2, m.CaptureCount())
+
+	assert.False(t, m.MatchString("x", 0))
+	assert.Equal(t, ERROR_NOMATCH, m.ReturnCode())
+	assert.Equal(t, 0, m.CaptureCount())
+
+	re2 := MustCompile(`abc`, 0)
+	defer re2.Free()
+	m.Init(re2)
+	assert.True(t, m.MatchString("xab", PARTIAL_HARD))
+	assert.Equal(t, ERROR_PARTIAL, m.ReturnCode())
+	assert.Equal(t, 0, m.CaptureCount())
+}
+
+func TestAppendGroup(t *testing.T) {
+	re := MustCompile(`(\w+)=(\d+)?`, 0)
+	defer re.Free()
+	m := re.NewMatcher()
+	defer m.Free()
+
+	buf := []byte("key:")
+	assert.Equal(t, "key:", string(m.AppendGroup(buf, 1)))
+
+	assert.True(t, m.MatchString("a=1", 0))
+	buf = m.AppendGroup(buf, 1)
+	buf = append(buf, ' ')
+	buf = m.AppendGroup(buf, 2)
+	assert.Equal(t, "key:a 1", string(buf))
+
+	assert.True(t, m.Match([]byte("bb="), 0))
+	assert.Equal(t, "bb", string(m.AppendGroup(nil, 1)))
+	assert.Nil(t, m.AppendGroup(nil, 2))
+
+	assert.True(t, m.MatchString("c=3", 0))
+	assert.Equal(t, "bb=3", string(m.AppendGroup([]byte("bb="), 2)))
+
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = m.AppendGroup(buf[:0], 1)
+	})
+	assert.Zero(t, allocs)
 }
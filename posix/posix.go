@@ -0,0 +1,285 @@
+// Package posix provides an API modelled on the POSIX functions regcomp,
+// regexec, regerror and regfree, as implemented by the pcre2posix
+// wrapper of PCRE2, on top of package pcre2. It eases porting C code
+// which used that wrapper: the REG_* flags and error codes have the same
+// values and meaning, and patterns use PCRE2 syntax, not POSIX syntax.
+package posix
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Jemmic/go-pcre2"
+)
+
+// Flags for Regcomp.
+const (
+	REG_EXTENDED = 0      // ignored, patterns always use PCRE2 syntax
+	REG_ICASE    = 0x0001 // pcre2.CASELESS
+	REG_NEWLINE  = 0x0002 // pcre2.MULTILINE
+	REG_DOTALL   = 0x0010 // pcre2.DOTALL
+	REG_NOSUB    = 0x0020 // pcre2.NO_AUTO_CAPTURE, no match offsets
+	REG_UTF      = 0x0040 // pcre2.UTF
+	REG_UNGREEDY = 0x0200 // pcre2.UNGREEDY
+	REG_UCP      = 0x0400 // pcre2.UCP
+	REG_PEND     = 0x0800 // ignored, Go strings have a length
+	REG_NOSPEC   = 0x1000 // pcre2.LITERAL
+)
+
+// Flags for Regexec.
+const (
+	REG_NOTBOL   = 0x0004 // pcre2.NOTBOL
+	REG_NOTEOL   = 0x0008 // pcre2.NOTEOL
+	REG_STARTEND = 0x0080 // match only between pmatch[0].So and pmatch[0].Eo
+	REG_NOTEMPTY = 0x0100 // pcre2.NOTEMPTY
+)
+
+// Error codes.
+const (
+	REG_ASSERT = iota + 1
+	REG_BADBR
+	REG_BADPAT
+	REG_BADRPT
+	REG_EBRACE
+	REG_EBRACK
+	REG_ECOLLATE
+	REG_ECTYPE
+	REG_EESCAPE
+	REG_EMPTY
+	REG_EPAREN
+	REG_ERANGE
+	REG_ESIZE
+	REG_ESPACE
+	REG_ESUBREG
+	REG_INVARG
+	REG_NOMATCH
+)
+
+// messages are the texts of the error codes, as returned by regerror.
+var messages = []string{
+	"",
+	"internal error",
+	"invalid repeat counts in {}",
+	"pattern error",
+	"? * + invalid",
+	"unbalanced {}",
+	"unbalanced []",
+	"collation error - not relevant",
+	"bad class",
+	"bad escape sequence",
+	"empty expression",
+	"unbalanced ()",
+	"bad range inside []",
+	"expression too big",
+	"failed to get memory",
+	"bad back reference",
+	"bad argument",
+	"match failed",
+}
+
+// Regerror returns the message of an error code.
+func Regerror(code int) string {
+	if code < 0 || code >= len(messages) {
+		return "unknown error code"
+	}
+	return messages[code]
+}
+
+// Error is the error returned by Regcomp and Regexec.
+type Error struct {
+	Code   int   // one of the REG_* error codes
+	Offset int   // offset in the pattern of a compile error, or -1
+	Err    error // the error of package pcre2, if any
+}
+
+// Error converts the error to a string like regerror.
+func (e *Error) Error() string {
+	if e.Offset >= 0 {
+		return fmt.Sprintf("%s at offset %d", Regerror(e.Code), e.Offset)
+	}
+	return Regerror(e.Code)
+}
+
+// Unwrap returns the error of package pcre2.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrNoMatch is returned by Regexec if the subject does not match.
+var ErrNoMatch = &Error{Code: REG_NOMATCH, Offset: -1}
+
+// Regmatch holds the offsets of a capture group, like regmatch_t. Both
+// are -1 for groups which are unset.
+type Regmatch struct {
+	So int // start offset
+	Eo int // end offset
+}
+
+// Regex is a compiled pattern, like regex_t.
+type Regex struct {
+	Nsub   int // number of capture groups
+	re     *pcre2.Regexp
+	cflags int
+}
+
+// compileFlags maps the flags of Regcomp to those of pcre2.Compile.
+var compileFlags = []struct {
+	reg   int
+	flags uint32
+}{
+	{REG_ICASE, pcre2.CASELESS},
+	{REG_NEWLINE, pcre2.MULTILINE},
+	{REG_DOTALL, pcre2.DOTALL},
+	{REG_NOSUB, pcre2.NO_AUTO_CAPTURE},
+	{REG_UTF, pcre2.UTF},
+	{REG_UNGREEDY, pcre2.UNGREEDY},
+	{REG_UCP, pcre2.UCP},
+	{REG_NOSPEC, pcre2.LITERAL},
+}
+
+// Regcomp compiles the pattern. If compilation fails, the error is an
+// *Error with the REG_* code pcre2posix maps the compile error to.
+func Regcomp(pattern string, cflags int) (*Regex, error) {
+	var flags uint32
+	for _, f := range compileFlags {
+		if cflags&f.reg != 0 {
+			flags |= f.flags
+		}
+	}
+	re, err := pcre2.Compile(pattern, flags)
+	if err != nil {
+		e := &Error{Code: REG_BADPAT, Offset: -1, Err: err}
+		var ce *pcre2.CompileError
+		if errors.As(err, &ce) {
+			e.Code = compileErrorCode(ce.ErrorNum)
+			e.Offset = ce.Offset
+		}
+		return nil, e
+	}
+	return &Regex{Nsub: re.Groups(), re: re, cflags: cflags}, nil
+}
+
+// compileErrorCodes maps the first PCRE2 compile errors, counted from
+// ERROR_END_BACKSLASH, to REG_* codes.
+var compileErrorCodes = []int{
+	REG_EESCAPE, // \ at end of pattern
+	REG_EESCAPE, // \c at end of pattern
+	REG_EESCAPE, // unrecognized character follows \
+	REG_BADBR,   // numbers out of order in {} quantifier
+	REG_BADBR,   // number too big in {} quantifier
+	REG_EBRACK,  // missing terminating ] for character class
+	REG_ECTYPE,  // invalid escape sequence in character class
+	REG_ERANGE,  // range out of order in character class
+	REG_BADRPT,  // nothing to repeat
+	REG_ASSERT,  // internal error: unexpected repeat
+	REG_BADPAT,  // unrecognized character after (? or (?-
+	REG_BADPAT,  // POSIX named classes are supported only within a class
+	REG_BADPAT,  // POSIX collating elements are not supported
+	REG_EPAREN,  // missing )
+	REG_ESUBREG, // reference to non-existent subpattern
+	REG_INVARG,  // pattern passed as NULL
+	REG_INVARG,  // unknown compile-time option bit(s)
+	REG_EPAREN,  // missing ) after (?# comment
+	REG_ESIZE,   // parentheses nested too deeply
+	REG_ESIZE,   // regular expression too large
+	REG_ESPACE,  // failed to get memory
+	REG_EPAREN,  // unmatched closing parenthesis
+	REG_ASSERT,  // internal error: code overflow
+}
+
+// More compile errors with a specific REG_* code.
+var otherCompileErrorCodes = map[int]int{
+	pcre2.ERROR_UNKNOWN_POSIX_CLASS:         REG_ECTYPE,
+	pcre2.ERROR_UNICODE_NOT_SUPPORTED:       REG_INVARG,
+	pcre2.ERROR_UNSUPPORTED_ESCAPE_SEQUENCE: REG_EESCAPE,
+	pcre2.ERROR_INTERNAL_UNKNOWN_NEWLINE:    REG_INVARG,
+	pcre2.ERROR_BAD_LITERAL_OPTIONS:         REG_INVARG,
+}
+
+func compileErrorCode(errnum int) int {
+	if i := errnum - pcre2.ERROR_END_BACKSLASH; i >= 0 && i < len(compileErrorCodes) {
+		return compileErrorCodes[i]
+	}
+	if code, ok := otherCompileErrorCodes[errnum]; ok {
+		return code
+	}
+	return REG_BADPAT
+}
+
+// Regexec matches the subject. On success it fills in pmatch with the
+// offsets of the whole match and the capture groups, as far as it has
+// room; entries without a group are set to -1. It returns ErrNoMatch if
+// the subject does not match, or another *Error.
+//
+// With REG_STARTEND, only the part of the subject between pmatch[0].So
+// and pmatch[0].Eo is searched, and the offsets are still relative to
+// the start of the subject. With REG_NOSUB at compile time, pmatch is
+// ignored.
+func (r *Regex) Regexec(subject string, pmatch []Regmatch, eflags int) error {
+	var opts pcre2.MatchOptions
+	if eflags&REG_NOTBOL != 0 {
+		opts.Flags |= pcre2.NOTBOL
+	}
+	if eflags&REG_NOTEOL != 0 {
+		opts.Flags |= pcre2.NOTEOL
+	}
+	if eflags&REG_NOTEMPTY != 0 {
+		opts.Flags |= pcre2.NOTEMPTY
+	}
+	if eflags&REG_STARTEND != 0 {
+		if len(pmatch) == 0 || pmatch[0].So < 0 || pmatch[0].So > pmatch[0].Eo ||
+			pmatch[0].Eo > len(subject) {
+			return &Error{Code: REG_INVARG, Offset: -1}
+		}
+		opts.Offset = pmatch[0].So
+		subject = subject[:pmatch[0].Eo]
+	}
+	if r.cflags&REG_NOSUB != 0 {
+		pmatch = nil
+	}
+	m := r.re.NewMatcher()
+	defer m.Free()
+	if !m.MatchStringWithOptions(subject, opts) {
+		err := m.GetError()
+		var me *pcre2.MatchError
+		if errors.As(err, &me) && me.ErrorNum == pcre2.ERROR_NOMATCH {
+			return ErrNoMatch
+		}
+		return &Error{Code: matchErrorCode(err), Offset: -1, Err: err}
+	}
+	for i := range pmatch {
+		pmatch[i] = Regmatch{-1, -1}
+		if i <= m.Groups() {
+			if loc := m.GroupIndices(i); loc != nil {
+				pmatch[i] = Regmatch{loc[0], loc[1]}
+			}
+		}
+	}
+	return nil
+}
+
+// matchErrorCode maps a match error to a REG_* code like pcre2posix.
+func matchErrorCode(err error) int {
+	switch {
+	case errors.Is(err, pcre2.ErrBadUTF), errors.Is(err, pcre2.ErrBadOffset):
+		return REG_INVARG
+	case errors.Is(err, pcre2.ErrMatchLimit), errors.Is(err, pcre2.ErrDepthLimit),
+		errors.Is(err, pcre2.ErrHeapLimit):
+		return REG_ESPACE
+	}
+	var me *pcre2.MatchError
+	if errors.As(err, &me) {
+		switch me.ErrorNum {
+		case pcre2.ERROR_NOMEMORY:
+			return REG_ESPACE
+		case pcre2.ERROR_BADMODE, pcre2.ERROR_BADMAGIC, pcre2.ERROR_BADOPTION:
+			return REG_INVARG
+		}
+	}
+	return REG_ASSERT
+}
+
+// Regfree releases the compiled pattern.
+func (r *Regex) Regfree() {
+	r.re.Free()
+}
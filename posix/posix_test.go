@@ -0,0 +1,68 @@
+package posix
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegexec(t *testing.T) {
+	r, err := Regcomp(`(\w+)@(\w+)(x)?`, REG_EXTENDED|REG_ICASE)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer r.Regfree()
+	assert.Equal(t, 3, r.Nsub)
+
+	pmatch := make([]Regmatch, 5)
+	assert.NoError(t, r.Regexec("mail USER@Example", pmatch, 0))
+	assert.Equal(t, []Regmatch{{5, 17}, {5, 9}, {10, 17}, {-1, -1}, {-1, -1}}, pmatch)
+
+	assert.Equal(t, ErrNoMatch, r.Regexec("no address", pmatch, 0))
+	assert.NoError(t, r.Regexec("a@b", nil, 0))
+}
+
+func TestRegexecFlags(t *testing.T) {
+	r, _ := Regcomp(`^b`, REG_NEWLINE)
+	assert.NoError(t, r.Regexec("a\nb", nil, 0))
+	assert.Equal(t, ErrNoMatch, r.Regexec("b", nil, REG_NOTBOL))
+
+	r, _ = Regcomp(`b+`, 0)
+	pmatch := []Regmatch{{3, 5}}
+	assert.NoError(t, r.Regexec("bb bbbb", pmatch, REG_STARTEND))
+	assert.Equal(t, Regmatch{3, 5}, pmatch[0], "offsets relative to the subject")
+
+	r, _ = Regcomp(`a.c`, REG_NOSPEC)
+	assert.Equal(t, ErrNoMatch, r.Regexec("abc", nil, 0))
+	assert.NoError(t, r.Regexec("a.c", nil, 0))
+
+	r, _ = Regcomp(`(a)(b)`, REG_NOSUB)
+	assert.Equal(t, 0, r.Nsub)
+	pmatch = []Regmatch{{7, 7}}
+	assert.NoError(t, r.Regexec("ab", pmatch, 0))
+	assert.Equal(t, Regmatch{7, 7}, pmatch[0], "pmatch ignored")
+}
+
+func TestRegcompErrors(t *testing.T) {
+	for pattern, code := range map[string]int{
+		`abc\`:      REG_EESCAPE,
+		`a{3,2}`:    REG_BADBR,
+		`[abc`:      REG_EBRACK,
+		`*a`:        REG_BADRPT,
+		`(abc`:      REG_EPAREN,
+		`abc)`:      REG_EPAREN,
+		`(a)\2`:     REG_ESUBREG,
+		`[[:foo:]]`: REG_ECTYPE,
+		`(?<`:       REG_BADPAT,
+	} {
+		_, err := Regcomp(pattern, 0)
+		var e *Error
+		if assert.True(t, errors.As(err, &e), pattern) {
+			assert.Equal(t, code, e.Code, "%s: %v", pattern, err)
+		}
+	}
+	_, err := Regcomp(`(abc`, 0)
+	assert.EqualError(t, err, "unbalanced () at offset 4")
+	assert.Equal(t, "match failed", Regerror(REG_NOMATCH))
+}
@@ -0,0 +1,150 @@
+package pcre2
+
+/*
+#define PCRE2_CODE_UNIT_WIDTH 8
+
+#include <pcre2.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ProfileEntry holds the statistics collected for a single item of a
+// profiled pattern.
+type ProfileEntry struct {
+	Offset int           // byte offset of the item in the pattern
+	Item   string        // pattern text of the item
+	Count  int64         // number of times matching reached the item
+	Time   time.Duration // time spent from the item up to the next one
+}
+
+// Profiler matches a pattern compiled with AUTO_CALLOUT and attributes
+// the time and iterations spent during matching to the items of the
+// pattern. This makes it possible to see where a pattern spends its
+// time, e.g. when debugging catastrophic backtracking.
+//
+// Profiling slows down matching considerably and should not be enabled
+// in production code paths.
+type Profiler struct {
+	re       *Regexp
+	m        *Matcher
+	entries  map[int]*ProfileEntry
+	matches  int64
+	total    time.Duration
+	last     *ProfileEntry // item reached by the previous callout
+	lastTime time.Time
+}
+
+// NewProfiler compiles the pattern with AUTO_CALLOUT in addition to
+// flags and returns a Profiler for it.
+func NewProfiler(pattern string, flags uint32) (*Profiler, error) {
+	re, err := Compile(pattern, flags|AUTO_CALLOUT)
+	if err != nil {
+		return nil, err
+	}
+	p := &Profiler{
+		re:      re,
+		m:       re.NewMatcher(),
+		entries: make(map[int]*ProfileEntry),
+	}
+	p.m.setCallout(p.callout)
+	return p, nil
+}
+
+func (p *Profiler) callout(block *C.pcre2_callout_block) C.int {
+	now := time.Now()
+	if p.last != nil {
+		p.last.Time += now.Sub(p.lastTime)
+	}
+	offset := int(block.pattern_position)
+	e := p.entries[offset]
+	if e == nil {
+		end := offset + int(block.next_item_length)
+		if end > len(p.re.Pattern) {
+			end = len(p.re.Pattern)
+		}
+		e = &ProfileEntry{Offset: offset, Item: p.re.Pattern[offset:end]}
+		p.entries[offset] = e
+	}
+	e.Count++
+	p.last = e
+	p.lastTime = now
+	return 0
+}
+
+func (p *Profiler) finish(start time.Time) {
+	now := time.Now()
+	if p.last != nil {
+		p.last.Time += now.Sub(p.lastTime)
+		p.last = nil
+	}
+	p.matches++
+	p.total += now.Sub(start)
+}
+
+// Match matches the subject and records the profile of the attempt.
+// The Matcher holding the results is returned.
+func (p *Profiler) Match(subject []byte, flags uint32) *Matcher {
+	start := time.Now()
+	p.m.Match(subject, flags)
+	p.finish(start)
+	return p.m
+}
+
+// MatchString is like Match, but with a string subject.
+func (p *Profiler) MatchString(subject string, flags uint32) *Matcher {
+	start := time.Now()
+	p.m.MatchString(subject, flags)
+	p.finish(start)
+	return p.m
+}
+
+// Entries returns the statistics of all items reached so far,
+// the most expensive first.
+func (p *Profiler) Entries() []ProfileEntry {
+	result := make([]ProfileEntry, 0, len(p.entries))
+	for _, e := range p.entries {
+		result = append(result, *e)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Time != result[j].Time {
+			return result[i].Time > result[j].Time
+		}
+		return result[i].Offset < result[j].Offset
+	})
+	return result
+}
+
+// Reset discards the statistics collected so far.
+func (p *Profiler) Reset() {
+	p.entries = make(map[int]*ProfileEntry)
+	p.matches = 0
+	p.total = 0
+}
+
+// Report renders the hot spots of the pattern as a table,
+// the most expensive items first.
+func (p *Profiler) Report() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "profile of %q: %d matches in %v\n", p.re.Pattern, p.matches, p.total)
+	fmt.Fprintf(&b, "%8s %10s %12s %7s  %s\n", "offset", "count", "time", "share", "item")
+	for _, e := range p.Entries() {
+		share := 0.0
+		if p.total > 0 {
+			share = 100 * float64(e.Time) / float64(p.total)
+		}
+		fmt.Fprintf(&b, "%8d %10d %12v %6.1f%%  %s\n", e.Offset, e.Count, e.Time, share, e.Item)
+	}
+	return b.String()
+}
+
+// Free releases the underlying C resources.
+func (p *Profiler) Free() error {
+	p.m.Free()
+	return p.re.Free()
+}
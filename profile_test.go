@@ -0,0 +1,34 @@
+package pcre2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfiler(t *testing.T) {
+	p, err := NewProfiler(`^(a+)+b`, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer p.Free()
+
+	m := p.MatchString("aaaaaaaaaaab", 0)
+	assert.True(t, m.Matches())
+	assert.False(t, p.MatchString("aaaaaaaaaaaa", 0).Matches())
+
+	entries := p.Entries()
+	if !assert.NotEmpty(t, entries) {
+		return
+	}
+	var items []string
+	for _, e := range entries {
+		assert.True(t, e.Count > 0, e.Item)
+		items = append(items, e.Item)
+	}
+	assert.Contains(t, items, "a+")
+	assert.Contains(t, p.Report(), `profile of "^(a+)+b": 2 matches`)
+
+	p.Reset()
+	assert.Empty(t, p.Entries())
+}
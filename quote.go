@@ -0,0 +1,45 @@
+package pcre2
+
+import "strings"
+
+// QuoteMeta returns a string that escapes all metacharacters of PCRE2
+// inside the argument text; the returned string is a pattern that
+// matches the literal text, like regexp.QuoteMeta. Besides punctuation,
+// white space and '#' are escaped, so that the result can be embedded
+// into EXTENDED patterns, too. NUL bytes are written as \x00, which
+// Compile accepts.
+func QuoteMeta(s string) string {
+	var b strings.Builder
+	b.Grow(2 * len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == 0:
+			b.WriteString(`\x00`)
+			continue
+		case special(c):
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// special reports whether the byte must be escaped by QuoteMeta. Any
+// ASCII character other than letters and digits can be escaped with a
+// backslash to stand for itself.
+func special(c byte) bool {
+	return c < 0x80 && !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_')
+}
+
+// QuoteLiteral is like QuoteMeta, but encloses the text in \Q...\E
+// instead of escaping characters one by one, which keeps it readable
+// and short. Occurrences of \E and NUL bytes are written outside of the
+// quotes.
+func QuoteLiteral(s string) string {
+	if s == "" {
+		return ""
+	}
+	s = strings.NewReplacer(`\E`, `\E\\E\Q`, "\x00", `\E\x00\Q`).Replace(s)
+	return `\Q` + s + `\E`
+}
@@ -0,0 +1,39 @@
+package pcre2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var quoteTests = []string{
+	"",
+	"abc_123",
+	`a.b*c+d?e(f)g[h]i{j}k|l^m$n\o`,
+	"a b\t#c\nd",
+	`x\Ey\Q`,
+	"nul\x00byte",
+	"-/:<>=!'\"`~@%&,;",
+	"unicode ü 日本",
+}
+
+func TestQuoteMeta(t *testing.T) {
+	assert.Equal(t, `1\.5\-2\.0\?`, QuoteMeta("1.5-2.0?"))
+	assert.Equal(t, "abc", QuoteMeta("abc"))
+	assert.Equal(t, `a\x00b`, QuoteMeta("a\x00b"))
+	assert.Equal(t, `\Qa.b\E`, QuoteLiteral("a.b"))
+	assert.Equal(t, `\Qa\E\\E\Qb\E`, QuoteLiteral(`a\Eb`))
+
+	for _, s := range quoteTests {
+		for _, quote := range []func(string) string{QuoteMeta, QuoteLiteral} {
+			for _, flags := range []uint32{0, EXTENDED, CASELESS | UTF} {
+				re, err := Compile("^"+quote(s)+"$", flags)
+				if !assert.NoError(t, err, "%q", s) {
+					continue
+				}
+				assert.True(t, re.MatcherString(s, 0).Matches(), "%q with flags %#x", s, flags)
+				re.Free()
+			}
+		}
+	}
+}
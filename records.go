@@ -0,0 +1,116 @@
+package pcre2
+
+import (
+	"bufio"
+	"io"
+)
+
+// RecordScanner groups the lines read from an io.Reader into logical
+// records, such as multi-line log events or stack traces. A record
+// begins with a line matching the record start pattern and includes all
+// following lines up to the next start line. Lines before the first
+// start line form a record of their own.
+//
+// Its interface follows bufio.Scanner:
+//
+//	s := pcre2.NewRecordScanner(r, pcre2.MustCompile(`^\d{4}-\d\d-\d\d `, 0))
+//	for s.Scan() {
+//		handle(s.Record())
+//	}
+//	if err := s.Err(); err != nil {
+//		...
+//	}
+type RecordScanner struct {
+	lines    *bufio.Scanner
+	m        *Matcher
+	record   []byte // the record returned by Record
+	pending  []byte // lines of the record being collected
+	started  bool   // pending holds at least one line
+	maxBytes int
+	done     bool
+}
+
+// NewRecordScanner returns a RecordScanner reading from r, which starts
+// a new record at every line matching start.
+func NewRecordScanner(r io.Reader, start *Regexp) *RecordScanner {
+	return &RecordScanner{
+		lines: bufio.NewScanner(r),
+		m:     start.NewMatcher(),
+	}
+}
+
+// Buffer sets the initial line buffer and the maximum line length,
+// as bufio.Scanner.Buffer does. It must be called before Scan.
+func (s *RecordScanner) Buffer(buf []byte, max int) {
+	s.lines.Buffer(buf, max)
+}
+
+// SetMaxRecordSize bounds the memory used for a single record. A record
+// which grows beyond n bytes is returned in pieces of at most n bytes,
+// split at line boundaries; a single line is never split. Zero, the
+// default, means no limit.
+func (s *RecordScanner) SetMaxRecordSize(n int) {
+	s.maxBytes = n
+}
+
+// Scan advances to the next record, which is then available through
+// Record. It returns false at the end of the input or on an error.
+func (s *RecordScanner) Scan() bool {
+	for !s.done {
+		if !s.lines.Scan() {
+			s.done = true
+			break
+		}
+		line := s.lines.Bytes()
+		isStart := s.m.Match(line, 0)
+		full := s.maxBytes > 0 && len(s.pending)+1+len(line) > s.maxBytes
+		if s.started && (isStart || full) {
+			s.emit()
+			s.add(line)
+			return true
+		}
+		s.add(line)
+	}
+	if s.started {
+		s.emit()
+		return true
+	}
+	s.record = nil
+	return false
+}
+
+func (s *RecordScanner) add(line []byte) {
+	if s.started {
+		s.pending = append(s.pending, '\n')
+	}
+	s.pending = append(s.pending, line...)
+	s.started = true
+}
+
+func (s *RecordScanner) emit() {
+	s.record = append(s.record[:0], s.pending...)
+	s.pending = s.pending[:0]
+	s.started = false
+}
+
+// Record returns the most recent record found by Scan, with its lines
+// joined by newlines. The underlying array may be overwritten by
+// subsequent calls to Scan.
+func (s *RecordScanner) Record() []byte {
+	return s.record
+}
+
+// Text returns the most recent record as a string.
+func (s *RecordScanner) Text() string {
+	return string(s.record)
+}
+
+// Err returns the first error encountered while reading, if any.
+func (s *RecordScanner) Err() error {
+	return s.lines.Err()
+}
+
+// Free releases the underlying C resources.
+func (s *RecordScanner) Free() {
+	s.m.Free()
+}
@@ -0,0 +1,51 @@
+package pcre2
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func scanRecords(s *RecordScanner) (records []string) {
+	for s.Scan() {
+		records = append(records, s.Text())
+	}
+	return
+}
+
+func TestRecordScanner(t *testing.T) {
+	input := "preamble\n" +
+		"2020-01-01 first\n" +
+		"  at foo\n" +
+		"  at bar\n" +
+		"2020-01-02 second\n" +
+		"2020-01-03 third\n" +
+		"  at baz\n"
+	start := MustCompile(`^\d{4}-\d\d-\d\d `, 0)
+
+	s := NewRecordScanner(bytes.NewBufferString(input), start)
+	defer s.Free()
+	assert.Equal(t, []string{
+		"preamble",
+		"2020-01-01 first\n  at foo\n  at bar",
+		"2020-01-02 second",
+		"2020-01-03 third\n  at baz",
+	}, scanRecords(s))
+	assert.NoError(t, s.Err())
+	assert.False(t, s.Scan())
+
+	s = NewRecordScanner(bytes.NewBufferString(input), start)
+	s.SetMaxRecordSize(20)
+	assert.Equal(t, []string{
+		"preamble",
+		"2020-01-01 first",
+		"  at foo\n  at bar",
+		"2020-01-02 second",
+		"2020-01-03 third",
+		"  at baz",
+	}, scanRecords(s))
+
+	s = NewRecordScanner(bytes.NewBufferString(""), start)
+	assert.Nil(t, scanRecords(s))
+}
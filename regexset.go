@@ -0,0 +1,166 @@
+package pcre2
+
+import (
+	"fmt"
+	"sync"
+)
+
+// RegexSet is a set of compiled patterns, like regex::RegexSet in Rust,
+// which answers which of them match a subject. The set only reports
+// whether each pattern matches, not where, so every pattern stops at its
+// first match, and patterns which need a longer subject are skipped
+// without calling PCRE2. A RegexSet is safe for concurrent use.
+type RegexSet struct {
+	regexps    []*Regexp
+	minLengths []int // MinSubjectLength of each pattern
+	// idle holds slices of one Matcher per pattern which are not in
+	// use, so that the match data is reused across calls. Free frees
+	// them.
+	mu   sync.Mutex
+	idle [][]*Matcher
+}
+
+// NewRegexSet compiles all patterns with the given flags into a
+// RegexSet. If one of them fails, the error names it.
+func NewRegexSet(patterns []string, flags uint32) (*RegexSet, error) {
+	s := &RegexSet{
+		regexps:    make([]*Regexp, 0, len(patterns)),
+		minLengths: make([]int, 0, len(patterns)),
+	}
+	for i, pattern := range patterns {
+		re, err := Compile(pattern, flags)
+		if err != nil {
+			s.Free()
+			return nil, fmt.Errorf("pattern %d (%q): %w", i, pattern, err)
+		}
+		s.regexps = append(s.regexps, re)
+		s.minLengths = append(s.minLengths, re.MinSubjectLength())
+	}
+	return s, nil
+}
+
+// JITCompile JIT compiles all patterns of the set, see Regexp.JITCompile.
+func (s *RegexSet) JITCompile(flags uint32) error {
+	for i, re := range s.regexps {
+		if err := re.JITCompile(flags); err != nil {
+			return fmt.Errorf("pattern %d (%q): %w", i, re.Pattern, err)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of patterns in the set.
+func (s *RegexSet) Len() int {
+	return len(s.regexps)
+}
+
+// Patterns returns the patterns of the set, in the order they were
+// given to NewRegexSet.
+func (s *RegexSet) Patterns() []string {
+	patterns := make([]string, len(s.regexps))
+	for i, re := range s.regexps {
+		patterns[i] = re.Pattern
+	}
+	return patterns
+}
+
+// IsMatch reports whether any pattern of the set matches the subject.
+// It stops at the first pattern which matches.
+func (s *RegexSet) IsMatch(subject []byte) bool {
+	return setMatch(s, subject, nil)
+}
+
+// IsMatchString is like IsMatch, but with a string subject.
+func (s *RegexSet) IsMatchString(subject string) bool {
+	return setMatch(s, subject, nil)
+}
+
+// WhichMatch returns the indexes of the patterns which match the
+// subject, in increasing order, or nil if none does. A pattern whose
+// match fails with an error, e.g. because a limit is hit, is reported
+// as not matching.
+func (s *RegexSet) WhichMatch(subject []byte) []int {
+	var which []int
+	setMatch(s, subject, &which)
+	return which
+}
+
+// WhichMatchString is like WhichMatch, but with a string subject.
+func (s *RegexSet) WhichMatchString(subject string) []int {
+	var which []int
+	setMatch(s, subject, &which)
+	return which
+}
+
+// setMatch matches the patterns of the set against the subject. If which
+// is nil, it returns at the first pattern which matches; otherwise it
+// appends the indexes of all matching patterns to it.
+func setMatch[S subject](s *RegexSet, subject S, which *[]int) bool {
+	ms := s.matchers()
+	defer s.release(ms)
+
+	found := false
+	for i, re := range s.regexps {
+		if len(subject) < s.minLengths[i] {
+			continue
+		}
+		m := ms[i]
+		if m == nil {
+			m = re.NewMatcher()
+			ms[i] = m
+		}
+		rc := execAt(m, subject, 0, 0)
+		// Do not keep the subject alive in the pool.
+		m.subjects, m.subjectb = "", nil
+		if rc < 0 {
+			continue
+		}
+		found = true
+		if which == nil {
+			return true
+		}
+		*which = append(*which, i)
+	}
+	return found
+}
+
+// matchers takes an idle slice of matchers, or creates one. Its
+// matchers are created on first use.
+func (s *RegexSet) matchers() []*Matcher {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n := len(s.idle); n > 0 {
+		ms := s.idle[n-1]
+		s.idle = s.idle[:n-1]
+		return ms
+	}
+	return make([]*Matcher, len(s.regexps))
+}
+
+func (s *RegexSet) release(ms []*Matcher) {
+	s.mu.Lock()
+	s.idle = append(s.idle, ms)
+	s.mu.Unlock()
+}
+
+// Free releases the underlying C resources of all patterns and their
+// matchers. The set must no longer be in use.
+func (s *RegexSet) Free() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	for _, ms := range s.idle {
+		for _, m := range ms {
+			if m != nil {
+				m.Free()
+			}
+		}
+	}
+	s.idle = nil
+	s.mu.Unlock()
+	for _, re := range s.regexps {
+		re.Free()
+	}
+	return nil
+}
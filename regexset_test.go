@@ -0,0 +1,66 @@
+package pcre2
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegexSet(t *testing.T) {
+	set, err := NewRegexSet([]string{`\w+`, `\d+`, `[a-z]+@[a-z]+\.com`, `^$`, `foo.{10}`}, 0)
+	assert.NoError(t, err)
+	defer set.Free()
+
+	assert.Equal(t, 5, set.Len())
+	assert.Equal(t, `\d+`, set.Patterns()[1])
+
+	assert.Equal(t, []int{0, 1}, set.WhichMatch([]byte("abc 123")))
+	assert.Equal(t, []int{0, 2}, set.WhichMatchString("mail me@example.com"))
+	assert.Equal(t, []int{3}, set.WhichMatchString(""))
+	assert.Nil(t, set.WhichMatchString("!!"))
+	assert.Equal(t, []int{0, 4}, set.WhichMatchString("foo-abcdefghij"))
+
+	assert.True(t, set.IsMatch([]byte("x")))
+	assert.True(t, set.IsMatchString(""))
+	assert.False(t, set.IsMatchString("--"))
+}
+
+func TestRegexSetError(t *testing.T) {
+	_, err := NewRegexSet([]string{`a`, `(b`}, 0)
+	assert.ErrorContains(t, err, `pattern 1 ("(b")`)
+	var cerr *CompileError
+	assert.ErrorAs(t, err, &cerr)
+}
+
+func TestRegexSetFree(t *testing.T) {
+	SetAutoCleanup(false)
+	defer SetAutoCleanup(true)
+	before := Snapshot()
+	s, err := NewRegexSet([]string{`a`, `b`, `xyz`}, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []int{0, 1}, s.WhichMatchString("ab"))
+	assert.NoError(t, s.Free())
+	assert.Equal(t, before.LiveRegexps, Snapshot().LiveRegexps)
+	assert.Equal(t, before.LiveMatchers, Snapshot().LiveMatchers)
+}
+
+func TestRegexSetConcurrent(t *testing.T) {
+	set, err := NewRegexSet([]string{`a`, `b`, `c`}, 0)
+	assert.NoError(t, err)
+	defer set.Free()
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 1000 {
+				assert.Equal(t, []int{0, 2}, set.WhichMatchString("xaxc"))
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,71 @@
+package pcre2
+
+import "unicode/utf8"
+
+// PCRE2 reports all offsets in bytes, while Go code working with []rune
+// counts code points. The helpers below convert between the two for
+// UTF-8 subjects. Invalid bytes count as one rune each, which matches the
+// conversion of a string to []rune.
+
+// RuneIndex converts a byte offset in subject to the number of runes
+// before it. The offset should be at a rune boundary, as match offsets
+// of UTF patterns are. It panics if offset is out of range.
+func RuneIndex[S []byte | string](subject S, offset int) int {
+	return runeCount(subject[:offset])
+}
+
+// ByteIndex converts a rune index in subject to a byte offset, so that
+// ByteIndex(s, RuneIndex(s, off)) == off for offsets at rune boundaries.
+// It returns -1 if subject has fewer than index runes.
+func ByteIndex[S []byte | string](subject S, index int) int {
+	offset := 0
+	for ; index > 0; index-- {
+		if offset >= len(subject) {
+			return -1
+		}
+		_, size := decodeRune(subject[offset:])
+		offset += size
+	}
+	return offset
+}
+
+func runeCount[S []byte | string](s S) int {
+	if b, ok := any(s).([]byte); ok {
+		return utf8.RuneCount(b)
+	}
+	return utf8.RuneCountInString(string(s))
+}
+
+func decodeRune[S []byte | string](s S) (rune, int) {
+	if b, ok := any(s).([]byte); ok {
+		return utf8.DecodeRune(b)
+	}
+	return utf8.DecodeRuneInString(string(s))
+}
+
+// GroupRuneIndices is like GroupIndices, but returns the positions as
+// rune indices into the subject, e.g. for slicing []rune(subject).
+// Capture groups which are not present return a nil slice.
+func (m *Matcher) GroupRuneIndices(group int) []int {
+	loc := m.GroupIndices(group)
+	if loc == nil {
+		return nil
+	}
+	if m.subjectb != nil {
+		return runeIndices(m.subjectb, loc)
+	}
+	return runeIndices(m.subjects, loc)
+}
+
+func runeIndices[S []byte | string](subject S, loc []int) []int {
+	start := runeCount(subject[:loc[0]])
+	return []int{start, start + runeCount(subject[loc[0]:loc[1]])}
+}
+
+// RuneIndex is like Index, but returns rune indices into the subject.
+func (m *Matcher) RuneIndex() []int {
+	if !m.matches {
+		return nil
+	}
+	return m.GroupRuneIndices(0)
+}
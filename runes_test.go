@@ -0,0 +1,36 @@
+package pcre2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuneIndex(t *testing.T) {
+	s := "aä€x"
+	assert.Equal(t, 0, RuneIndex(s, 0))
+	assert.Equal(t, 2, RuneIndex(s, 3))
+	assert.Equal(t, 3, RuneIndex([]byte(s), 6))
+	assert.Equal(t, 4, RuneIndex(s, len(s)))
+	assert.Equal(t, 6, ByteIndex(s, 3))
+	assert.Equal(t, 7, ByteIndex([]byte(s), 4))
+	assert.Equal(t, -1, ByteIndex(s, 5))
+	// Invalid bytes count as one rune each, like []rune(s).
+	assert.Equal(t, 2, RuneIndex("\xff\xfe", 2))
+}
+
+func TestGroupRuneIndices(t *testing.T) {
+	re := MustCompile(`(€+)(x)?`, UTF)
+	subject := "äöü€€ y"
+	m := re.MatcherString(subject, 0)
+	assert.Equal(t, []int{6, 12}, m.Index())
+	assert.Equal(t, []int{3, 5}, m.RuneIndex())
+	loc := m.GroupRuneIndices(1)
+	assert.Equal(t, "€€", string([]rune(subject)[loc[0]:loc[1]]))
+	assert.Nil(t, m.GroupRuneIndices(2))
+
+	m.Match([]byte("€"), 0)
+	assert.Equal(t, []int{0, 1}, m.GroupRuneIndices(1))
+	m.MatchString("none", 0)
+	assert.Nil(t, m.RuneIndex())
+}
@@ -0,0 +1,260 @@
+package pcre2
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SafeLimits are the limits applied by SafeCompile and the matches of a
+// SafeRegexp. Zero values leave the corresponding limit unset.
+type SafeLimits struct {
+	MaxPatternLength int           // in bytes
+	ParensNestLimit  uint32        // depth of nested parentheses
+	MatchLimit       uint32        // see MatchContext.SetMatchLimit
+	DepthLimit       uint32        // see MatchContext.SetDepthLimit
+	HeapLimit        uint32        // in KiB, see MatchContext.SetHeapLimit
+	MaxSubjectLength int           // in bytes
+	Timeout          time.Duration // wall-clock bound of a single match
+}
+
+// DefaultSafeLimits are conservative limits for patterns and subjects
+// supplied by untrusted users.
+var DefaultSafeLimits = SafeLimits{
+	MaxPatternLength: 4096,
+	ParensNestLimit:  64,
+	MatchLimit:       1000000,
+	DepthLimit:       10000,
+	HeapLimit:        16 * 1024,
+	MaxSubjectLength: 1 << 20,
+	Timeout:          100 * time.Millisecond,
+}
+
+// LimitError is returned by the functions of the hardened mode when a
+// limit trips. Err holds the underlying error.
+type LimitError struct {
+	Limit   string // which limit was hit, e.g. "match limit"
+	Value   int64  // the configured value of the limit
+	Pattern string
+	Err     error
+}
+
+// Error converts the limit error to a string.
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("pcre2: %s (%d) exceeded for pattern %q: %v",
+		e.Limit, e.Value, e.Pattern, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// SafeRegexp is a compiled pattern whose matches always run with the
+// resource limits it was compiled with. It is safe for concurrent use.
+type SafeRegexp struct {
+	re     *Regexp
+	limits SafeLimits
+	mctx   *MatchContext
+	mu     sync.Mutex
+	idle   []*Matcher // matchers not in use, freed by Free
+}
+
+// SafeCompile compiles the pattern with DefaultSafeLimits. It is meant
+// for systems executing patterns supplied by untrusted users.
+func SafeCompile(pattern string, flags uint32) (*SafeRegexp, error) {
+	return SafeCompileWithLimits(pattern, flags, DefaultSafeLimits)
+}
+
+// SafeCompileWithLimits compiles the pattern with the given limits. If a
+// compile-time limit trips, the error is a *LimitError wrapping the
+// *CompileError.
+func SafeCompileWithLimits(pattern string, flags uint32, limits SafeLimits) (*SafeRegexp, error) {
+	re, err := compileWithOptions(pattern, CompileOptions{
+		Flags:            flags,
+		MaxPatternLength: limits.MaxPatternLength,
+		ParensNestLimit:  limits.ParensNestLimit,
+	}, false)
+	if err != nil {
+		return nil, compileLimitError(err, pattern, limits.MaxPatternLength, limits.ParensNestLimit)
+	}
+	re.SetMaxSubjectLength(limits.MaxSubjectLength)
+	s := &SafeRegexp{re: re, limits: limits, mctx: NewMatchContext()}
+	if limits.MatchLimit != 0 {
+		s.mctx.SetMatchLimit(limits.MatchLimit)
+	}
+	if limits.DepthLimit != 0 {
+		s.mctx.SetDepthLimit(limits.DepthLimit)
+	}
+	if limits.HeapLimit != 0 {
+		s.mctx.SetHeapLimit(limits.HeapLimit)
+	}
+	return s, nil
+}
+
+// compileLimitError returns a *LimitError wrapping err if it is the
+// *CompileError of a tripped compile-time limit, and err otherwise.
+func compileLimitError(err error, pattern string, maxPatternLength int, parensNestLimit uint32) error {
+	if cerr, ok := err.(*CompileError); ok {
+		switch cerr.ErrorNum {
+		case ERROR_PATTERN_STRING_TOO_LONG:
+			return &LimitError{"pattern length", int64(maxPatternLength), pattern, err}
+		case ERROR_PARENTHESES_NEST_TOO_DEEP:
+			return &LimitError{"parentheses nesting", int64(parensNestLimit), pattern, err}
+		}
+	}
+	return err
+}
+
+// Limits are the limits applied by ValidatePattern. Zero values leave
+// the corresponding limit unset.
+type Limits struct {
+	MaxPatternLength int    // in bytes
+	ParensNestLimit  uint32 // depth of nested parentheses
+	MaxCompiledSize  int    // size of the compiled pattern in bytes, see Size
+	MaxGroups        int    // number of capture groups
+}
+
+// DefaultLimits are conservative limits for patterns supplied by
+// untrusted users, matching DefaultSafeLimits.
+var DefaultLimits = Limits{
+	MaxPatternLength: DefaultSafeLimits.MaxPatternLength,
+	ParensNestLimit:  DefaultSafeLimits.ParensNestLimit,
+	MaxCompiledSize:  64 * 1024,
+	MaxGroups:        256,
+}
+
+// ValidatePattern checks that the pattern compiles with the flags within
+// the limits, without keeping the compiled code, for servers which accept
+// patterns from users but only need to validate them. It returns nil if
+// the pattern is valid, a *LimitError wrapping the cause if a limit
+// trips, or the *CompileError, which holds the offset of the error in
+// the pattern.
+func ValidatePattern(pattern string, flags uint32, limits Limits) error {
+	re, err := compileWithOptions(pattern, CompileOptions{
+		Flags:            flags,
+		MaxPatternLength: limits.MaxPatternLength,
+		ParensNestLimit:  limits.ParensNestLimit,
+	}, false)
+	if err != nil {
+		return compileLimitError(err, pattern, limits.MaxPatternLength, limits.ParensNestLimit)
+	}
+	defer re.Free()
+	if size := re.Size(); limits.MaxCompiledSize != 0 && size > limits.MaxCompiledSize {
+		return &LimitError{"compiled size", int64(limits.MaxCompiledSize), pattern,
+			fmt.Errorf("pattern compiles to %d bytes", size)}
+	}
+	if groups := re.Groups(); limits.MaxGroups != 0 && groups > limits.MaxGroups {
+		return &LimitError{"capture groups", int64(limits.MaxGroups), pattern,
+			fmt.Errorf("pattern has %d capture groups", groups)}
+	}
+	return nil
+}
+
+// Regexp returns the underlying compiled pattern.
+func (s *SafeRegexp) Regexp() *Regexp {
+	return s.re
+}
+
+// Match reports whether the subject matches. If the match could not be
+// completed, the error is a *LimitError for tripped limits, or a
+// *MatchError.
+func (s *SafeRegexp) Match(subject []byte, flags uint32) (bool, error) {
+	if s == nil || s.re == nil || s.re.ptr == nil {
+		return false, ErrInvalidRegexp
+	}
+	m := s.matcher()
+	defer s.release(m)
+	return s.result(m, execOptions(m, subject, s.options(flags)))
+}
+
+// MatchString is like Match, but with a string subject.
+func (s *SafeRegexp) MatchString(subject string, flags uint32) (bool, error) {
+	if s == nil || s.re == nil || s.re.ptr == nil {
+		return false, ErrInvalidRegexp
+	}
+	m := s.matcher()
+	defer s.release(m)
+	return s.result(m, execOptions(m, subject, s.options(flags)))
+}
+
+func (s *SafeRegexp) matcher() *Matcher {
+	s.mu.Lock()
+	if n := len(s.idle); n > 0 {
+		m := s.idle[n-1]
+		s.idle = s.idle[:n-1]
+		s.mu.Unlock()
+		return m
+	}
+	s.mu.Unlock()
+	m := s.re.NewMatcher()
+	m.SetMatchContext(s.mctx)
+	return m
+}
+
+// release drops the reference of m to the subject and makes it idle
+// again.
+func (s *SafeRegexp) release(m *Matcher) {
+	m.subjects, m.subjectb = "", nil
+	s.mu.Lock()
+	s.idle = append(s.idle, m)
+	s.mu.Unlock()
+}
+
+func (s *SafeRegexp) options(flags uint32) MatchOptions {
+	return MatchOptions{Flags: flags, Timeout: s.limits.Timeout}
+}
+
+// result converts the return code of a match.
+func (s *SafeRegexp) result(m *Matcher, rc int) (bool, error) {
+	if m.record(rc) {
+		return true, nil
+	}
+	var limit string
+	var value int64
+	switch rc {
+	case ERROR_NOMATCH:
+		return false, nil
+	case ERROR_MATCHLIMIT:
+		limit, value = "match limit", int64(s.limits.MatchLimit)
+	case ERROR_DEPTHLIMIT:
+		limit, value = "depth limit", int64(s.limits.DepthLimit)
+	case ERROR_HEAPLIMIT:
+		limit, value = "heap limit", int64(s.limits.HeapLimit)
+	case ERROR_SUBJECT_TOO_LONG:
+		limit, value = "subject length", int64(s.limits.MaxSubjectLength)
+	case ERROR_TIMEOUT:
+		limit, value = "timeout", int64(s.limits.Timeout)
+	default:
+		return false, m.GetError()
+	}
+	return false, &LimitError{limit, value, s.re.Pattern, m.GetError()}
+}
+
+// Free releases the underlying C resources, including those of the
+// matchers and the match context of s. It must not be called while s is
+// in use.
+func (s *SafeRegexp) Free() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	for _, m := range s.idle {
+		m.Free()
+	}
+	s.idle = nil
+	s.mu.Unlock()
+	s.mctx.Free()
+	return s.re.Free()
+}
+
+// SafeMatch compiles the pattern with DefaultSafeLimits and matches the
+// subject against it, for one-off checks of untrusted input.
+func SafeMatch(pattern string, subject []byte, flags uint32) (bool, error) {
+	s, err := SafeCompile(pattern, flags)
+	if err != nil {
+		return false, err
+	}
+	defer s.Free()
+	return s.Match(subject, 0)
+}
@@ -0,0 +1,117 @@
+package pcre2
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSafeCompile(t *testing.T) {
+	s, err := SafeCompile(`^\w+@\w+$`, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer s.Free()
+	ok, err := s.MatchString("user@example", 0)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	ok, err = s.Match([]byte("nope"), 0)
+	assert.False(t, ok)
+	assert.NoError(t, err)
+
+	_, err = SafeCompile(strings.Repeat("(", 100)+strings.Repeat(")", 100), 0)
+	var lerr *LimitError
+	if assert.True(t, errors.As(err, &lerr)) {
+		assert.Equal(t, "parentheses nesting", lerr.Limit)
+		assert.IsType(t, &CompileError{}, lerr.Err)
+	}
+	_, err = SafeCompile(strings.Repeat("a", 5000), 0)
+	if assert.True(t, errors.As(err, &lerr)) {
+		assert.Equal(t, "pattern length", lerr.Limit)
+	}
+	_, err = SafeCompile(`(`, 0)
+	assert.IsType(t, &CompileError{}, err)
+}
+
+func TestSafeRegexpFree(t *testing.T) {
+	SetAutoCleanup(false)
+	defer SetAutoCleanup(true)
+	before := Snapshot()
+	s, err := SafeCompile(`a+`, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ok, err := s.MatchString("baa", 0)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, "", s.idle[0].subjects, "subject is not kept")
+	assert.NoError(t, s.Free())
+	assert.Equal(t, before.LiveRegexps, Snapshot().LiveRegexps)
+	assert.Equal(t, before.LiveMatchers, Snapshot().LiveMatchers)
+	assert.Nil(t, s.mctx.ptr)
+}
+
+func TestSafeMatchLimits(t *testing.T) {
+	limits := DefaultSafeLimits
+	limits.Timeout = 0
+	limits.MaxSubjectLength = 100
+	s, err := SafeCompileWithLimits(`^(a+)+$`, 0, limits)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer s.Free()
+
+	var lerr *LimitError
+	_, err = s.MatchString(strings.Repeat("a", 40)+"b", 0)
+	if assert.True(t, errors.As(err, &lerr)) {
+		assert.Equal(t, "match limit", lerr.Limit)
+		assert.Equal(t, int64(limits.MatchLimit), lerr.Value)
+		assert.IsType(t, &MatchError{}, lerr.Err)
+	}
+	_, err = s.MatchString(strings.Repeat("a", 101), 0)
+	if assert.True(t, errors.As(err, &lerr)) {
+		assert.Equal(t, "subject length", lerr.Limit)
+		assert.Equal(t, ErrSubjectTooLong, lerr.Err)
+	}
+
+	ok, err := SafeMatch(`b+`, []byte("abbc"), 0)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+}
+
+func TestValidatePattern(t *testing.T) {
+	before := Snapshot().LiveRegexps
+	assert.NoError(t, ValidatePattern(`^(\w+)@(\w+)$`, 0, DefaultLimits))
+	assert.NoError(t, ValidatePattern(strings.Repeat("a", 5000), 0, Limits{}))
+	assert.Equal(t, before, Snapshot().LiveRegexps, "compiled code is not kept")
+
+	err := ValidatePattern(`ab(c`, 0, DefaultLimits)
+	var cerr *CompileError
+	if assert.True(t, errors.As(err, &cerr)) {
+		assert.Equal(t, 4, cerr.Offset)
+		assert.Equal(t, ERROR_MISSING_CLOSING_PARENTHESIS, cerr.ErrorNum)
+	}
+
+	var lerr *LimitError
+	err = ValidatePattern(strings.Repeat("a", 5000), 0, DefaultLimits)
+	if assert.True(t, errors.As(err, &lerr)) {
+		assert.Equal(t, "pattern length", lerr.Limit)
+		assert.ErrorAs(t, err, &cerr)
+	}
+	err = ValidatePattern(strings.Repeat("(", 65)+strings.Repeat(")", 65), 0, DefaultLimits)
+	if assert.True(t, errors.As(err, &lerr)) {
+		assert.Equal(t, "parentheses nesting", lerr.Limit)
+	}
+	err = ValidatePattern(`(?:ab|cd){100}`, 0, Limits{MaxCompiledSize: 1000})
+	if assert.True(t, errors.As(err, &lerr)) {
+		assert.Equal(t, "compiled size", lerr.Limit)
+		assert.Equal(t, int64(1000), lerr.Value)
+	}
+	err = ValidatePattern(strings.Repeat("(a)", 3), 0, Limits{MaxGroups: 2})
+	if assert.True(t, errors.As(err, &lerr)) {
+		assert.Equal(t, "capture groups", lerr.Limit)
+		assert.EqualError(t, lerr.Err, "pattern has 3 capture groups")
+	}
+}
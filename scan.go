@@ -0,0 +1,94 @@
+package pcre2
+
+import (
+	"cmp"
+	"io"
+	"slices"
+)
+
+// ScanFunc is called by RegexSet.Scan for every match, with the index of
+// the pattern and the input offsets of the match. It returns false to
+// stop the scan.
+type ScanFunc func(id int, from, to int64) bool
+
+// scanMatch is a match found by Scan, before it is reported.
+type scanMatch struct {
+	id       int
+	from, to int64
+}
+
+// Scan reads the input until its end and calls fn for every match of
+// every pattern of the set, like the streaming scan of Hyperscan, for
+// scanning traffic or files for a set of signatures. Each pattern finds
+// successive non-overlapping matches, as with FindAllIndex, and matches
+// may span the blocks in which the input is read. Only as much input is
+// kept as a match of each pattern can still span.
+//
+// Matches are reported as soon as they are certain, which is after the
+// block of input in which they end has been read. The matches found in
+// a block are reported in order of their end offsets, and for equal
+// ends in order of the patterns; a match which needed later input to
+// be certain may be reported after a match of another pattern which
+// ends later.
+//
+// Scan returns the first read error other than io.EOF, without reporting
+// matches in the data read along with it, or the error of a match which
+// failed, e.g. because a limit was hit. It returns nil if fn stopped the
+// scan.
+func (s *RegexSet) Scan(r io.Reader, flags uint32, fn ScanFunc) error {
+	searchers := make([]*streamSearcher, len(s.regexps))
+	for i, re := range s.regexps {
+		searchers[i] = newStreamSearcher(re, flags, nil)
+	}
+	defer func() {
+		for _, ss := range searchers {
+			ss.free()
+		}
+	}()
+
+	chunk := make([]byte, streamChunk)
+	done := make([]bool, len(searchers))
+	var found []scanMatch
+	for {
+		n, readErr := r.Read(chunk)
+		if readErr != nil && readErr != io.EOF {
+			// The input is truncated, so the matches of the block, e.g.
+			// of $, are not certain.
+			return readErr
+		}
+		eof := readErr == io.EOF
+		found = found[:0]
+		for i, ss := range searchers {
+			if done[i] {
+				continue
+			}
+			ss.buf = append(ss.buf, chunk[:n]...)
+			ss.eof = eof
+			for searching := true; searching; {
+				from, to, result := ss.search()
+				switch result {
+				case streamMatch:
+					found = append(found, scanMatch{i, from, to})
+				case streamMore:
+					searching = false
+				case streamDone:
+					if err := ss.err(); err != nil {
+						return err
+					}
+					done[i], searching = true, false
+				}
+			}
+		}
+		slices.SortStableFunc(found, func(a, b scanMatch) int {
+			return cmp.Compare(a.to, b.to)
+		})
+		for _, match := range found {
+			if !fn(match.id, match.from, match.to) {
+				return nil
+			}
+		}
+		if eof {
+			return nil
+		}
+	}
+}
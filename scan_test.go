@@ -0,0 +1,114 @@
+package pcre2
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegexSetScan(t *testing.T) {
+	set, err := NewRegexSet([]string{`password=\w+`, `\d{4}-\d{4}`, `secret`}, 0)
+	assert.NoError(t, err)
+	defer set.Free()
+
+	input := "user=bob password=hunter2 card 1234-5678 secret\n" +
+		strings.Repeat("x", 10000) + " password=abc 9999-0000"
+	var want []scanMatch
+	for i, re := range set.regexps {
+		for _, loc := range re.FindAllStringIndex(input, 0, -1) {
+			want = append(want, scanMatch{i, int64(loc[0]), int64(loc[1])})
+		}
+	}
+
+	for _, r := range []struct {
+		name   string
+		reader func() io.Reader
+	}{
+		{"whole", func() io.Reader { return strings.NewReader(input) }},
+		{"bytes", func() io.Reader { return iotest.OneByteReader(strings.NewReader(input)) }},
+		{"halves", func() io.Reader { return iotest.HalfReader(strings.NewReader(input)) }},
+	} {
+		t.Run(r.name, func(t *testing.T) {
+			var got []scanMatch
+			err := set.Scan(r.reader(), 0, func(id int, from, to int64) bool {
+				got = append(got, scanMatch{id, from, to})
+				return true
+			})
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, want, got)
+		})
+	}
+}
+
+func TestRegexSetScanStop(t *testing.T) {
+	set, err := NewRegexSet([]string{`a`, `b`}, 0)
+	assert.NoError(t, err)
+	defer set.Free()
+
+	var got []scanMatch
+	err = set.Scan(strings.NewReader("ab ba ab"), 0, func(id int, from, to int64) bool {
+		got = append(got, scanMatch{id, from, to})
+		return len(got) < 3
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []scanMatch{{0, 0, 1}, {1, 1, 2}, {1, 3, 4}}, got)
+}
+
+func TestRegexSetScanError(t *testing.T) {
+	set, err := NewRegexSet([]string{`a`}, 0)
+	assert.NoError(t, err)
+	defer set.Free()
+
+	readErr := errors.New("broken")
+	var n int
+	err = set.Scan(io.MultiReader(strings.NewReader("b"), iotest.ErrReader(readErr)), 0, func(int, int64, int64) bool {
+		n++
+		return true
+	})
+	assert.Equal(t, readErr, err)
+	assert.Zero(t, n)
+}
+
+func TestRegexSetScanTruncated(t *testing.T) {
+	set, err := NewRegexSet([]string{`a$`}, 0)
+	assert.NoError(t, err)
+	defer set.Free()
+
+	// A read error is not the end of the input, so $ does not match.
+	readErr := errors.New("broken")
+	r := iotest.DataErrReader(io.MultiReader(strings.NewReader("a"), iotest.ErrReader(readErr)))
+	var n int
+	err = set.Scan(r, 0, func(int, int64, int64) bool {
+		n++
+		return true
+	})
+	assert.Equal(t, readErr, err)
+	assert.Zero(t, n)
+}
+
+func TestRegexSetScanEmptyAndUTF(t *testing.T) {
+	set, err := NewRegexSet([]string{`(?m)^`, `é`, ``}, UTF)
+	assert.NoError(t, err)
+	defer set.Free()
+
+	input := strings.Repeat("a", 4095) + "üxé\nab"
+	var want []scanMatch
+	for i, re := range set.regexps {
+		for _, loc := range re.FindAllStringIndex(input, 0, -1) {
+			want = append(want, scanMatch{i, int64(loc[0]), int64(loc[1])})
+		}
+	}
+	for _, r := range []io.Reader{strings.NewReader(input), iotest.OneByteReader(strings.NewReader(input))} {
+		var got []scanMatch
+		err := set.Scan(r, 0, func(id int, from, to int64) bool {
+			got = append(got, scanMatch{id, from, to})
+			return true
+		})
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, want, got)
+	}
+}
@@ -0,0 +1,48 @@
+package pcre2
+
+import "fmt"
+
+// BuildInfo describes the PCRE2 library the package is linked with.
+type BuildInfo struct {
+	Version       string // version of the linked library
+	HeaderVersion string // version of the headers the package was built with
+	Linkage       string // "shared" or "static" (pcre2_linkstatic, pcre2_vcpkg)
+	Unicode       bool
+	JIT           bool
+}
+
+// String returns a one-line summary, suitable for startup logs.
+func (b BuildInfo) String() string {
+	return fmt.Sprintf("PCRE2 %s (%s, headers %s, unicode %t, jit %t)",
+		b.Version, b.Linkage, b.HeaderVersion, b.Unicode, b.JIT)
+}
+
+// SelfCheck verifies that the linked library works, by compiling and
+// matching a pattern, and reports which library it is. It is meant to
+// be called at startup of statically linked programs, e.g. in scratch
+// or Alpine images, to fail early with a clear message. An error is
+// returned if the library is older than the headers, or the test match
+// fails.
+func SelfCheck() (BuildInfo, error) {
+	info := BuildInfo{
+		Version:       Version(),
+		HeaderVersion: fmt.Sprintf("%d.%02d", headerMajor, headerMinor),
+		Linkage:       linkage,
+		Unicode:       UnicodeSupported(),
+		JIT:           JITSupported(),
+	}
+	if !versionAtLeast(libraryMajor, libraryMinor, headerMajor, headerMinor) {
+		return info, fmt.Errorf("pcre2: library %s is older than headers %s", info.Version, info.HeaderVersion)
+	}
+	re, err := Compile(`^(\w+)-(\d+)$`, 0)
+	if err != nil {
+		return info, fmt.Errorf("pcre2: self-check: %w", err)
+	}
+	defer re.Free()
+	m := re.MatcherString("pcre-2", 0)
+	defer m.Free()
+	if !m.Matches() || m.GroupString(2) != "2" {
+		return info, fmt.Errorf("pcre2: self-check: test match failed: %v", m.GetError())
+	}
+	return info, nil
+}
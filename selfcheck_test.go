@@ -0,0 +1,15 @@
+package pcre2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelfCheck(t *testing.T) {
+	info, err := SelfCheck()
+	assert.NoError(t, err)
+	assert.Equal(t, Version(), info.Version)
+	assert.Equal(t, linkage, info.Linkage)
+	assert.Contains(t, info.String(), "PCRE2 "+info.Version)
+}
@@ -0,0 +1,110 @@
+package pcre2
+
+/*
+#define PCRE2_CODE_UNIT_WIDTH 8
+
+#include <pcre2.h>
+#include "./pcre2_fallback.h"
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// SerializeError is returned when compiled patterns cannot be serialized
+// or deserialized.
+type SerializeError struct {
+	ErrorNum int // the error number
+	Message  string
+}
+
+// Error converts a serialization error to a string.
+func (e *SerializeError) Error() string {
+	return fmt.Sprintf("Serialization failed: %s", e.Message)
+}
+
+func newSerializeError(rc C.int32_t) *SerializeError {
+	return &SerializeError{
+		ErrorNum: int(rc),
+		Message:  errorMessage(int(rc)),
+	}
+}
+
+// serialize encodes the compiled codes, along with the character tables
+// they use, into a single blob.
+func serialize(codes []*C.pcre2_code) ([]byte, error) {
+	if err := Require(FeatureSerialize); err != nil {
+		return nil, err
+	}
+	var bytes *C.uint8_t
+	var size C.PCRE2_SIZE
+	rc := C.pcre2_serialize_encode(&codes[0], C.int32_t(len(codes)), &bytes, &size, generalContext)
+	if rc < 0 {
+		return nil, newSerializeError(rc)
+	}
+	defer C.pcre2_serialize_free(bytes)
+	return C.GoBytes(unsafe.Pointer(bytes), C.int(size)), nil
+}
+
+// serializedHeaderSize is the size of the header of serialized data:
+// magic number, version, configuration and number of codes.
+const serializedHeaderSize = 16
+
+// deserialize decodes all compiled codes of a blob created by serialize.
+func deserialize(data []byte) ([]*C.pcre2_code, error) {
+	if err := Require(FeatureSerialize); err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errors.New("no serialized data")
+	}
+	if len(data) < serializedHeaderSize {
+		return nil, newSerializeError(ERROR_BADSERIALIZEDDATA)
+	}
+	bytes := (*C.uint8_t)(unsafe.Pointer(&data[0]))
+	n := C.pcre2_serialize_get_number_of_codes(bytes)
+	if n < 0 {
+		return nil, newSerializeError(n)
+	}
+	codes := make([]*C.pcre2_code, n)
+	if n == 0 {
+		return codes, nil
+	}
+	if rc := C.pcre2_serialize_decode(&codes[0], n, bytes, generalContext); rc < 0 {
+		return nil, newSerializeError(rc)
+	}
+	return codes, nil
+}
+
+// Serialize encodes the compiled pattern into a byte slice, from which
+// DeserializeRegexp can recreate it much faster than compiling. The data
+// can only be loaded by the same PCRE2 version on the same architecture.
+// It does not include the pattern text nor JIT compiled code.
+func (re *Regexp) Serialize() ([]byte, error) {
+	rptr, err := re.validRegexpPtr()
+	if err != nil {
+		return nil, err
+	}
+	return serialize([]*C.pcre2_code{rptr})
+}
+
+// DeserializeRegexp recreates a compiled pattern from data created by
+// Serialize. The data is trusted: PCRE2 only checks its header, so it
+// must not come from untrusted sources. The Pattern field of the result
+// is empty.
+func DeserializeRegexp(data []byte) (*Regexp, error) {
+	codes, err := deserialize(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(codes) != 1 {
+		for _, ptr := range codes {
+			C.pcre2_code_free(ptr)
+		}
+		return nil, fmt.Errorf("serialized data holds %d patterns, expected 1", len(codes))
+	}
+	return newRegexp("", codes[0]), nil
+}
@@ -0,0 +1,44 @@
+package pcre2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSerialize(t *testing.T) {
+	re := MustCompile(`^(\w+)@(\w+)$`, CASELESS)
+	data, err := re.Serialize()
+	if !assert.NoError(t, err) {
+		return
+	}
+	re.Free()
+
+	re, err = DeserializeRegexp(data)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer re.Free()
+	assert.Equal(t, 2, re.Groups())
+	m := re.MatcherString("User@Example", 0)
+	assert.True(t, m.Matches())
+	assert.Equal(t, "Example", m.GroupString(2))
+
+	_, err = re.Serialize()
+	assert.NoError(t, err)
+}
+
+func TestDeserializeBadData(t *testing.T) {
+	_, err := DeserializeRegexp(nil)
+	assert.Error(t, err)
+	_, err = DeserializeRegexp([]byte("short"))
+	if assert.IsType(t, &SerializeError{}, err) {
+		assert.Equal(t, ERROR_BADSERIALIZEDDATA, err.(*SerializeError).ErrorNum)
+	}
+	_, err = DeserializeRegexp(make([]byte, 64))
+	if assert.IsType(t, &SerializeError{}, err) {
+		assert.Equal(t, ERROR_BADMAGIC, err.(*SerializeError).ErrorNum)
+	}
+	_, err = (&Regexp{}).Serialize()
+	assert.Equal(t, ErrInvalidRegexp, err)
+}
@@ -0,0 +1,40 @@
+package pcre2
+
+import (
+	"sync/atomic"
+)
+
+// Counters backing Snapshot. They are updated whenever a compiled
+// pattern or match data block is allocated or released.
+var (
+	statLiveRegexps   atomic.Int64
+	statCompiledBytes atomic.Int64
+	statJITBytes      atomic.Int64
+	statLiveMatchData atomic.Int64
+)
+
+// Stats is a point-in-time view of the C resources held by this package.
+type Stats struct {
+	LiveRegexps   int64 // compiled patterns which have not been freed
+	CompiledBytes int64 // total size of all live compiled patterns
+	JITBytes      int64 // total size of the JIT code of all live patterns
+	LiveMatchers  int64 // matchers holding match data which has not been freed
+	CBytes        int64 // C memory held by PCRE2, see TotalCBytesAllocated
+	CacheHits     int64 // calls of CompileCached served from the cache
+	CacheMisses   int64 // calls of CompileCached which compiled the pattern
+}
+
+// Snapshot returns the current resource statistics of the package.
+// It is cheap enough to be scraped periodically by services which
+// manage large numbers of patterns.
+func Snapshot() Stats {
+	return Stats{
+		LiveRegexps:   statLiveRegexps.Load(),
+		CompiledBytes: statCompiledBytes.Load(),
+		JITBytes:      statJITBytes.Load(),
+		LiveMatchers:  statLiveMatchData.Load(),
+		CBytes:        TotalCBytesAllocated(),
+		CacheHits:     statCacheHits.Load(),
+		CacheMisses:   statCacheMisses.Load(),
+	}
+}
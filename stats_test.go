@@ -0,0 +1,57 @@
+package pcre2
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	re := MustCompile(`^(\w+)@(\w+)$`, 0)
+	m := re.MatcherString("user@example", 0)
+	s := Snapshot()
+	assert.True(t, s.LiveRegexps >= 1, "live regexps")
+	assert.True(t, s.CompiledBytes >= re.code.size, "compiled bytes")
+	assert.True(t, s.LiveMatchers >= 1, "live matchers")
+
+	if re.JITCompile(JIT_COMPLETE) == nil {
+		assert.True(t, Snapshot().JITBytes >= re.code.jitSize, "JIT bytes")
+		assert.NotZero(t, re.code.jitSize)
+	}
+
+	m.Free()
+	re.Free()
+	assert.Zero(t, re.ptr)
+}
+
+func TestCBytesAllocated(t *testing.T) {
+	// Let the cleanups of earlier tests finish, so that the counters
+	// only change because of this test.
+	waitFor(func() bool {
+		n := TotalCBytesAllocated()
+		runtime.GC()
+		time.Sleep(5 * time.Millisecond)
+		return n == TotalCBytesAllocated()
+	})
+	before := TotalCBytesAllocated()
+	regexps, matchData := LiveRegexps(), LiveMatchData()
+
+	re := MustCompile(`(\d+)-(\d+)`, 0)
+	afterCompile := TotalCBytesAllocated()
+	assert.True(t, afterCompile-before >= re.code.size, "compiled pattern counted")
+	assert.Equal(t, regexps+1, LiveRegexps())
+
+	m := re.MatcherString("12-34", 0)
+	assert.True(t, m.Matches())
+	assert.True(t, TotalCBytesAllocated() > afterCompile, "match data counted")
+	assert.Equal(t, matchData+1, LiveMatchData())
+	assert.True(t, Snapshot().CBytes > 0)
+
+	m.Free()
+	re.Free()
+	assert.Equal(t, before, TotalCBytesAllocated())
+	assert.Equal(t, regexps, LiveRegexps())
+	assert.Equal(t, matchData, LiveMatchData())
+}
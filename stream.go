@@ -0,0 +1,237 @@
+package pcre2
+
+import (
+	"io"
+	"slices"
+	"unicode/utf8"
+)
+
+// streamChunk is the number of bytes read from a stream at a time.
+const streamChunk = 4096
+
+// streamSearcher finds successive matches in input which arrives in
+// pieces. It matches with PARTIAL_HARD until the end of the input, so
+// that a match which may continue in the next piece is not cut short,
+// and keeps only the bytes from which a match can still start, plus the
+// context lookbehind assertions need.
+//
+// Input is either pulled with the read function by next, or pushed by
+// appending to buf and calling search until it needs more.
+type streamSearcher struct {
+	m     *Matcher
+	flags uint32
+	// read appends more input to the buffer and returns it, together
+	// with io.EOF or another error at the end of the input.
+	read func(buf []byte) ([]byte, error)
+	buf  []byte
+	base int64 // offset of buf[0] in the input
+	// offset in buf at which the next match can start; no match
+	// starts before it
+	start int
+	keep  int  // bytes of context kept before start
+	empty bool // the last match was empty and ended at start
+	eof   bool
+	utf   bool // the input is UTF-8, see complete
+	// If emit is set, the bytes from out up to the next match are
+	// passed to it as soon as it is certain that no match starts
+	// among them, before they are discarded.
+	emit    func([]byte)
+	out     int64
+	readErr error // error of read other than io.EOF
+}
+
+// Results of streamSearcher.search.
+const (
+	streamMatch = iota // a match was found
+	streamMore         // more input is needed
+	streamDone         // there are no more matches, or matching failed
+)
+
+func newStreamSearcher(re *Regexp, flags uint32, read func([]byte) ([]byte, error)) *streamSearcher {
+	// One character more than the lookbehind is needed by ^ in
+	// MULTILINE mode and \b.
+	keep := (re.MaxLookbehind() + 1) * utf8.UTFMax
+	return &streamSearcher{m: re.NewMatcher(), flags: flags, read: read, keep: keep,
+		utf: pcreOptions(re.ptr)&UTF != 0}
+}
+
+// runeReader returns a read function for a streamSearcher which reads
+// from a RuneReader. Errors other than io.EOF end the input, too.
+func runeReader(r io.RuneReader) func([]byte) ([]byte, error) {
+	return func(buf []byte) ([]byte, error) {
+		for n := len(buf) + streamChunk; len(buf) < n; {
+			c, _, err := r.ReadRune()
+			if err != nil {
+				return buf, err
+			}
+			buf = utf8.AppendRune(buf, c)
+		}
+		return buf, nil
+	}
+}
+
+// byteReader returns a read function for a streamSearcher which reads
+// from a Reader.
+func byteReader(r io.Reader) func([]byte) ([]byte, error) {
+	return func(buf []byte) ([]byte, error) {
+		buf = slices.Grow(buf, streamChunk)
+		n, err := r.Read(buf[len(buf) : len(buf)+streamChunk])
+		return buf[:len(buf)+n], err
+	}
+}
+
+// complete returns the length of the buffered input which can be
+// matched. In UTF mode, a character which is cut off at the end of the
+// buffer is held back until the rest of it arrives, as PCRE2 would
+// report it as invalid UTF-8.
+func (s *streamSearcher) complete() int {
+	n := len(s.buf)
+	if !s.utf || s.eof {
+		return n
+	}
+	for i := n - 1; i >= 0 && i > n-utf8.UTFMax; i-- {
+		if utf8.RuneStart(s.buf[i]) {
+			if !utf8.FullRune(s.buf[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return n
+}
+
+// search looks for the next match in the input buffered so far, and
+// returns its input offsets if it finds one.
+func (s *streamSearcher) search() (start, end int64, result int) {
+	flags := s.flags
+	if !s.eof {
+		flags |= PARTIAL_HARD
+	}
+	if s.empty {
+		flags |= NOTEMPTY_ATSTART
+	}
+	n := s.complete()
+	rc := s.m.ExecOptions(s.buf[:n], MatchOptions{Flags: flags, Offset: s.start})
+	s.m.record(rc)
+	switch {
+	case rc >= 0:
+		from, to, _ := s.m.mData.offsets(0)
+		s.start, s.empty = int(to), from == to
+		start, end = s.base+from, s.base+to
+		s.discard()
+		return start, end, streamMatch
+	case rc == ERROR_PARTIAL:
+		// No match starts before the partial one.
+		from, _, _ := s.m.mData.offsets(0)
+		s.start, s.empty = int(from), s.empty && int(from) == s.start
+	case rc == ERROR_NOMATCH && !s.eof:
+		// An empty match at start must not be found again once more
+		// input has arrived.
+		s.start, s.empty = n, s.empty && n == s.start
+	default:
+		return -1, -1, streamDone
+	}
+	if s.emit != nil && s.base+int64(s.start) > s.out {
+		s.emit(s.text(s.out, s.base+int64(s.start)))
+		s.out = s.base + int64(s.start)
+	}
+	s.discard()
+	return -1, -1, streamMore
+}
+
+// next returns the input offsets of the next match, reading input as
+// needed. ok is false if there is none, or matching failed with an
+// error, which is returned by err.
+func (s *streamSearcher) next() (start, end int64, ok bool) {
+	for {
+		start, end, result := s.search()
+		switch result {
+		case streamMatch:
+			return start, end, true
+		case streamDone:
+			return -1, -1, false
+		}
+		s.fill()
+	}
+}
+
+// fill reads more input into the buffer.
+func (s *streamSearcher) fill() {
+	var err error
+	s.buf, err = s.read(s.buf)
+	if err != nil {
+		s.eof = true
+		if err != io.EOF {
+			s.readErr = err
+		}
+	}
+}
+
+// text returns the buffered input between the input offsets. It is only
+// valid until the next search.
+func (s *streamSearcher) text(from, to int64) []byte {
+	return s.buf[from-s.base : to-s.base]
+}
+
+// rest returns the buffered input from the input offset to the end.
+func (s *streamSearcher) rest(from int64) []byte {
+	return s.buf[from-s.base:]
+}
+
+// discard drops the bytes before start which are neither needed as
+// context nor waiting to be emitted.
+func (s *streamSearcher) discard() {
+	n := s.start - s.keep
+	if s.emit != nil {
+		n = min(n, int(s.out-s.base))
+	}
+	if n > 0 && n >= len(s.buf)/2 {
+		s.buf = s.buf[:copy(s.buf, s.buf[n:])]
+		s.base += int64(n)
+		s.start -= n
+	}
+}
+
+// err returns the error of the last match, if it failed.
+func (s *streamSearcher) err() error {
+	return s.m.matchError()
+}
+
+func (s *streamSearcher) free() {
+	s.m.Free()
+}
+
+// MatchReader reports whether the text read from the RuneReader
+// contains any match of the pattern, like regexp.Regexp.MatchReader.
+// The text is read until a match is certain, and only as much of it is
+// kept as a match can span. Invalid UTF-8 in the input is read as
+// utf8.RuneError.
+func (re *Regexp) MatchReader(r io.RuneReader, flags uint32) bool {
+	return re.FindReaderIndex(r, flags) != nil
+}
+
+// FindReaderIndex returns the start and end of the first match in the
+// text read from the RuneReader, as byte offsets into the UTF-8 encoded
+// text, or nil if there is no match.
+func (re *Regexp) FindReaderIndex(r io.RuneReader, flags uint32) []int {
+	s := newStreamSearcher(re, flags, runeReader(r))
+	defer s.free()
+	start, end, ok := s.next()
+	if !ok {
+		return nil
+	}
+	return []int{int(start), int(end)}
+}
+
+// FindReaderIndex64 is like FindReaderIndex, but returns the offsets as
+// int64, so that they do not overflow on platforms with 32-bit int when
+// more than 2 GiB are read.
+func (re *Regexp) FindReaderIndex64(r io.RuneReader, flags uint32) []int64 {
+	s := newStreamSearcher(re, flags, runeReader(r))
+	defer s.free()
+	start, end, ok := s.next()
+	if !ok {
+		return nil
+	}
+	return []int64{start, end}
+}
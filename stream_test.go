@@ -0,0 +1,57 @@
+package pcre2
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchReader(t *testing.T) {
+	re := MustCompile(`b+c`, 0)
+	assert.True(t, re.MatchReader(strings.NewReader("aabbbc"), 0))
+	assert.False(t, re.MatchReader(strings.NewReader("aabbb"), 0))
+	assert.Equal(t, []int{2, 6}, re.FindReaderIndex(strings.NewReader("aabbbcbc"), 0))
+	assert.Nil(t, re.FindReaderIndex(strings.NewReader(""), 0))
+}
+
+func TestFindReaderIndexLarge(t *testing.T) {
+	long := strings.Repeat("x", 3*streamChunk+17)
+	for _, tc := range []struct {
+		pattern, subject string
+	}{
+		{`needle`, long + "needle"},
+		{`ne+dle`, long[:streamChunk-3] + "needle" + long},
+		{`(?<=x)y+$`, long + strings.Repeat("y", streamChunk)},
+		{`(?m)^z`, long + "z\nz"},
+		{`\bword`, long + "word"},
+		{`x{100}y`, long},
+		{`é+`, long + "éé"},
+		{`a|`, long},
+	} {
+		re := MustCompile(tc.pattern, UTF)
+		want := re.FindStringIndex(tc.subject, 0)
+		got := re.FindReaderIndex(bufio.NewReader(strings.NewReader(tc.subject)), 0)
+		assert.Equal(t, want, got, tc.pattern)
+	}
+}
+
+func TestStreamSearcherAll(t *testing.T) {
+	subject := strings.Repeat("ab1 c22 ", streamChunk/3)
+	for _, pattern := range []string{`\d+`, `\d*`, `(?<=c)\d`} {
+		re := MustCompile(pattern, 0)
+		s := newStreamSearcher(re, 0, runeReader(strings.NewReader(subject)))
+		var got [][]int
+		for {
+			start, end, ok := s.next()
+			if !ok {
+				break
+			}
+			got = append(got, []int{int(start), int(end)})
+		}
+		assert.NoError(t, s.err())
+		s.free()
+		assert.Equal(t, re.FindAllStringIndex(subject, 0, -1), got, pattern)
+	}
+}
@@ -0,0 +1,141 @@
+package pcre2
+
+import "io"
+
+// NewReplaceReader returns a Reader which reads from r and replaces all
+// matches of re by repl, like ReplaceAllSafe, while the data is read. Only
+// the text a match can span, plus the lookbehind of re, is buffered, so
+// streams of any size can be rewritten with bounded memory, unless a
+// match or partial match itself is huge. Match errors, e.g. when a
+// limit is hit, are returned by Read after the text before them.
+func NewReplaceReader(r io.Reader, re *Regexp, repl []byte) io.Reader {
+	rr := &replaceReader{repl: repl}
+	rr.s = newStreamSearcher(re, 0, byteReader(r))
+	rr.s.emit = func(text []byte) {
+		rr.pending = append(rr.pending, text...)
+	}
+	return rr
+}
+
+type replaceReader struct {
+	s       *streamSearcher
+	repl    []byte
+	pending []byte // output which has not been read yet
+	err     error  // returned once pending is drained
+}
+
+// Read implements io.Reader.
+func (rr *replaceReader) Read(p []byte) (int, error) {
+	for len(rr.pending) == 0 {
+		if rr.err != nil {
+			return 0, rr.err
+		}
+		s := rr.s
+		start, end, result := s.search()
+		switch result {
+		case streamMatch:
+			rr.pending = append(rr.pending, s.text(s.out, start)...)
+			rr.pending = append(rr.pending, rr.repl...)
+			s.out = end
+		case streamMore:
+			s.fill()
+		case streamDone:
+			rr.pending = append(rr.pending, s.rest(s.out)...)
+			rr.err = streamError(s)
+			s.free()
+		}
+	}
+	n := copy(p, rr.pending)
+	if n == len(rr.pending) {
+		rr.pending = rr.pending[:0]
+	} else {
+		rr.pending = rr.pending[n:]
+	}
+	return n, nil
+}
+
+// streamError returns the error which ended the input of s, or io.EOF.
+func streamError(s *streamSearcher) error {
+	if err := s.err(); err != nil {
+		return err
+	}
+	if s.readErr != nil {
+		return s.readErr
+	}
+	return io.EOF
+}
+
+// NewReplaceWriter returns a WriteCloser which replaces all matches of
+// re by repl, like ReplaceAllSafe, in the data written to it, and writes
+// the result to w. Like NewReplaceReader, it buffers only the text a
+// match can span. Close must be called to write the end of the data; it
+// does not close w.
+func NewReplaceWriter(w io.Writer, re *Regexp, repl []byte) io.WriteCloser {
+	rw := &replaceWriter{w: w, repl: repl}
+	rw.s = newStreamSearcher(re, 0, nil)
+	rw.s.emit = rw.write
+	return rw
+}
+
+type replaceWriter struct {
+	s    *streamSearcher
+	w    io.Writer
+	repl []byte
+	err  error // first error, returned by all further calls
+}
+
+func (rw *replaceWriter) write(p []byte) {
+	if rw.err == nil {
+		_, rw.err = rw.w.Write(p)
+	}
+}
+
+// Write implements io.Writer.
+func (rw *replaceWriter) Write(p []byte) (int, error) {
+	if rw.err != nil {
+		return 0, rw.err
+	}
+	rw.s.buf = append(rw.s.buf, p...)
+	rw.replace()
+	if rw.err != nil {
+		return 0, rw.err
+	}
+	return len(p), nil
+}
+
+// replace writes the output for the buffered input, as far as it is
+// known.
+func (rw *replaceWriter) replace() {
+	s := rw.s
+	for rw.err == nil {
+		start, end, result := s.search()
+		switch result {
+		case streamMatch:
+			rw.write(s.text(s.out, start))
+			rw.write(rw.repl)
+			s.out = end
+		case streamMore:
+			return
+		case streamDone:
+			if err := s.err(); err != nil {
+				rw.err = err
+				return
+			}
+			rw.write(s.rest(s.out))
+			s.out = s.base + int64(len(s.buf))
+			return
+		}
+	}
+}
+
+// Close writes the output for the rest of the data. It does not close
+// the underlying Writer.
+func (rw *replaceWriter) Close() error {
+	if rw.s.eof {
+		return rw.err
+	}
+	rw.s.eof = true
+	rw.replace()
+	rw.s.free()
+	return rw.err
+}
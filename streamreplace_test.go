@@ -0,0 +1,97 @@
+package pcre2
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var streamReplaceTests = []struct {
+	pattern string
+	flags   uint32
+	subject string
+}{
+	{`secret\d+`, 0, strings.Repeat("some text secret123 more ", 2000)},
+	{`(?<=key=)\w+`, 0, strings.Repeat("key=value;", 3000)},
+	{`x*`, 0, strings.Repeat("axxb", 2000)},
+	{`a.{5000}b`, 0, "a" + strings.Repeat("-", 5000) + "b" + strings.Repeat("a-b", 1000)},
+	{`(?m)^#.*\n`, 0, strings.Repeat("# comment\ncode\n", 1000)},
+	{`nomatch`, 0, strings.Repeat("abc", 5000)},
+	{`z`, 0, ""},
+	{`(?m)^`, 0, "axxb cd\nef"},
+	{``, 0, "axxb cd"},
+	{`é`, UTF, strings.Repeat("a", 4095) + "üxé"},
+	{`.`, UTF, strings.Repeat("ü€𝄞", 2000)},
+}
+
+func TestReplaceReader(t *testing.T) {
+	repl := []byte("<R>")
+	for _, tc := range streamReplaceTests {
+		re := MustCompile(tc.pattern, tc.flags)
+		want := re.ReplaceAllSafe([]byte(tc.subject), repl, 0)
+
+		got, err := io.ReadAll(NewReplaceReader(iotest.OneByteReader(strings.NewReader(tc.subject)), re, repl))
+		assert.NoError(t, err)
+		assert.Equal(t, string(want), string(got), tc.pattern)
+
+		got, err = io.ReadAll(NewReplaceReader(strings.NewReader(tc.subject), re, repl))
+		assert.NoError(t, err)
+		assert.Equal(t, string(want), string(got), tc.pattern)
+	}
+}
+
+func TestReplaceWriter(t *testing.T) {
+	repl := []byte("<R>")
+	for _, tc := range streamReplaceTests {
+		re := MustCompile(tc.pattern, tc.flags)
+		want := re.ReplaceAllSafe([]byte(tc.subject), repl, 0)
+
+		for _, size := range []int{1, 1000} {
+			var out bytes.Buffer
+			w := NewReplaceWriter(&out, re, repl)
+			for i := 0; i < len(tc.subject); i += size {
+				n, err := w.Write([]byte(tc.subject[i:min(i+size, len(tc.subject))]))
+				assert.NoError(t, err)
+				assert.Equal(t, min(size, len(tc.subject)-i), n)
+			}
+			assert.NoError(t, w.Close())
+			assert.Equal(t, string(want), out.String(), tc.pattern)
+		}
+	}
+}
+
+func TestReplaceReaderBoundedMemory(t *testing.T) {
+	re := MustCompile(`needle`, 0)
+	r := NewReplaceReader(io.LimitReader(neverEnding('x'), 10<<20), re, nil).(*replaceReader)
+	n, err := io.Copy(io.Discard, r)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10<<20), n)
+	assert.True(t, cap(r.s.buf) < 64<<10, "buffer grew to %d", cap(r.s.buf))
+}
+
+type neverEnding byte
+
+func (b neverEnding) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(b)
+	}
+	return len(p), nil
+}
+
+func TestReplaceReaderErrors(t *testing.T) {
+	errRead := errors.New("read failed")
+	r := io.MultiReader(strings.NewReader("a needle b"), iotest.ErrReader(errRead))
+	got, err := io.ReadAll(NewReplaceReader(r, MustCompile(`needle`, 0), []byte("pin")))
+	assert.Equal(t, errRead, err)
+	assert.Equal(t, "a pin b", string(got))
+
+	defer SetDefaultLimits(0, 0, 0)
+	SetDefaultLimits(100, 0, 0)
+	_, err = io.ReadAll(NewReplaceReader(strings.NewReader("aaaaaaaaaaaaaaaaaab"), MustCompile(`(a+)+$`, 0), nil))
+	assert.ErrorIs(t, err, ErrMatchLimit)
+}
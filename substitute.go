@@ -0,0 +1,366 @@
+package pcre2
+
+/*
+#define PCRE2_CODE_UNIT_WIDTH 8
+
+#include <stdint.h>
+#include <pcre2.h>
+#include "./pcre2_fallback.h"
+
+extern int goSubstituteCallout(pcre2_substitute_callout_block *, uintptr_t);
+
+static int mySubstituteCalloutTrampoline(pcre2_substitute_callout_block *block, void *data) {
+	return goSubstituteCallout(block, (uintptr_t) data);
+}
+
+static void mySetSubstituteCallout(pcre2_match_context *mcontext, uintptr_t handle) {
+	if (handle == 0) {
+		pcre2_set_substitute_callout(mcontext, NULL, NULL);
+	} else {
+		pcre2_set_substitute_callout(mcontext, mySubstituteCalloutTrampoline, (void *) handle);
+	}
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/cgo"
+	"time"
+	"unsafe"
+)
+
+// substituteNewer are the substitute options which need
+// FeatureSubstituteMatched.
+const substituteNewer = SUBSTITUTE_LITERAL | SUBSTITUTE_MATCHED | SUBSTITUTE_REPLACEMENT_ONLY
+
+// Substitute returns a copy of the subject in which the first match, or
+// all matches with SUBSTITUTE_GLOBAL, is replaced by the replacement,
+// using pcre2_substitute, and the number of replacements, so that
+// callers can tell whether anything was replaced. The replacement may
+// refer to capture groups as $1, ${name} and, with SUBSTITUTE_EXTENDED,
+// use the extended syntax of PCRE2; SUBSTITUTE_LITERAL takes it
+// literally. The other SUBSTITUTE_* options control unset groups, and
+// which parts of the subject are kept; further flags are passed on to
+// the match.
+//
+// With SUBSTITUTE_MATCHED, the first match is not searched for, but
+// taken from the last match of the Matcher, which must have been made
+// on the same subject. Afterwards, the Matcher holds no match.
+//
+// The matches are made like all others of the Matcher, with its match
+// context and watchdog, and are reported to Metrics and the logger, but
+// leftmost-longest matching is not available: substituting with a
+// Regexp made Longest fails with ErrSubstituteLongest.
+//
+// The error is a *MatchError for invalid replacements, which reports
+// the offset of the error in the replacement, and for failed matches,
+// or a *FeatureError if SUBSTITUTE_LITERAL, SUBSTITUTE_MATCHED or
+// SUBSTITUTE_REPLACEMENT_ONLY are not supported by the library.
+func (m *Matcher) Substitute(subject, replacement []byte, flags uint32) ([]byte, int, error) {
+	if m.re.ptr == nil {
+		panic("Matcher.Substitute: uninitialized")
+	}
+	return substitute(m, subject, replacement, 0, flags)
+}
+
+// SubstituteString is like Substitute, but for strings.
+func (m *Matcher) SubstituteString(subject, replacement string, flags uint32) (string, int, error) {
+	if m.re.ptr == nil {
+		panic("Matcher.SubstituteString: uninitialized")
+	}
+	out, n, err := substitute(m, subject, replacement, 0, flags)
+	return string(out), n, err
+}
+
+// SubstituteWithOptions is like Substitute, but with the given options
+// instead of just flags. Matches are searched from opts.Offset on, and a
+// deadline or timeout bounds the whole substitution.
+func (m *Matcher) SubstituteWithOptions(subject, replacement []byte, opts MatchOptions) ([]byte, int, error) {
+	if m.re.ptr == nil {
+		panic("Matcher.SubstituteWithOptions: uninitialized")
+	}
+	defer m.applyOptions(opts)()
+	return substitute(m, subject, replacement, opts.Offset, opts.Flags)
+}
+
+// SubstituteStringWithOptions is like SubstituteWithOptions, but for
+// strings.
+func (m *Matcher) SubstituteStringWithOptions(subject, replacement string, opts MatchOptions) (string, int, error) {
+	if m.re.ptr == nil {
+		panic("Matcher.SubstituteStringWithOptions: uninitialized")
+	}
+	defer m.applyOptions(opts)()
+	out, n, err := substitute(m, subject, replacement, opts.Offset, opts.Flags)
+	return string(out), n, err
+}
+
+// Substitute is like Matcher.Substitute, but with a temporary Matcher,
+// so SUBSTITUTE_MATCHED cannot be used.
+func (re *Regexp) Substitute(subject, replacement []byte, flags uint32) ([]byte, int, error) {
+	m := re.NewMatcher()
+	defer m.Free()
+	return substitute(m, subject, replacement, 0, flags&^SUBSTITUTE_MATCHED)
+}
+
+// SubstituteString is like Substitute, but for strings.
+func (re *Regexp) SubstituteString(subject, replacement string, flags uint32) (string, int, error) {
+	m := re.NewMatcher()
+	defer m.Free()
+	out, n, err := substitute(m, subject, replacement, 0, flags&^SUBSTITUTE_MATCHED)
+	return string(out), n, err
+}
+
+// MustSubstitute is like Substitute, but panics if the substitution
+// fails, e.g. because the replacement is invalid. It simplifies
+// substitutions with fixed replacements.
+func (re *Regexp) MustSubstitute(subject, replacement []byte, flags uint32) []byte {
+	out, _, err := re.Substitute(subject, replacement, flags)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// MustSubstituteString is like MustSubstitute, but for strings.
+func (re *Regexp) MustSubstituteString(subject, replacement string, flags uint32) string {
+	out, _, err := re.SubstituteString(subject, replacement, flags)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// ErrSubstituteLongest is returned by Substitute for Regexps made
+// leftmost-longest by Longest, as pcre2_substitute only matches by
+// backtracking. ReplaceAll supports them.
+var ErrSubstituteLongest = errors.New("substitute cannot match leftmost-longest")
+
+func substitute[S subject](m *Matcher, subject, replacement S, offset int, flags uint32) ([]byte, int, error) {
+	if flags&substituteNewer != 0 {
+		if err := Require(FeatureSubstituteMatched); err != nil {
+			return nil, 0, err
+		}
+	}
+	if m.re.longest {
+		return nil, 0, ErrSubstituteLongest
+	}
+	if offset < 0 || offset > len(subject) {
+		return nil, 0, ErrBadOffset
+	}
+	// As in execAt, the substitution is observed as a whole; rc is the
+	// return code of its last attempt.
+	var rc int
+	if h := metrics.Load(); h != nil {
+		defer observeMatch(h, m.re, time.Now(), &rc)
+	}
+	if logger.Load() != nil {
+		defer logLimit(m.re, offset, &rc)
+	}
+	if m.re.subjectTooLong(len(subject)) {
+		rc = ERROR_SUBJECT_TOO_LONG
+		return nil, 0, ErrSubjectTooLong
+	}
+	if m.re.literal && flags&SUBSTITUTE_MATCHED != 0 {
+		// The fast path of literal patterns does not fill in the match
+		// data PCRE2 reads, so the first match is searched again.
+		flags &^= SUBSTITUTE_MATCHED
+	}
+	switch s := any(subject).(type) {
+	case []byte:
+		m.subjects, m.subjectb = "", s
+	case string:
+		m.subjects, m.subjectb = s, nil
+	}
+	defer func() {
+		m.matches, m.partial, m.rc = false, false, 0
+	}()
+
+	subjectptr, replacementptr := dataPtr(subject), dataPtr(replacement)
+	var pinner runtime.Pinner
+	pinner.Pin(subjectptr)
+	pinner.Pin(replacementptr)
+	defer pinner.Unpin()
+	if m.callout != nil {
+		defer m.removeCallout(m.installCallout())
+	}
+	if m.jitPool != nil {
+		defer m.releaseJITStack(m.acquireJITStack())
+	}
+	// The code must not be JIT compiled by AutoJIT while it is in use.
+	if aj := m.re.autoJIT; aj != nil && !aj.done.Load() {
+		defer aj.count(m.re)()
+	}
+	w := m.watchdog()
+	abandonable := w != nil || !m.deadline.IsZero()
+	call := func(out []byte) C.PCRE2_SIZE {
+		var outlen C.PCRE2_SIZE
+		run := func() int {
+			mc := m.matchContext()
+			defer runtime.KeepAlive(mc)
+			outlen = C.PCRE2_SIZE(len(out))
+			return int(C.pcre2_substitute(m.re.ptr,
+				C.PCRE2_SPTR(unsafe.Pointer(subjectptr)), C.PCRE2_SIZE(len(subject)), C.PCRE2_SIZE(offset),
+				C.uint32_t(flags|SUBSTITUTE_OVERFLOW_LENGTH), m.mData.md, mc.pointer(),
+				C.PCRE2_SPTR(unsafe.Pointer(replacementptr)), C.PCRE2_SIZE(len(replacement)),
+				(*C.PCRE2_UCHAR)(unsafe.Pointer(&out[0])), &outlen))
+		}
+		if abandonable {
+			rc = m.bounded(len(subject), w, run)
+		} else {
+			rc = run()
+		}
+		return outlen
+	}
+
+	// The output usually is about as long as the subject. If it is
+	// longer, PCRE2 reports the length needed, and the substitution is
+	// done again.
+	size := len(subject) + len(replacement) + 64
+	if m.substituteCallout != nil {
+		// The substitute callout must see every replacement once, so
+		// the output must not overflow. Skipping replacements keeps the
+		// matched text instead, so it is at most as long as the subject
+		// more than the output with all replacements, which is computed
+		// without the callout first.
+		if err := Require(FeatureSubstituteCallout); err != nil {
+			return nil, 0, err
+		}
+		outlen := call(make([]byte, 1))
+		if rc < 0 && rc != ERROR_NOMEMORY {
+			return nil, 0, substituteError(rc, outlen)
+		}
+		size = max(size, int(outlen)+len(subject))
+		// The matches completed once, so they are not bounded again:
+		// retrying would show replacements to the callout twice.
+		abandonable = false
+		defer m.removeSubstituteCallout(m.installSubstituteCallout())
+	}
+	out := make([]byte, size)
+	for {
+		outlen := call(out)
+		switch {
+		case rc >= 0:
+			return out[:outlen], rc, nil
+		case rc == ERROR_NOMEMORY && int(outlen) > len(out):
+			out = make([]byte, outlen)
+		case rc == ERROR_NOMEMORY && SUBSTITUTE_OVERFLOW_LENGTH == 0:
+			out = make([]byte, 2*len(out))
+		default:
+			return nil, 0, substituteError(rc, outlen)
+		}
+	}
+}
+
+// substituteError returns the error for a failed substitution. For
+// errors in the replacement, PCRE2 reports their offset in outlen.
+func substituteError(rc int, outlen C.PCRE2_SIZE) error {
+	switch rc {
+	case ERROR_BADREPESCAPE, ERROR_BADREPLACEMENT, ERROR_BADSUBSTITUTION, ERROR_REPMISSINGBRACE:
+		return &MatchError{
+			ErrorNum: rc,
+			Message:  fmt.Sprintf("%s at offset %d of the replacement", errorMessage(rc), outlen),
+		}
+	}
+	return matchError(rc)
+}
+
+// substituteCalloutFunc is invoked after every replacement of Substitute.
+// A return value of zero accepts it, a positive value keeps the matched
+// text instead, and a negative value accepts no further replacements.
+type substituteCalloutFunc func(block *C.pcre2_substitute_callout_block) C.int
+
+// installSubstituteCallout installs the substitute callout of m in its
+// match context for the duration of a single Substitute.
+func (m *Matcher) installSubstituteCallout() cgo.Handle {
+	h := cgo.NewHandle(m.substituteCallout)
+	C.mySetSubstituteCallout(m.privateContext().ptr, C.uintptr_t(h))
+	return h
+}
+
+func (m *Matcher) removeSubstituteCallout(h cgo.Handle) {
+	C.mySetSubstituteCallout(m.private.ptr, 0)
+	h.Delete()
+}
+
+// SubstituteCalloutBlock describes a replacement of Substitute. It is
+// only valid during the call of the SubstituteCalloutFunc which receives
+// it.
+type SubstituteCalloutBlock struct {
+	Number int // 1 for the first replacement, 2 for the second, and so on
+
+	m     *Matcher
+	block *C.pcre2_substitute_callout_block
+}
+
+// SubstituteCalloutFunc is invoked after every replacement of
+// Substitute, e.g. to log it or to replace only some matches. Returning
+// zero accepts the replacement, a positive value keeps the matched text
+// instead, and a negative value keeps it and ends the substitution, so
+// that the rest of the subject is copied unchanged.
+type SubstituteCalloutFunc func(block *SubstituteCalloutBlock) int
+
+// SetSubstituteCallout installs fn, which is invoked after every
+// replacement of the subsequent calls of Substitute on m. It needs
+// PCRE2 10.33, see FeatureSubstituteCallout. Substitute matches the
+// subject once more beforehand to size the output, so that fn sees
+// every replacement once, while callouts of the pattern are invoked for
+// both passes. A nil function removes the callout.
+func (m *Matcher) SetSubstituteCallout(fn SubstituteCalloutFunc) {
+	if fn == nil {
+		m.substituteCallout = nil
+		return
+	}
+	m.substituteCallout = func(block *C.pcre2_substitute_callout_block) C.int {
+		return C.int(fn(&SubstituteCalloutBlock{int(block.subscount), m, block}))
+	}
+}
+
+// GroupIndices returns the start and end offsets of the capture group in
+// the match which was replaced, or nil if it is not set.
+func (b *SubstituteCalloutBlock) GroupIndices(group int) []int {
+	if group < 0 || group >= int(b.block.oveccount) {
+		return nil
+	}
+	ovector := unsafe.Slice(b.block.ovector, 2*b.block.oveccount)
+	start, end := ovector[2*group], ovector[2*group+1]
+	if start == UNSET {
+		return nil
+	}
+	return []int{int(start), int(end)}
+}
+
+// Group returns the text of the capture group in the match which was
+// replaced, or nil.
+func (b *SubstituteCalloutBlock) Group(group int) []byte {
+	loc := b.GroupIndices(group)
+	if loc == nil {
+		return nil
+	}
+	if b.m.subjectb != nil {
+		return b.m.subjectb[loc[0]:loc[1]]
+	}
+	return []byte(b.m.subjects[loc[0]:loc[1]])
+}
+
+// GroupString is like Group, but returns a string.
+func (b *SubstituteCalloutBlock) GroupString(group int) string {
+	loc := b.GroupIndices(group)
+	if loc == nil {
+		return ""
+	}
+	if b.m.subjectb != nil {
+		return string(b.m.subjectb[loc[0]:loc[1]])
+	}
+	return b.m.subjects[loc[0]:loc[1]]
+}
+
+// Replacement returns the text which replaces the match, after the
+// substitution of groups in the replacement.
+func (b *SubstituteCalloutBlock) Replacement() string {
+	start, end := b.block.output_offsets[0], b.block.output_offsets[1]
+	output := unsafe.Slice((*byte)(unsafe.Pointer(b.block.output)), end)
+	return string(output[start:end])
+}
@@ -0,0 +1,196 @@
+package pcre2
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubstitute(t *testing.T) {
+	re := MustCompile(`(?<y>\d{4})-(\d\d)-(\d\d)`, 0)
+	defer re.Free()
+
+	out, _, err := re.SubstituteString("from 2024-05-01 to 2024-06-30", "$3.$2.${y}", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "from 01.05.2024 to 2024-06-30", out)
+
+	out, _, err = re.SubstituteString("from 2024-05-01 to 2024-06-30", "$3.$2.${y}", SUBSTITUTE_GLOBAL)
+	assert.NoError(t, err)
+	assert.Equal(t, "from 01.05.2024 to 30.06.2024", out)
+
+	b, _, err := re.Substitute([]byte("none"), []byte("x"), SUBSTITUTE_GLOBAL)
+	assert.NoError(t, err)
+	assert.Equal(t, "none", string(b))
+
+	// The output grows beyond the initial buffer.
+	long := strings.Repeat("2024-05-01 ", 100)
+	out, _, err = re.SubstituteString(long, strings.Repeat("$0", 10), SUBSTITUTE_GLOBAL)
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Repeat(strings.Repeat("2024-05-01", 10)+" ", 100), out)
+
+	_, _, err = re.SubstituteString("2024-05-01", "${4}", 0)
+	var merr *MatchError
+	assert.ErrorAs(t, err, &merr)
+	assert.Equal(t, ERROR_NOSUBSTRING, merr.ErrorNum)
+
+	_, _, err = re.SubstituteString("2024-05-01", "ab$", 0)
+	assert.ErrorAs(t, err, &merr)
+	assert.Equal(t, ERROR_BADREPLACEMENT, merr.ErrorNum)
+	assert.Contains(t, err.Error(), "at offset 3 of the replacement")
+}
+
+func TestSubstituteExtended(t *testing.T) {
+	re := MustCompile(`(\w+)(?: (\w+))?`, 0)
+	defer re.Free()
+	out, _, err := re.SubstituteString("hello world", `\U$1\E${2:+ and $2:-}`, SUBSTITUTE_EXTENDED)
+	assert.NoError(t, err)
+	assert.Equal(t, "HELLO and world", out)
+}
+
+func TestSubstituteNewer(t *testing.T) {
+	if !Supports(FeatureSubstituteMatched) {
+		t.Skip("needs PCRE2 10.34")
+	}
+	re := MustCompile(`\d+`, 0)
+	defer re.Free()
+
+	out, _, err := re.SubstituteString("a1b22", "$0!", SUBSTITUTE_GLOBAL|SUBSTITUTE_LITERAL)
+	assert.NoError(t, err)
+	assert.Equal(t, "a$0!b$0!", out)
+
+	out, _, err = re.SubstituteString("a1b22c", "<$0>", SUBSTITUTE_GLOBAL|SUBSTITUTE_REPLACEMENT_ONLY)
+	assert.NoError(t, err)
+	assert.Equal(t, "<1><22>", out)
+
+	m := re.NewMatcher()
+	defer m.Free()
+	subject := "x12y34"
+	assert.True(t, m.MatchStringWithOptions(subject, MatchOptions{Offset: 3}))
+	out, _, err = m.SubstituteString(subject, "#", SUBSTITUTE_MATCHED)
+	assert.NoError(t, err)
+	assert.Equal(t, "x12y#", out)
+	assert.False(t, m.Matches())
+}
+
+func TestSubstituteCallout(t *testing.T) {
+	if !Supports(FeatureSubstituteCallout) {
+		t.Skip("needs PCRE2 10.33")
+	}
+	re := MustCompile(`(\w+)@(\w+)`, 0)
+	defer re.Free()
+	m := re.NewMatcher()
+	defer m.Free()
+
+	var seen []string
+	m.SetSubstituteCallout(func(b *SubstituteCalloutBlock) int {
+		seen = append(seen, fmt.Sprintf("%d %s %v %s", b.Number, b.GroupString(0), b.GroupIndices(2), b.Replacement()))
+		if b.GroupString(2) == "internal" {
+			return 1
+		}
+		return 0
+	})
+	out, _, err := m.SubstituteString("a@example b@internal c@other", "<$1 at $2>", SUBSTITUTE_GLOBAL)
+	assert.NoError(t, err)
+	assert.Equal(t, "<a at example> b@internal <c at other>", out)
+	assert.Equal(t, []string{
+		"1 a@example [2 9] <a at example>",
+		"2 b@internal [12 20] <b at internal>",
+		"3 c@other [23 28] <c at other>",
+	}, seen)
+
+	// Ending the substitution keeps the rest.
+	seen = nil
+	m.SetSubstituteCallout(func(b *SubstituteCalloutBlock) int {
+		seen = append(seen, b.GroupString(0))
+		return -1
+	})
+	out, _, err = m.SubstituteString("a@b c@d", "x", SUBSTITUTE_GLOBAL)
+	assert.NoError(t, err)
+	assert.Equal(t, "a@b c@d", out)
+	assert.Equal(t, []string{"a@b"}, seen)
+
+	// The callout sees every replacement once, even if the output
+	// grows much longer than the subject.
+	var n int
+	m.SetSubstituteCallout(func(b *SubstituteCalloutBlock) int {
+		n++
+		return 0
+	})
+	out, _, err = m.SubstituteString(strings.Repeat("a@b ", 50), strings.Repeat("$0", 20), SUBSTITUTE_GLOBAL)
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Repeat(strings.Repeat("a@b", 20)+" ", 50), out)
+	assert.Equal(t, 50, n)
+
+	m.SetSubstituteCallout(nil)
+	out, _, err = m.SubstituteString("a@b", "x", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "x", out)
+}
+
+func TestSubstituteCount(t *testing.T) {
+	re := MustCompile(`o`, 0)
+	defer re.Free()
+
+	out, n, err := re.SubstituteString("foo boo", "0", SUBSTITUTE_GLOBAL)
+	assert.NoError(t, err)
+	assert.Equal(t, "f00 b00", out)
+	assert.Equal(t, 4, n)
+
+	out, n, err = re.SubstituteString("foo boo", "0", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "f0o boo", out)
+	assert.Equal(t, 1, n)
+
+	b, n, err := re.Substitute([]byte("bar"), []byte("0"), SUBSTITUTE_GLOBAL)
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", string(b))
+	assert.Zero(t, n)
+
+	assert.Equal(t, "f00", re.MustSubstituteString("foo", "0", SUBSTITUTE_GLOBAL))
+	assert.Equal(t, "f0o", string(re.MustSubstitute([]byte("foo"), []byte("0"), 0)))
+	assert.Panics(t, func() { re.MustSubstituteString("foo", "$9", 0) })
+}
+
+func TestSubstituteWithOptions(t *testing.T) {
+	re := MustCompile(`a`, 0)
+	defer re.Free()
+	m := re.NewMatcher()
+	defer m.Free()
+
+	out, n, err := m.SubstituteStringWithOptions("aaa", "x", MatchOptions{Flags: SUBSTITUTE_GLOBAL, Offset: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "axx", out)
+	assert.Equal(t, 2, n)
+
+	_, _, err = m.SubstituteWithOptions([]byte("aaa"), []byte("x"), MatchOptions{Offset: 4})
+	assert.ErrorIs(t, err, ErrBadOffset)
+
+	slow := MustCompile(`(a+)+$`, 0)
+	defer slow.Free()
+	m = slow.NewMatcher()
+	defer m.Free()
+	_, _, err = m.SubstituteStringWithOptions(strings.Repeat("a", 40)+"b", "x",
+		MatchOptions{Timeout: time.Millisecond})
+	assert.ErrorIs(t, err, ErrTimeout)
+}
+
+func TestSubstituteLikeMatches(t *testing.T) {
+	pm := NewPatternMetrics()
+	pm.Track(`b+`)
+	SetMetrics(pm)
+	defer SetMetrics(nil)
+
+	re := MustCompile(`b+`, 0)
+	defer re.Free()
+	out, _, err := re.SubstituteString("abbc", "-", SUBSTITUTE_GLOBAL)
+	assert.NoError(t, err)
+	assert.Equal(t, "a-c", out)
+	assert.Equal(t, int64(1), pm.Stats()[0].Matches)
+
+	re.Longest()
+	_, _, err = re.SubstituteString("abbc", "-", 0)
+	assert.ErrorIs(t, err, ErrSubstituteLongest)
+}
@@ -0,0 +1,154 @@
+package pcre2
+
+/*
+#define PCRE2_CODE_UNIT_WIDTH 8
+
+#define _GNU_SOURCE
+#include <locale.h>
+#include <stdint.h>
+#include <stdlib.h>
+#include <pcre2.h>
+#include "./pcre2_fallback.h"
+
+// Builds the tables for the given locale, which only affects the
+// calling thread. Returns NULL if the locale is unknown.
+static const uint8_t *myMakeTables(const char *name, int *badLocale) {
+	const uint8_t *tables;
+
+	*badLocale = 0;
+	if (name == NULL) {
+		return pcre2_maketables(NULL);
+	}
+#ifdef _WIN32
+	// Windows has no uselocale, but setlocale can be made thread-local.
+	int prev = _configthreadlocale(_ENABLE_PER_THREAD_LOCALE);
+	char *saved = _strdup(setlocale(LC_CTYPE, NULL));
+	if (setlocale(LC_CTYPE, name) == NULL) {
+		*badLocale = 1;
+		tables = NULL;
+	} else {
+		tables = pcre2_maketables(NULL);
+		setlocale(LC_CTYPE, saved);
+	}
+	free(saved);
+	_configthreadlocale(prev);
+	return tables;
+#else
+	locale_t loc, old;
+
+	loc = newlocale(LC_CTYPE_MASK, name, (locale_t) 0);
+	if (loc == (locale_t) 0) {
+		*badLocale = 1;
+		return NULL;
+	}
+	old = uselocale(loc);
+	tables = pcre2_maketables(NULL);
+	uselocale(old);
+	freelocale(loc);
+	return tables;
+#endif
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Tables holds character tables, which define the lower/upper case
+// mapping and the character classes used when matching 8-bit non-UTF
+// data. Patterns compiled with a CompileContext using the tables keep
+// a reference to them, so they remain valid until both the Tables and
+// all such patterns have been freed.
+type Tables struct {
+	ptr     *C.uint8_t
+	refs    atomic.Int32
+	once    sync.Once // of Free
+	cleanup runtime.Cleanup
+}
+
+// MakeTables builds character tables for the LC_CTYPE category of the
+// given locale, e.g. "de_DE.ISO-8859-1". An empty locale uses the
+// current locale of the process. The built-in tables of the library
+// correspond to the "C" locale.
+func MakeTables(locale string) (*Tables, error) {
+	var name *C.char
+	if locale != "" {
+		name = C.CString(locale)
+		defer C.free(unsafe.Pointer(name))
+	}
+	// The locale is switched for the current thread only.
+	runtime.LockOSThread()
+	var badLocale C.int
+	ptr := C.myMakeTables(name, &badLocale)
+	runtime.UnlockOSThread()
+	if badLocale != 0 {
+		return nil, fmt.Errorf("unknown locale %q", locale)
+	}
+	if ptr == nil {
+		return nil, ErrNoMemory
+	}
+	t := &Tables{ptr: ptr}
+	t.refs.Store(1)
+	// Patterns and contexts using the tables keep t reachable, so the
+	// cleanup only runs once the reference of the caller is the last.
+	t.cleanup = addCleanup(t, freeTables, ptr)
+	return t, nil
+}
+
+func freeTables(ptr *C.uint8_t) {
+	if Supports(FeatureMaketablesFree) {
+		C.pcre2_maketables_free(nil, ptr)
+	} else {
+		// Older libraries allocate the tables with malloc.
+		C.free(unsafe.Pointer(ptr))
+	}
+}
+
+// Free releases the reference held by the caller. The underlying C
+// memory is released once no compiled pattern uses the tables anymore.
+func (t *Tables) Free() error {
+	if t == nil {
+		return nil
+	}
+	t.once.Do(func() {
+		t.cleanup.Stop()
+		t.release()
+	})
+	return nil
+}
+
+func (t *Tables) acquire() {
+	if t != nil {
+		t.refs.Add(1)
+	}
+}
+
+func (t *Tables) release() {
+	if t != nil && t.refs.Add(-1) == 0 {
+		freeTables(t.ptr)
+		t.ptr = nil
+	}
+}
+
+// SetCharacterTables makes patterns compiled with this context use the
+// given tables, instead of the built-in ones. Nil restores the built-in
+// tables.
+func (cc *CompileContext) SetCharacterTables(t *Tables) {
+	if t != nil && t.ptr == nil {
+		panic("CompileContext.SetCharacterTables: tables have been freed")
+	}
+	old := cc.res.tables
+	t.acquire()
+	cc.res.tables = t
+	if t == nil {
+		C.pcre2_set_character_tables(cc.ptr, nil)
+	} else {
+		C.pcre2_set_character_tables(cc.ptr, t.ptr)
+	}
+	old.release()
+}
@@ -0,0 +1,37 @@
+package pcre2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTables(t *testing.T) {
+	tables, err := MakeTables("C")
+	if !assert.NoError(t, err) {
+		return
+	}
+	cc := NewCompileContext()
+	cc.SetCharacterTables(tables)
+	re, err := CompileWithContext(`ab+`, CASELESS, cc)
+	if !assert.NoError(t, err) {
+		return
+	}
+	clone, err := re.Clone()
+	assert.NoError(t, err)
+
+	// The patterns keep the tables alive.
+	assert.NoError(t, tables.Free())
+	assert.NoError(t, cc.Free())
+	assert.NotNil(t, tables.ptr)
+	assert.Equal(t, []int{1, 4}, re.FindIndex([]byte("xABb"), 0))
+	re.Free()
+	assert.Equal(t, []int{0, 2}, clone.FindIndex([]byte("aB"), 0))
+	clone.Free()
+	assert.Nil(t, tables.ptr)
+}
+
+func TestTablesUnknownLocale(t *testing.T) {
+	_, err := MakeTables("no_SUCH.locale")
+	assert.Error(t, err)
+}
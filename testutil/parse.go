@@ -0,0 +1,438 @@
+package testutil
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Jemmic/go-pcre2"
+)
+
+// Script is a parsed pcre2test output file.
+type Script struct {
+	Name     string // file name, for messages
+	Patterns []*Pattern
+}
+
+// Pattern is a pattern of a script with its subjects.
+type Pattern struct {
+	Line    int    // line number of the pattern in the file
+	Pattern string // the pattern, without delimiters
+	Flags   uint32 // compile flags from the modifiers
+	JIT     bool   // the jit modifier is set
+	// Error is the expected "Failed: ..." line if compiling fails, or
+	// empty if the pattern compiles.
+	Error    string
+	Subjects []*Subject
+	// Skip is the reason the pattern is not run, e.g. an unsupported
+	// modifier, or empty.
+	Skip string
+
+	// subject modifiers given on the pattern, which apply to all of
+	// its subjects
+	matchFlags uint32
+	global     bool
+}
+
+// Subject is a subject line of a script with the expected output.
+type Subject struct {
+	Line     int      // line number of the subject in the file
+	Text     []byte   // the subject with escapes processed
+	Flags    uint32   // match flags from the modifiers
+	Offset   int      // the offset modifier
+	Global   bool     // the g or global modifier is set
+	Expected []string // the output lines following the subject
+	Skip     string   // the reason the subject is not run, or empty
+}
+
+// ParseFile reads a pcre2test output file, see Parse.
+func ParseFile(path string) (*Script, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f, path)
+}
+
+// delimiters are the characters which may start a pattern line.
+const delimiters = "/!\"'`-=_:;,%&@~"
+
+// resultLine matches the output lines of a successful match, e.g.
+// " 0: abc" or "12: <unset>".
+var resultLine = regexp.MustCompile(`^(?: \d|\d\d+)[:+] `)
+
+// Parse reads a pcre2test output file, which holds the input lines of
+// the test followed by the output pcre2test produced for them. name is
+// used in messages. Lines which are not understood are ignored, and
+// patterns and subjects using features which are not supported are
+// marked with the reason in Skip.
+func Parse(r io.Reader, name string) (*Script, error) {
+	s := &Script{Name: name}
+	var p *Pattern
+	var subj *Subject
+	var defaultPattern, defaultSubject string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	lineno := 0
+	next := func() (string, bool) {
+		if !scanner.Scan() {
+			return "", false
+		}
+		lineno++
+		return scanner.Text(), true
+	}
+	for {
+		line, ok := next()
+		if !ok {
+			break
+		}
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			p, subj = nil, nil
+		case strings.HasPrefix(line, "PCRE2 version "):
+		case line[0] == '#':
+			p, subj = nil, nil
+			command, args, _ := strings.Cut(line[1:], " ")
+			switch command {
+			case "pattern":
+				defaultPattern = strings.TrimSpace(args)
+			case "subject":
+				defaultSubject = strings.TrimSpace(args)
+			case "pop", "popcopy":
+				// The subjects which follow are for a pattern which
+				// was saved in another part of the test.
+				p = &Pattern{Line: lineno, Skip: "#" + command + " is not supported"}
+				s.Patterns = append(s.Patterns, p)
+			}
+		case resultLine.MatchString(line) || strings.HasPrefix(line, "No match") ||
+			strings.HasPrefix(line, "Partial match: ") || strings.HasPrefix(line, "Failed: "):
+			switch {
+			case subj != nil:
+				subj.Expected = append(subj.Expected, line)
+			case p != nil && p.Error == "" && strings.HasPrefix(line, "Failed: "):
+				p.Error = line
+			}
+		case line[0] == ' ' || line[0] == '\t' || line[0] == '\\':
+			if p == nil {
+				break
+			}
+			if strings.HasPrefix(trimmed, `\=`) && (len(trimmed) == 2 || trimmed[2] == ' ') {
+				// A comment.
+				subj = nil
+				break
+			}
+			subj = parseSubject(p, trimmed, defaultSubject)
+			subj.Line = lineno
+			p.Subjects = append(p.Subjects, subj)
+		case strings.IndexByte(delimiters, line[0]) >= 0:
+			p, subj = &Pattern{Line: lineno}, nil
+			text, modifiers, closed := splitPattern(line)
+			for !closed {
+				more, ok := next()
+				if !ok {
+					return nil, fmt.Errorf("%s:%d: pattern is not terminated", name, p.Line)
+				}
+				var rest string
+				rest, modifiers, closed = splitPattern(string(line[0]) + more)
+				text += "\n" + rest
+			}
+			p.Pattern = text
+			parsePatternModifiers(p, defaultPattern, modifiers)
+			s.Patterns = append(s.Patterns, p)
+		default:
+			// Other output, e.g. of callouts, which cannot be checked.
+			if subj != nil && subj.Skip == "" {
+				subj.Skip = fmt.Sprintf("unsupported output %q", line)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return s, nil
+}
+
+// splitPattern splits a pattern line, which starts with the delimiter,
+// into the pattern and the modifiers. closed is false if the pattern
+// continues on the next line.
+func splitPattern(line string) (text, modifiers string, closed bool) {
+	delim := line[0]
+	for i := 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case delim:
+			text, modifiers = line[1:i], strings.TrimSpace(line[i+1:])
+			if strings.HasPrefix(modifiers, `\`) {
+				text += `\`
+				modifiers = modifiers[1:]
+			}
+			return text, modifiers, true
+		}
+	}
+	return line[1:], "", false
+}
+
+// compileModifiers maps the pattern modifiers of pcre2test to compile
+// flags.
+var compileModifiers = map[string]uint32{
+	"i":                   pcre2.CASELESS,
+	"caseless":            pcre2.CASELESS,
+	"m":                   pcre2.MULTILINE,
+	"multiline":           pcre2.MULTILINE,
+	"s":                   pcre2.DOTALL,
+	"dotall":              pcre2.DOTALL,
+	"x":                   pcre2.EXTENDED,
+	"extended":            pcre2.EXTENDED,
+	"xx":                  pcre2.EXTENDED_MORE,
+	"extended_more":       pcre2.EXTENDED_MORE,
+	"n":                   pcre2.NO_AUTO_CAPTURE,
+	"no_auto_capture":     pcre2.NO_AUTO_CAPTURE,
+	"utf":                 pcre2.UTF,
+	"ucp":                 pcre2.UCP,
+	"ungreedy":            pcre2.UNGREEDY,
+	"dollar_endonly":      pcre2.DOLLAR_ENDONLY,
+	"firstline":           pcre2.FIRSTLINE,
+	"dupnames":            pcre2.DUPNAMES,
+	"allow_empty_class":   pcre2.ALLOW_EMPTY_CLASS,
+	"alt_bsux":            pcre2.ALT_BSUX,
+	"alt_circumflex":      pcre2.ALT_CIRCUMFLEX,
+	"alt_verbnames":       pcre2.ALT_VERBNAMES,
+	"match_unset_backref": pcre2.MATCH_UNSET_BACKREF,
+	"never_backslash_c":   pcre2.NEVER_BACKSLASH_C,
+	"never_ucp":           pcre2.NEVER_UCP,
+	"never_utf":           pcre2.NEVER_UTF,
+	"no_auto_possess":     pcre2.NO_AUTO_POSSESS,
+	"no_dotstar_anchor":   pcre2.NO_DOTSTAR_ANCHOR,
+	"no_start_optimize":   pcre2.NO_START_OPTIMIZE,
+	"literal":             pcre2.LITERAL,
+}
+
+// matchModifiers maps the subject modifiers of pcre2test to match
+// flags. They may also be given on the pattern, as defaults.
+var matchModifiers = map[string]uint32{
+	"anchored":         pcre2.ANCHORED,
+	"endanchored":      pcre2.ENDANCHORED,
+	"notbol":           pcre2.NOTBOL,
+	"noteol":           pcre2.NOTEOL,
+	"notempty":         pcre2.NOTEMPTY,
+	"notempty_atstart": pcre2.NOTEMPTY_ATSTART,
+	"ps":               pcre2.PARTIAL_SOFT,
+	"partial_soft":     pcre2.PARTIAL_SOFT,
+	"ph":               pcre2.PARTIAL_HARD,
+	"partial_hard":     pcre2.PARTIAL_HARD,
+}
+
+// splitModifiers splits a modifier list into its items. Single letter
+// modifiers may be run together, as in "im".
+func splitModifiers(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		_, known := compileModifiers[item]
+		if known || strings.Trim(item, "imsxng") != "" {
+			if item != "" {
+				items = append(items, item)
+			}
+			continue
+		}
+		for len(item) > 0 {
+			n := 1
+			if strings.HasPrefix(item, "xx") {
+				n = 2
+			}
+			items = append(items, item[:n])
+			item = item[n:]
+		}
+	}
+	return items
+}
+
+// parsePatternModifiers applies the modifiers of a pattern line, after
+// the defaults set with #pattern.
+func parsePatternModifiers(p *Pattern, defaults, modifiers string) {
+	for _, item := range splitModifiers(defaults + "," + modifiers) {
+		if flag, ok := compileModifiers[item]; ok {
+			p.Flags |= flag
+			continue
+		}
+		if flag, ok := matchModifiers[item]; ok {
+			p.matchFlags |= flag
+			continue
+		}
+		switch item {
+		case "g", "global":
+			p.global = true
+		case "jit":
+			p.JIT = true
+		default:
+			if p.Skip == "" {
+				p.Skip = fmt.Sprintf("unsupported pattern modifier %q", item)
+			}
+		}
+	}
+	if p.Skip == "" && strings.Contains(p.Pattern, "(?C") {
+		p.Skip = "callouts are not supported"
+	}
+}
+
+// parseSubject parses a subject line, without the leading and trailing
+// white space, applying the modifiers of the pattern and the defaults
+// set with #subject before its own.
+func parseSubject(p *Pattern, line, defaults string) *Subject {
+	subj := &Subject{Flags: p.matchFlags, Global: p.global}
+	text, modifiers, err := unescape(line, p.Flags&pcre2.UTF != 0)
+	if err != nil {
+		subj.Skip = err.Error()
+		return subj
+	}
+	subj.Text = text
+	for _, item := range splitModifiers(defaults + "," + modifiers) {
+		if flag, ok := matchModifiers[item]; ok {
+			subj.Flags |= flag
+			continue
+		}
+		if value, ok := strings.CutPrefix(item, "offset="); ok {
+			if subj.Offset, err = strconv.Atoi(value); err == nil {
+				continue
+			}
+		}
+		switch item {
+		case "g", "global":
+			subj.Global = true
+		default:
+			if subj.Skip == "" {
+				subj.Skip = fmt.Sprintf("unsupported subject modifier %q", item)
+			}
+		}
+	}
+	if subj.Skip == "" && subj.Global && (subj.Offset != 0 || subj.Flags&(pcre2.PARTIAL_SOFT|pcre2.PARTIAL_HARD) != 0) {
+		subj.Skip = "global matching with an offset or partial matching is not supported"
+	}
+	return subj
+}
+
+// unescape processes the backslash escapes of a subject line, and
+// returns the subject and the modifier list following \=, if any.
+func unescape(line string, utf bool) (subject []byte, modifiers string, err error) {
+	var b []byte
+	// appendChar appends a character given by its code point, which is
+	// UTF-8 encoded in UTF mode.
+	appendChar := func(c uint64) error {
+		switch {
+		case utf && c <= utf8.MaxRune:
+			b = utf8.AppendRune(b, rune(c))
+		case !utf && c <= 0xff:
+			b = append(b, byte(c))
+		default:
+			return fmt.Errorf("character value \\x{%x} is too large", c)
+		}
+		return nil
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if c != '\\' {
+			b = append(b, c)
+			continue
+		}
+		i++
+		if i == len(line) {
+			// A trailing backslash is ignored.
+			break
+		}
+		c = line[i]
+		switch c {
+		case '=':
+			return b, line[i+1:], nil
+		case 'a':
+			b = append(b, '\a')
+		case 'b':
+			b = append(b, '\b')
+		case 'e':
+			b = append(b, 0x1b)
+		case 'f':
+			b = append(b, '\f')
+		case 'n':
+			b = append(b, '\n')
+		case 'r':
+			b = append(b, '\r')
+		case 't':
+			b = append(b, '\t')
+		case 'v':
+			b = append(b, '\v')
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			j := i
+			for j < len(line) && j < i+3 && line[j] >= '0' && line[j] <= '7' {
+				j++
+			}
+			v, _ := strconv.ParseUint(line[i:j], 8, 32)
+			if v <= 0xff {
+				b = append(b, byte(v))
+			} else if err := appendChar(v); err != nil {
+				return nil, "", err
+			}
+			i = j - 1
+		case 'o', 'x':
+			base := 8
+			if c == 'x' {
+				base = 16
+			}
+			if i+1 < len(line) && line[i+1] == '{' {
+				end := strings.IndexByte(line[i+1:], '}')
+				if end < 0 {
+					return nil, "", fmt.Errorf("missing } in \\%c{", c)
+				}
+				v, err := strconv.ParseUint(line[i+2:i+1+end], base, 32)
+				if err != nil {
+					return nil, "", fmt.Errorf("invalid \\%c{} escape", c)
+				}
+				if err := appendChar(v); err != nil {
+					return nil, "", err
+				}
+				i += end + 1
+				break
+			}
+			if c == 'o' {
+				return nil, "", fmt.Errorf("missing { after \\o")
+			}
+			j := i + 1
+			for j < len(line) && j < i+3 && strings.IndexByte("0123456789abcdefABCDEF", line[j]) >= 0 {
+				j++
+			}
+			v, _ := strconv.ParseUint(line[i+1:j], 16, 8)
+			b = append(b, byte(v))
+			i = j - 1
+		case '[':
+			// \[chars]{count} replicates chars.
+			close := strings.Index(line[i:], "]{")
+			end := strings.IndexByte(line[i:], '}')
+			if close < 0 || end < close {
+				return nil, "", fmt.Errorf("invalid \\[...]{n} replication")
+			}
+			n, err := strconv.Atoi(line[i+close+2 : i+end])
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid \\[...]{n} replication")
+			}
+			chars, _, err := unescape(line[i+1:i+close], utf)
+			if err != nil {
+				return nil, "", err
+			}
+			b = append(b, bytes.Repeat(chars, n)...)
+			i += end
+		default:
+			if c < 0x80 && (c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+				return nil, "", fmt.Errorf("unrecognized escape \\%c", c)
+			}
+			b = append(b, c)
+		}
+	}
+	return b, "", nil
+}
@@ -0,0 +1,216 @@
+// Package testutil runs test files in the format of pcre2test, the test
+// program of PCRE2, against package pcre2. This validates the system
+// libpcre2 and the binding against the upstream test corpora, e.g. the
+// testoutput files in the testdata directory of the PCRE2 sources.
+//
+// A pcre2test output file holds the input of the test, patterns and the
+// subjects to match them against, followed by the expected output. A
+// subset of the format is supported: patterns with the common compile
+// modifiers, subjects with backslash escapes and the common match
+// modifiers, and the output of matches, partial matches and errors.
+// Patterns and subjects using other features, e.g. callouts, DFA
+// matching or pattern information, are skipped.
+package testutil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/Jemmic/go-pcre2"
+)
+
+// Failure describes a pattern or subject whose output differs from the
+// expected output.
+type Failure struct {
+	Name    string   // file name of the script
+	Line    int      // line number of the pattern or subject
+	Pattern string   // the pattern
+	Subject []byte   // the subject, or nil for compile failures
+	Want    []string // the expected output lines
+	Got     []string // the output lines of the binding
+}
+
+// Error formats the failure with the lines which differ.
+func (f *Failure) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:%d: /%s/", f.Name, f.Line, f.Pattern)
+	if f.Subject != nil {
+		fmt.Fprintf(&b, " against %q", f.Subject)
+	}
+	b.WriteString("\nwant:\n\t" + strings.Join(f.Want, "\n\t"))
+	b.WriteString("\ngot:\n\t" + strings.Join(f.Got, "\n\t"))
+	return b.String()
+}
+
+// Result summarizes a run of a script.
+type Result struct {
+	Passed   int       // subjects and compile failures with the expected output
+	Skipped  int       // patterns and subjects which were not run
+	Failures []Failure // patterns and subjects with differing output
+}
+
+// Run runs all patterns and subjects of the script which are supported.
+func (s *Script) Run() Result {
+	var r Result
+	for _, p := range s.Patterns {
+		s.runPattern(p, &r)
+	}
+	return r
+}
+
+func (s *Script) runPattern(p *Pattern, r *Result) {
+	if p.Skip != "" {
+		r.Skipped += 1 + len(p.Subjects)
+		return
+	}
+	fail := func(subject []byte, line int, want, got []string) {
+		r.Failures = append(r.Failures, Failure{s.Name, line, p.Pattern, subject, want, got})
+	}
+	re, err := pcre2.Compile(p.Pattern, p.Flags)
+	if err != nil {
+		got := []string{compileFailure(err)}
+		if p.Error != got[0] {
+			fail(nil, p.Line, []string{p.Error}, got)
+			return
+		}
+		r.Passed++
+		return
+	}
+	defer re.Free()
+	if p.Error != "" {
+		fail(nil, p.Line, []string{p.Error}, nil)
+		return
+	}
+	if p.JIT {
+		// Without JIT support, matching falls back to the interpreter,
+		// with the same results.
+		re.JITCompile(pcre2.JIT_COMPLETE)
+	}
+	utf := p.Flags&pcre2.UTF != 0
+	for _, subj := range p.Subjects {
+		if subj.Skip != "" {
+			r.Skipped++
+			continue
+		}
+		got := output(re, subj, utf)
+		if !equalOutput(subj.Expected, got) {
+			fail(subj.Text, subj.Line, subj.Expected, got)
+			continue
+		}
+		r.Passed++
+	}
+}
+
+// compileFailure formats a compile error like pcre2test.
+func compileFailure(err error) string {
+	var ce *pcre2.CompileError
+	if errors.As(err, &ce) {
+		return fmt.Sprintf("Failed: error %d at offset %d: %s", ce.ErrorNum, ce.Offset, ce.Message)
+	}
+	return "Failed: " + err.Error()
+}
+
+// output returns the output lines of pcre2test for matching the subject.
+func output(re *pcre2.Regexp, subj *Subject, utf bool) []string {
+	m := re.NewMatcher()
+	defer m.Free()
+	if subj.Global {
+		var lines []string
+		for m := range re.Iterate(subj.Text, subj.Flags) {
+			lines = appendGroups(lines, m, utf)
+		}
+		if lines != nil {
+			return lines
+		}
+		// Report why there is no match.
+	}
+	m.MatchWithOptions(subj.Text, pcre2.MatchOptions{Flags: subj.Flags, Offset: subj.Offset})
+	switch rc := m.ReturnCode(); {
+	case m.Partial():
+		return []string{"Partial match: " + escape(m.Group(0), utf)}
+	case rc == pcre2.ERROR_NOMATCH:
+		return []string{"No match"}
+	case rc < 0:
+		msg := m.GetError().Error()
+		var me *pcre2.MatchError
+		if errors.As(m.GetError(), &me) {
+			msg = me.Message
+		}
+		return []string{fmt.Sprintf("Failed: error %d: %s", rc, msg)}
+	}
+	return appendGroups(nil, m, utf)
+}
+
+// appendGroups appends the output lines of a match, one per capture
+// group up to the last one which is set.
+func appendGroups(lines []string, m *pcre2.Matcher, utf bool) []string {
+	n := m.ReturnCode()
+	if n == 0 {
+		n = m.Groups() + 1
+	}
+	for i := range n {
+		text := "<unset>"
+		if m.Present(i) {
+			text = escape(m.Group(i), utf)
+		}
+		lines = append(lines, fmt.Sprintf("%2d: %s", i, text))
+	}
+	return lines
+}
+
+// equalOutput compares the expected output with the output of the
+// binding. pcre2test adds the offset to the messages of UTF errors,
+// which is ignored.
+func equalOutput(want, got []string) bool {
+	if len(want) != len(got) {
+		return false
+	}
+	for i := range want {
+		if want[i] != got[i] && !(strings.Contains(got[i], "UTF-8 error") &&
+			strings.HasPrefix(want[i], got[i]+" at offset ")) {
+			return false
+		}
+	}
+	return true
+}
+
+// escape formats captured text like pcre2test: printable ASCII as is,
+// other characters as \xhh, or \x{hh} in UTF mode.
+func escape(text []byte, utf bool) string {
+	var b strings.Builder
+	for len(text) > 0 {
+		c, size := rune(text[0]), 1
+		if utf {
+			c, size = utf8.DecodeRune(text)
+		}
+		switch {
+		case c >= 0x20 && c < 0x7f:
+			b.WriteRune(c)
+		case utf:
+			fmt.Fprintf(&b, "\\x{%02x}", c)
+		default:
+			fmt.Fprintf(&b, "\\x%02x", c)
+		}
+		text = text[size:]
+	}
+	return b.String()
+}
+
+// RunFile runs the pcre2test output file at path, and reports every
+// failure as an error of t.
+func RunFile(t testing.TB, path string) Result {
+	t.Helper()
+	s, err := ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := s.Run()
+	for _, f := range r.Failures {
+		t.Error(f.Error())
+	}
+	t.Logf("%s: %d passed, %d skipped, %d failed", path, r.Passed, r.Skipped, len(r.Failures))
+	return r
+}
@@ -0,0 +1,96 @@
+package testutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Jemmic/go-pcre2"
+)
+
+func TestRunFile(t *testing.T) {
+	r := RunFile(t, "testdata/sample.txt")
+	assert.Equal(t, 24, r.Passed)
+	assert.Equal(t, 6, r.Skipped)
+}
+
+func TestParse(t *testing.T) {
+	s, err := Parse(strings.NewReader(`PCRE2 version 10.42 2022-12-11
+#pattern i
+/a(b)?
+c/x,g
+    A\x{62}C\=notbol
+ 0: abc
+ 1: b
+    \= A comment
+    x\=offset=1,ps
+Partial match: x
+
+/a(/
+Failed: error 114 at offset 2: missing closing parenthesis
+
+/a/B
+    a\=aftertext
+`), "test")
+	if !assert.NoError(t, err) {
+		return
+	}
+	if !assert.Len(t, s.Patterns, 3) {
+		return
+	}
+	p := s.Patterns[0]
+	assert.Equal(t, 3, p.Line)
+	assert.Equal(t, "a(b)?\nc", p.Pattern)
+	assert.Equal(t, uint32(pcre2.CASELESS|pcre2.EXTENDED), p.Flags)
+	if assert.Len(t, p.Subjects, 2) {
+		subj := p.Subjects[0]
+		assert.Equal(t, 5, subj.Line)
+		assert.Equal(t, "AbC", string(subj.Text))
+		assert.True(t, subj.Global)
+		assert.Equal(t, []string{" 0: abc", " 1: b"}, subj.Expected)
+		assert.Equal(t, 1, p.Subjects[1].Offset)
+		assert.Equal(t, []string{"Partial match: x"}, p.Subjects[1].Expected)
+		assert.NotEmpty(t, p.Subjects[1].Skip, "global partial matching")
+	}
+	assert.Equal(t, "Failed: error 114 at offset 2: missing closing parenthesis", s.Patterns[1].Error)
+	assert.Equal(t, `unsupported pattern modifier "B"`, s.Patterns[2].Skip)
+	assert.Equal(t, `unsupported subject modifier "aftertext"`, s.Patterns[2].Subjects[0].Skip)
+}
+
+func TestRunFailure(t *testing.T) {
+	s, err := Parse(strings.NewReader("/a+/\n    xaa\n 0: a\n"), "test")
+	assert.NoError(t, err)
+	r := s.Run()
+	if assert.Len(t, r.Failures, 1) {
+		f := r.Failures[0]
+		assert.Equal(t, 2, f.Line)
+		assert.Equal(t, []string{" 0: aa"}, f.Got)
+		assert.Equal(t, "test:2: /a+/ against \"xaa\"\nwant:\n\t 0: a\ngot:\n\t 0: aa", f.Error())
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	for _, tc := range []struct {
+		line, want, modifiers string
+		utf                   bool
+	}{
+		{`a\tb\n`, "a\tb\n", "", false},
+		{`\x41\x{42}\103\o{104}`, "ABCD", "", false},
+		{`\x{e9}`, "\xe9", "", false},
+		{`\x{e9}\xe9`, "é\xe9", "", true},
+		{`\[ab]{3}x`, "abababx", "", false},
+		{`a\=notbol,g`, "a", "notbol,g", false},
+		{`a\/\\\`, `a/\`, "", false},
+	} {
+		got, modifiers, err := unescape(tc.line, tc.utf)
+		if assert.NoError(t, err, tc.line) {
+			assert.Equal(t, tc.want, string(got), tc.line)
+			assert.Equal(t, tc.modifiers, modifiers, tc.line)
+		}
+	}
+	for _, line := range []string{`\x{100}`, `\q`, `\o12`, `\[ab]x`} {
+		_, _, err := unescape(line, false)
+		assert.Error(t, err, line)
+	}
+}
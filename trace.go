@@ -0,0 +1,160 @@
+package pcre2
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TraceEvent records a single step of a traced match, i.e. the matcher
+// reaching an item of the pattern at some subject position.
+type TraceEvent struct {
+	Start         int    `json:"start"`          // subject offset of the match attempt
+	Position      int    `json:"position"`       // current subject offset
+	PatternOffset int    `json:"pattern_offset"` // offset of the item in the pattern
+	Item          string `json:"item"`           // pattern text of the item
+	NewAttempt    bool   `json:"new_attempt,omitempty"`
+	Backtrack     bool   `json:"backtrack,omitempty"` // reached by backtracking
+}
+
+// Trace is the structured record of a traced match.
+type Trace struct {
+	Pattern    string       `json:"pattern"`
+	Subject    string       `json:"subject"`
+	Result     int          `json:"result"` // return code of the match
+	Attempts   int          `json:"attempts"`
+	Backtracks int          `json:"backtracks"`
+	Truncated  bool         `json:"truncated,omitempty"` // events were dropped
+	Events     []TraceEvent `json:"events"`
+}
+
+// WriteText dumps the trace as human readable text, one event per line.
+// New match attempts are marked with '>', backtracks with '<'.
+func (t *Trace) WriteText(w io.Writer) error {
+	result := "match"
+	if !matched(t.Result) {
+		result = fmt.Sprintf("no match (%d)", t.Result)
+	}
+	if _, err := fmt.Fprintf(w, "trace of %q against %q: %s, %d attempts, %d backtracks\n",
+		t.Pattern, t.Subject, result, t.Attempts, t.Backtracks); err != nil {
+		return err
+	}
+	for _, e := range t.Events {
+		mark := ' '
+		switch {
+		case e.NewAttempt:
+			mark = '>'
+		case e.Backtrack:
+			mark = '<'
+		}
+		if _, err := fmt.Fprintf(w, "%c %6d %6d %6d  %s\n",
+			mark, e.Start, e.Position, e.PatternOffset, e.Item); err != nil {
+			return err
+		}
+	}
+	if t.Truncated {
+		_, err := fmt.Fprintln(w, "... (truncated)")
+		return err
+	}
+	return nil
+}
+
+// String returns the text dump of the trace.
+func (t *Trace) String() string {
+	var b strings.Builder
+	t.WriteText(&b)
+	return b.String()
+}
+
+// JSON returns the trace encoded as JSON.
+func (t *Trace) JSON() ([]byte, error) {
+	return json.Marshal(t)
+}
+
+// DefaultMaxTraceEvents is the number of events a Tracer records per
+// match unless changed with SetMaxEvents.
+const DefaultMaxTraceEvents = 10000
+
+// Tracer matches a pattern compiled with AUTO_CALLOUT and records every
+// step taken by the matcher: the subject offsets tried and where it
+// backtracked. This helps to understand why a pattern is slow, e.g. in
+// case of catastrophic backtracking. Unlike the Profiler, which
+// aggregates, the Tracer keeps the individual steps, so it is meant for
+// small subjects.
+type Tracer struct {
+	re        *Regexp
+	m         *Matcher
+	maxEvents int
+	trace     *Trace
+}
+
+// NewTracer compiles the pattern with AUTO_CALLOUT in addition to flags
+// and returns a Tracer for it.
+func NewTracer(pattern string, flags uint32) (*Tracer, error) {
+	re, err := Compile(pattern, flags|AUTO_CALLOUT)
+	if err != nil {
+		return nil, err
+	}
+	tr := &Tracer{
+		re:        re,
+		m:         re.NewMatcher(),
+		maxEvents: DefaultMaxTraceEvents,
+	}
+	tr.m.SetCallout(tr.callout)
+	return tr, nil
+}
+
+// SetMaxEvents limits the number of events recorded per match. Further
+// events are only counted, and the trace is marked as truncated.
+func (tr *Tracer) SetMaxEvents(n int) {
+	tr.maxEvents = n
+}
+
+func (tr *Tracer) callout(b *CalloutBlock) int {
+	t := tr.trace
+	e := TraceEvent{
+		Start:         b.StartMatch,
+		Position:      b.CurrentPosition,
+		PatternOffset: b.PatternPosition,
+		NewAttempt:    b.Flags&CALLOUT_STARTMATCH != 0,
+		Backtrack:     b.Flags&CALLOUT_BACKTRACK != 0,
+	}
+	if e.NewAttempt {
+		t.Attempts++
+	}
+	if e.Backtrack {
+		t.Backtracks++
+	}
+	if len(t.Events) >= tr.maxEvents {
+		t.Truncated = true
+		return 0
+	}
+	end := b.PatternPosition + b.NextItemLength
+	if end > len(tr.re.Pattern) {
+		end = len(tr.re.Pattern)
+	}
+	e.Item = tr.re.Pattern[b.PatternPosition:end]
+	t.Events = append(t.Events, e)
+	return 0
+}
+
+// Trace matches the subject and returns the trace of the match.
+func (tr *Tracer) Trace(subject []byte, flags uint32) *Trace {
+	tr.trace = &Trace{Pattern: tr.re.Pattern, Subject: string(subject)}
+	tr.trace.Result = tr.m.Exec(subject, flags)
+	return tr.trace
+}
+
+// TraceString is like Trace, but with a string subject.
+func (tr *Tracer) TraceString(subject string, flags uint32) *Trace {
+	tr.trace = &Trace{Pattern: tr.re.Pattern, Subject: subject}
+	tr.trace.Result = tr.m.ExecString(subject, flags)
+	return tr.trace
+}
+
+// Free releases the underlying C resources.
+func (tr *Tracer) Free() error {
+	tr.m.Free()
+	return tr.re.Free()
+}
@@ -0,0 +1,41 @@
+package pcre2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracer(t *testing.T) {
+	tr, err := NewTracer(`^(a+)+$`, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer tr.Free()
+
+	trace := tr.TraceString("aa", 0)
+	assert.Equal(t, 2, trace.Result)
+	assert.Equal(t, 1, trace.Attempts)
+	if assert.NotEmpty(t, trace.Events) {
+		assert.True(t, trace.Events[0].NewAttempt)
+		assert.Equal(t, "^", trace.Events[0].Item)
+	}
+
+	trace = tr.Trace([]byte("aaab"), 0)
+	assert.Equal(t, ERROR_NOMATCH, trace.Result)
+	assert.NotZero(t, trace.Backtracks)
+	assert.Contains(t, trace.String(), `trace of "^(a+)+$" against "aaab": no match (-1)`)
+
+	data, err := trace.JSON()
+	assert.NoError(t, err)
+	var decoded Trace
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, *trace, decoded)
+
+	tr.SetMaxEvents(3)
+	trace = tr.TraceString("aaab", 0)
+	assert.Len(t, trace.Events, 3)
+	assert.True(t, trace.Truncated)
+	assert.Contains(t, trace.String(), "(truncated)")
+}
@@ -0,0 +1,197 @@
+package pcre2
+
+import "fmt"
+
+// The Try methods are counterparts of methods which panic on misuse,
+// e.g. on a freed Regexp or Matcher, or with a group number out of
+// range. They return ErrInvalidRegexp, ErrMatcherFreed or
+// ErrGroupOutOfRange instead, so that library code can handle such bugs
+// without crashing. The counterpart of NewMatcher is NewMatcherErr.
+
+// TryGroups is like Groups, but returns ErrInvalidRegexp instead of
+// panicking if re is nil or has been freed.
+func (re *Regexp) TryGroups() (int, error) {
+	if _, err := re.validRegexpPtr(); err != nil {
+		return 0, err
+	}
+	return re.Groups(), nil
+}
+
+// TryMatcher is like Matcher, but returns an error instead of panicking
+// on misuse. Errors of the match itself, see GetError, are returned
+// along with the matcher, which must be freed in any case.
+func (re *Regexp) TryMatcher(subject []byte, flags uint32) (*Matcher, error) {
+	m, err := re.NewMatcherErr()
+	if err != nil {
+		return nil, err
+	}
+	m.Match(subject, flags)
+	return m, m.matchError()
+}
+
+// TryMatcherString is like TryMatcher, but with a string subject.
+func (re *Regexp) TryMatcherString(subject string, flags uint32) (*Matcher, error) {
+	m, err := re.NewMatcherErr()
+	if err != nil {
+		return nil, err
+	}
+	m.MatchString(subject, flags)
+	return m, m.matchError()
+}
+
+// usable returns an error if the matcher cannot be used.
+func (m *Matcher) usable() error {
+	if m == nil {
+		return ErrMatcherFreed
+	}
+	if _, err := m.re.validRegexpPtr(); err != nil {
+		return err
+	}
+	if m.mData == nil {
+		return ErrMatcherFreed
+	}
+	return nil
+}
+
+// checkGroup returns an error if the matcher cannot be used, or the
+// group does not exist.
+func (m *Matcher) checkGroup(group int) error {
+	if err := m.usable(); err != nil {
+		return err
+	}
+	if group < 0 || group > m.groups {
+		return fmt.Errorf("%w: %d", ErrGroupOutOfRange, group)
+	}
+	return nil
+}
+
+// TryMatch is like Match, but returns an error instead of panicking on
+// misuse. Errors of the match itself, see GetError, are returned as
+// well; not matching is no error.
+func (m *Matcher) TryMatch(subject []byte, flags uint32) (bool, error) {
+	if err := m.usable(); err != nil {
+		return false, err
+	}
+	return m.Match(subject, flags), m.matchError()
+}
+
+// TryMatchString is like TryMatch, but with a string subject.
+func (m *Matcher) TryMatchString(subject string, flags uint32) (bool, error) {
+	if err := m.usable(); err != nil {
+		return false, err
+	}
+	return m.MatchString(subject, flags), m.matchError()
+}
+
+// TryExec is like Exec, but returns an error instead of panicking on
+// misuse. Errors of the match itself are left in the returned code.
+func (m *Matcher) TryExec(subject []byte, flags uint32) (int, error) {
+	if err := m.usable(); err != nil {
+		return 0, err
+	}
+	return m.Exec(subject, flags), nil
+}
+
+// TryExecString is like TryExec, but with a string subject.
+func (m *Matcher) TryExecString(subject string, flags uint32) (int, error) {
+	if err := m.usable(); err != nil {
+		return 0, err
+	}
+	return m.ExecString(subject, flags), nil
+}
+
+// matchError is like GetError, but returns nil if the subject did not
+// match.
+func (m *Matcher) matchError() error {
+	if m.rc == ERROR_NOMATCH {
+		return nil
+	}
+	return m.GetError()
+}
+
+// TryGroup is like Group, but returns an error instead of panicking on
+// misuse. A group which is not present in the last match, or any group
+// if there was no match, is returned as nil without error.
+func (m *Matcher) TryGroup(group int) ([]byte, error) {
+	if err := m.checkGroup(group); err != nil {
+		return nil, err
+	}
+	if !m.matches || !m.Present(group) {
+		return nil, nil
+	}
+	return m.Group(group), nil
+}
+
+// TryGroupString is like TryGroup, but returns a string.
+func (m *Matcher) TryGroupString(group int) (string, error) {
+	if err := m.checkGroup(group); err != nil {
+		return "", err
+	}
+	if !m.matches || !m.Present(group) {
+		return "", nil
+	}
+	return m.GroupString(group), nil
+}
+
+// TryIndex is like Index, but returns an error instead of panicking if
+// the matcher cannot be used.
+func (m *Matcher) TryIndex() ([]int, error) {
+	if err := m.usable(); err != nil {
+		return nil, err
+	}
+	return m.Index(), nil
+}
+
+// TryExtract is like Extract, but returns an error instead of panicking
+// if the matcher cannot be used.
+func (m *Matcher) TryExtract() ([][]byte, error) {
+	if err := m.usable(); err != nil {
+		return nil, err
+	}
+	return m.Extract(), nil
+}
+
+// TryExtractString is like TryExtract, but returns strings.
+func (m *Matcher) TryExtractString() ([]string, error) {
+	if err := m.usable(); err != nil {
+		return nil, err
+	}
+	return m.ExtractString(), nil
+}
+
+// TryGroupIndices is like GroupIndices, but returns an error instead of
+// panicking on misuse.
+func (m *Matcher) TryGroupIndices(group int) ([]int, error) {
+	if err := m.checkGroup(group); err != nil {
+		return nil, err
+	}
+	if !m.matches || !m.Present(group) {
+		return nil, nil
+	}
+	return m.GroupIndices(group), nil
+}
+
+// TryNamed is like Named, but returns an error instead of panicking if
+// the matcher cannot be used.
+func (m *Matcher) TryNamed(group string) ([]byte, error) {
+	if err := m.usable(); err != nil {
+		return nil, err
+	}
+	n, err := m.name2index(group)
+	if err != nil {
+		return nil, err
+	}
+	return m.TryGroup(n)
+}
+
+// TryNamedString is like TryNamed, but returns a string.
+func (m *Matcher) TryNamedString(group string) (string, error) {
+	if err := m.usable(); err != nil {
+		return "", err
+	}
+	n, err := m.name2index(group)
+	if err != nil {
+		return "", err
+	}
+	return m.TryGroupString(n)
+}
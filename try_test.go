@@ -0,0 +1,107 @@
+package pcre2
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTry(t *testing.T) {
+	re := MustCompile(`(?<word>\w+)(!)?`, 0)
+	n, err := re.TryGroups()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	m := re.NewMatcher()
+	ok, err := m.TryMatchString("hi", 0)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	g, err := m.TryGroup(1)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hi"), g)
+	g, err = m.TryGroup(2)
+	assert.NoError(t, err)
+	assert.Nil(t, g)
+	s, err := m.TryGroupString(0)
+	assert.NoError(t, err)
+	assert.Equal(t, "hi", s)
+	loc, err := m.TryGroupIndices(1)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 2}, loc)
+	g, err = m.TryNamed("word")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hi"), g)
+	_, err = m.TryNamed("nope")
+	assert.Error(t, err)
+	s, err = m.TryNamedString("word")
+	assert.NoError(t, err)
+	assert.Equal(t, "hi", s)
+	loc, err = m.TryIndex()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 2}, loc)
+	all, err := m.TryExtractString()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"hi", "hi", ""}, all)
+	rc, err := m.TryExecString("x!", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, rc)
+	ball, err := m.TryExtract()
+	assert.NoError(t, err)
+	assert.Len(t, ball, 3)
+
+	_, err = m.TryGroup(3)
+	assert.True(t, errors.Is(err, ErrGroupOutOfRange))
+	_, err = m.TryGroupString(-1)
+	assert.True(t, errors.Is(err, ErrGroupOutOfRange))
+
+	ok, err = m.TryMatch([]byte("!"), 0)
+	assert.False(t, ok)
+	assert.NoError(t, err)
+	g, err = m.TryGroup(1)
+	assert.NoError(t, err)
+	assert.Nil(t, g)
+
+	m.Free()
+	_, err = m.TryMatch([]byte("x"), 0)
+	assert.Equal(t, ErrMatcherFreed, err)
+	_, err = m.TryGroup(0)
+	assert.Equal(t, ErrMatcherFreed, err)
+	_, err = m.TryExec([]byte("x"), 0)
+	assert.Equal(t, ErrMatcherFreed, err)
+	_, err = m.TryIndex()
+	assert.Equal(t, ErrMatcherFreed, err)
+	_, err = m.TryExtract()
+	assert.Equal(t, ErrMatcherFreed, err)
+	_, err = m.TryNamedString("word")
+	assert.Equal(t, ErrMatcherFreed, err)
+
+	m, err = re.TryMatcherString("yo", 0)
+	if assert.NoError(t, err) {
+		assert.True(t, m.Matches())
+		m.Free()
+	}
+
+	re.Free()
+	_, err = re.TryGroups()
+	assert.Equal(t, ErrInvalidRegexp, err)
+	_, err = re.TryMatcher([]byte("x"), 0)
+	assert.Equal(t, ErrInvalidRegexp, err)
+	var zero Matcher
+	_, err = zero.TryMatchString("x", 0)
+	assert.Equal(t, ErrInvalidRegexp, err)
+}
+
+func TestTryMatchError(t *testing.T) {
+	re := MustCompile(`(a+)+$`, 0)
+	defer re.Free()
+	m := re.NewMatcher()
+	defer m.Free()
+	ctx := NewMatchContext()
+	defer ctx.Free()
+	ctx.SetMatchLimit(10)
+	m.SetMatchContext(ctx)
+	ok, err := m.TryMatchString("aaaaaaaaaaaaaaaaaaaab", 0)
+	assert.False(t, ok)
+	assert.IsType(t, &MatchError{}, err)
+}
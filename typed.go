@@ -0,0 +1,110 @@
+package pcre2
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// CaptureError is returned by the typed capture helpers, e.g. GroupInt,
+// when a capture group cannot be converted. Err is ErrGroupNotPresent,
+// or the error of the conversion, e.g. a *strconv.NumError.
+type CaptureError struct {
+	Group int
+	Name  string // name of the group, for the Named helpers
+	Text  string // the captured text
+	Err   error
+}
+
+// Error converts the capture error to a string.
+func (e *CaptureError) Error() string {
+	group := strconv.Itoa(e.Group)
+	if e.Name != "" {
+		group = strconv.Quote(e.Name)
+	}
+	if e.Err == ErrGroupNotPresent {
+		return fmt.Sprintf("pcre2: capture group %s not present", group)
+	}
+	return fmt.Sprintf("pcre2: capture group %s: cannot convert %q: %v", group, e.Text, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *CaptureError) Unwrap() error {
+	return e.Err
+}
+
+// convertGroup converts the text of the capture group with parse. name
+// is the name the group was given by, if any.
+func convertGroup[T any](m *Matcher, group int, name string, parse func(string) (T, error)) (T, error) {
+	var zero T
+	if err := m.checkGroup(group); err != nil {
+		return zero, err
+	}
+	if !m.matches || !m.Present(group) {
+		return zero, &CaptureError{group, name, "", ErrGroupNotPresent}
+	}
+	text := m.GroupString(group)
+	v, err := parse(text)
+	if err != nil {
+		return zero, &CaptureError{group, name, text, err}
+	}
+	return v, nil
+}
+
+// convertNamed is like convertGroup, but looks up the group by name.
+func convertNamed[T any](m *Matcher, name string, parse func(string) (T, error)) (T, error) {
+	group, err := m.name2index(name)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return convertGroup(m, group, name, parse)
+}
+
+func parseInt(s string) (int, error) {
+	return strconv.Atoi(s)
+}
+
+func parseFloat(s string) (float64, error) {
+	return strconv.ParseFloat(s, 64)
+}
+
+func timeParser(layout string) func(string) (time.Time, error) {
+	return func(s string) (time.Time, error) {
+		return time.Parse(layout, s)
+	}
+}
+
+// GroupInt parses the numbered capture group of the last match as a
+// decimal integer, like strconv.Atoi. The error is a *CaptureError if
+// the group is not present or is no valid integer.
+func (m *Matcher) GroupInt(group int) (int, error) {
+	return convertGroup(m, group, "", parseInt)
+}
+
+// GroupFloat parses the numbered capture group of the last match as a
+// floating-point number, like strconv.ParseFloat.
+func (m *Matcher) GroupFloat(group int) (float64, error) {
+	return convertGroup(m, group, "", parseFloat)
+}
+
+// GroupTime parses the numbered capture group of the last match as a
+// time in the given layout, like time.Parse.
+func (m *Matcher) GroupTime(group int, layout string) (time.Time, error) {
+	return convertGroup(m, group, "", timeParser(layout))
+}
+
+// NamedInt is like GroupInt, but for a named capture group.
+func (m *Matcher) NamedInt(name string) (int, error) {
+	return convertNamed(m, name, parseInt)
+}
+
+// NamedFloat is like GroupFloat, but for a named capture group.
+func (m *Matcher) NamedFloat(name string) (float64, error) {
+	return convertNamed(m, name, parseFloat)
+}
+
+// NamedTime is like GroupTime, but for a named capture group.
+func (m *Matcher) NamedTime(name, layout string) (time.Time, error) {
+	return convertNamed(m, name, timeParser(layout))
+}
@@ -0,0 +1,59 @@
+package pcre2
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedCaptures(t *testing.T) {
+	re := MustCompile(`^(?<time>\S+) (?<status>\d+|-) (?<seconds>[\d.]+)(?: (?<size>\d+))?$`, 0)
+	defer re.Free()
+	m := re.MatcherString("2024-05-01T10:00:00Z 404 0.25", 0)
+	defer m.Free()
+
+	status, err := m.GroupInt(2)
+	assert.NoError(t, err)
+	assert.Equal(t, 404, status)
+	status, err = m.NamedInt("status")
+	assert.NoError(t, err)
+	assert.Equal(t, 404, status)
+
+	seconds, err := m.NamedFloat("seconds")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.25, seconds)
+	seconds, err = m.GroupFloat(3)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.25, seconds)
+
+	when, err := m.NamedTime("time", time.RFC3339)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), when)
+	_, err = m.GroupTime(1, time.DateOnly)
+	assert.Error(t, err)
+
+	_, err = m.NamedInt("size")
+	assert.ErrorIs(t, err, ErrGroupNotPresent)
+	assert.EqualError(t, err, `pcre2: capture group "size" not present`)
+
+	_, err = m.GroupInt(5)
+	assert.ErrorIs(t, err, ErrGroupOutOfRange)
+	_, err = m.NamedInt("other")
+	assert.Error(t, err)
+
+	assert.True(t, m.MatchString("2024-05-01T10:00:00Z - 1.5", 0))
+	_, err = m.GroupInt(2)
+	var cerr *CaptureError
+	assert.ErrorAs(t, err, &cerr)
+	assert.Equal(t, 2, cerr.Group)
+	assert.Equal(t, "-", cerr.Text)
+	assert.True(t, errors.Is(err, strconv.ErrSyntax))
+	assert.EqualError(t, err, `pcre2: capture group 2: cannot convert "-": strconv.Atoi: parsing "-": invalid syntax`)
+
+	assert.False(t, m.MatchString("nothing", 0))
+	_, err = m.GroupInt(2)
+	assert.ErrorIs(t, err, ErrGroupNotPresent)
+}
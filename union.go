@@ -0,0 +1,106 @@
+package pcre2
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// UnionRegexp is a single compiled pattern which matches any of several
+// patterns, built by Union. It can be used like any Regexp; Which tells
+// which of the patterns a match belongs to.
+//
+// The alternatives are combined in a branch reset group, so that the
+// capture groups of each pattern keep their numbers, and numbered back
+// references in the patterns still work. Each alternative ends with a
+// (*MARK) naming its index, which PCRE2 reports with the match.
+type UnionRegexp struct {
+	*Regexp
+	patterns []string
+}
+
+// Union compiles the alternation of the patterns. Like in any
+// alternation, the first pattern which matches at a position wins.
+func Union(patterns ...string) (*UnionRegexp, error) {
+	return UnionWithFlags(0, patterns...)
+}
+
+// UnionWithFlags is like Union, but compiles with the given flags. Each
+// pattern is checked on its own first, so that the error of an invalid
+// one names it. Patterns must not start with option verbs like (*UTF),
+// which only work at the start of the whole pattern, and named groups
+// may only be used by more than one pattern with the same numbers.
+func UnionWithFlags(flags uint32, patterns ...string) (*UnionRegexp, error) {
+	for i, pattern := range patterns {
+		re, err := Compile(pattern, flags)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %d (%q): %w", i, pattern, err)
+		}
+		re.Free()
+	}
+	re, err := Compile(unionPattern(patterns), flags)
+	if err != nil {
+		return nil, err
+	}
+	return &UnionRegexp{re, append([]string(nil), patterns...)}, nil
+}
+
+// unionPattern returns the alternation of the patterns. Each pattern is
+// wrapped in its own group, so that options set inside it do not carry
+// over to the next one. Before the group is closed, \E ends a \Q quote
+// which is still open, and a line break ends a # comment in extended
+// mode. The line break is put into a class repeated zero times, so that
+// it matches nothing whether it is a comment, a class or left over from
+// one.
+func unionPattern(patterns []string) string {
+	if len(patterns) == 0 {
+		return `(*FAIL)`
+	}
+	var b strings.Builder
+	b.WriteString("(?|")
+	for i, pattern := range patterns {
+		if i > 0 {
+			b.WriteByte('|')
+		}
+		fmt.Fprintf(&b, "(?:%s\\E[\r\n]{0})(*MARK:%d)", pattern, i)
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// Patterns returns the patterns of the union, in the order they were
+// given to Union.
+func (u *UnionRegexp) Patterns() []string {
+	return append([]string(nil), u.patterns...)
+}
+
+// Which returns the index of the pattern which matched in the last match
+// of the Matcher, or -1 if there is no match. The Matcher must have been
+// created for the union.
+func (u *UnionRegexp) Which(m *Matcher) int {
+	if !m.matches || m.re != u.Regexp {
+		return -1
+	}
+	mark, _ := m.Mark()
+	i, err := strconv.Atoi(mark)
+	if err != nil || i < 0 || i >= len(u.patterns) {
+		return -1
+	}
+	return i
+}
+
+// FindPattern returns the index of the pattern of the first match in
+// the subject, together with the start and end of the match, or -1 and
+// nil if there is no match.
+func (u *UnionRegexp) FindPattern(subject []byte, flags uint32) (int, []int) {
+	m := u.Matcher(subject, flags)
+	defer m.Free()
+	return u.Which(m), m.Index()
+}
+
+// FindPatternString is like FindPattern, but with a string subject.
+func (u *UnionRegexp) FindPatternString(subject string, flags uint32) (int, []int) {
+	m := u.MatcherString(subject, flags)
+	defer m.Free()
+	return u.Which(m), m.Index()
+}
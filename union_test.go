@@ -0,0 +1,81 @@
+package pcre2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnion(t *testing.T) {
+	u, err := Union(`(\d+)-(\d+)`, `(?i)error`, `(\w)\1`, `x`)
+	assert.NoError(t, err)
+	defer u.Free()
+	assert.Equal(t, []string{`(\d+)-(\d+)`, `(?i)error`, `(\w)\1`, `x`}, u.Patterns())
+
+	id, loc := u.FindPatternString("page 10-20", 0)
+	assert.Equal(t, 0, id)
+	assert.Equal(t, []int{5, 10}, loc)
+
+	id, loc = u.FindPatternString("an ERROR", 0)
+	assert.Equal(t, 1, id)
+	assert.Equal(t, []int{3, 8}, loc)
+
+	// The back reference of the third pattern still refers to its own
+	// first group, and options of the second do not carry over.
+	id, loc = u.FindPattern([]byte("ab Yummy X"), 0)
+	assert.Equal(t, 2, id)
+	assert.Equal(t, []int{5, 7}, loc)
+
+	id, loc = u.FindPatternString("---", 0)
+	assert.Equal(t, -1, id)
+	assert.Nil(t, loc)
+
+	var ids []int
+	var groups []string
+	for m := range u.IterateString("1-2 xx error X x", 0) {
+		ids = append(ids, u.Which(m))
+		groups = append(groups, m.GroupString(1))
+	}
+	assert.Equal(t, []int{0, 2, 1, 3}, ids)
+	assert.Equal(t, []string{"1", "x", "", ""}, groups)
+}
+
+func TestUnionQuoteAndComment(t *testing.T) {
+	u, err := UnionWithFlags(EXTENDED, "a # letter a", "b")
+	if assert.NoError(t, err) {
+		id, loc := u.FindPatternString("xb", 0)
+		assert.Equal(t, 1, id)
+		assert.Equal(t, []int{1, 2}, loc)
+		u.Free()
+	}
+
+	u, err = Union(`x\Qy`, `(?x) c # letter c`, "\n")
+	if assert.NoError(t, err) {
+		for subject, want := range map[string][]int{
+			"axy": {0, 1, 3},
+			"ac":  {1, 1, 2},
+			"a\n": {2, 1, 2},
+			"x\n": {2, 1, 2},
+		} {
+			id, loc := u.FindPatternString(subject, 0)
+			assert.Equal(t, want, append([]int{id}, loc...), subject)
+		}
+		u.Free()
+	}
+}
+
+func TestUnionError(t *testing.T) {
+	_, err := Union(`a`, `b(`)
+	assert.ErrorContains(t, err, `pattern 1 ("b(")`)
+	var cerr *CompileError
+	assert.ErrorAs(t, err, &cerr)
+}
+
+func TestUnionEmpty(t *testing.T) {
+	u, err := Union()
+	assert.NoError(t, err)
+	defer u.Free()
+	id, loc := u.FindPatternString("anything", 0)
+	assert.Equal(t, -1, id)
+	assert.Nil(t, loc)
+}
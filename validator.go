@@ -0,0 +1,103 @@
+package pcre2
+
+import (
+	"sync"
+)
+
+// Resource limits applied by a Validator. Validation patterns are
+// expected to be simple, so these are far below the PCRE2 defaults.
+const (
+	validatorMatchLimit = 100000
+	validatorDepthLimit = 1000
+	validatorHeapLimit  = 1024 // in KiB
+)
+
+// Validator checks that input matches a pattern in its entirety.
+// The pattern is compiled with ANCHORED, ENDANCHORED and UTF, the input
+// is always checked for valid UTF-8, and matching runs with tight
+// resource limits. None of its methods panic, and a Validator is safe
+// for concurrent use.
+type Validator struct {
+	re   *Regexp
+	mctx *MatchContext
+	mu   sync.Mutex
+	idle []*Matcher // matchers not in use, freed by Free
+}
+
+// NewValidator compiles the pattern into a Validator.
+func NewValidator(pattern string) (*Validator, error) {
+	re, err := Compile(pattern, ANCHORED|ENDANCHORED|UTF)
+	if err != nil {
+		return nil, err
+	}
+	v := &Validator{re: re, mctx: NewMatchContext()}
+	v.mctx.SetMatchLimit(validatorMatchLimit)
+	v.mctx.SetDepthLimit(validatorDepthLimit)
+	v.mctx.SetHeapLimit(validatorHeapLimit)
+	return v, nil
+}
+
+// Valid reports whether s matches the pattern in its entirety.
+func (v *Validator) Valid(s string) bool {
+	return v.Explain(s) == nil
+}
+
+// Explain returns nil if s matches the pattern in its entirety.
+// Otherwise it returns ErrNoMatch, or a *MatchError describing why
+// the input could not be checked, e.g. because it is not valid UTF-8
+// or because a resource limit was exceeded.
+func (v *Validator) Explain(s string) error {
+	if v == nil || v.re == nil || v.re.ptr == nil {
+		return ErrInvalidRegexp
+	}
+	m := v.matcher()
+	defer v.release(m)
+
+	if m.MatchString(s, 0) {
+		return nil
+	}
+	if m.rc == ERROR_NOMATCH {
+		return ErrNoMatch
+	}
+	return m.GetError()
+}
+
+// matcher takes an idle matcher, or creates one.
+func (v *Validator) matcher() *Matcher {
+	v.mu.Lock()
+	if n := len(v.idle); n > 0 {
+		m := v.idle[n-1]
+		v.idle = v.idle[:n-1]
+		v.mu.Unlock()
+		return m
+	}
+	v.mu.Unlock()
+	m := v.re.NewMatcher()
+	m.SetMatchContext(v.mctx)
+	return m
+}
+
+// release wipes the input from m and makes it idle again.
+func (v *Validator) release(m *Matcher) {
+	m.Zeroize()
+	v.mu.Lock()
+	v.idle = append(v.idle, m)
+	v.mu.Unlock()
+}
+
+// Free releases the underlying C resources, including those of the
+// matchers and the match context of v. It must not be called while v
+// is in use.
+func (v *Validator) Free() error {
+	if v == nil {
+		return nil
+	}
+	v.mu.Lock()
+	for _, m := range v.idle {
+		m.Free()
+	}
+	v.idle = nil
+	v.mu.Unlock()
+	v.mctx.Free()
+	return v.re.Free()
+}
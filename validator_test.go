@@ -0,0 +1,59 @@
+package pcre2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidator(t *testing.T) {
+	v, err := NewValidator(`[a-z]+\d*`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer v.Free()
+
+	assert.True(t, v.Valid("abc123"))
+	assert.False(t, v.Valid("abc123!"))
+	assert.False(t, v.Valid("!abc"))
+	assert.Equal(t, ErrNoMatch, v.Explain("ABC"))
+
+	err = v.Explain("ab\xffc")
+	if assert.IsType(t, &MatchError{}, err) {
+		assert.Equal(t, ERROR_UTF8_ERR21, err.(*MatchError).ErrorNum)
+	}
+
+	v, err = NewValidator(`(a|aa)+$`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer v.Free()
+	long := make([]byte, 5000)
+	for i := range long {
+		long[i] = 'a'
+	}
+	err = v.Explain(string(long) + "b")
+	assert.IsType(t, &MatchError{}, err)
+
+	_, err = NewValidator(`(`)
+	assert.Error(t, err)
+	var nilValidator *Validator
+	assert.False(t, nilValidator.Valid("a"))
+}
+
+func TestValidatorFree(t *testing.T) {
+	SetAutoCleanup(false)
+	defer SetAutoCleanup(true)
+	before := Snapshot()
+	v, err := NewValidator(`[a-z]+`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, v.Valid("abc"))
+	assert.False(t, v.Valid("ABC"))
+	assert.NoError(t, v.Free())
+	assert.Equal(t, before.LiveRegexps, Snapshot().LiveRegexps)
+	assert.Equal(t, before.LiveMatchers, Snapshot().LiveMatchers)
+	assert.Nil(t, v.mctx.ptr)
+	assert.Equal(t, ErrInvalidRegexp, v.Explain("abc"))
+}
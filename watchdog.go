@@ -0,0 +1,193 @@
+package pcre2
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Maximum number of incidents kept by a Watchdog, and maximum number of
+// subject bytes recorded per incident.
+const (
+	maxWatchdogIncidents = 100
+	maxIncidentSubject   = 256
+)
+
+// Incident records a match which was interrupted by a Watchdog.
+type Incident struct {
+	Pattern       string
+	Subject       string // the start of the subject, at most 256 bytes
+	SubjectLength int    // the full length of the subject
+	Started       time.Time
+	Duration      time.Duration // running time when interrupted
+}
+
+// Watchdog monitors in-flight matches and interrupts those which run
+// longer than a configured duration. Interrupted matches fail with
+// ERROR_TIMEOUT, and an Incident is recorded for diagnostics. A watchdog
+// is installed for all matches with SetWatchdog, or for the matches
+// using a context with MatchContext.SetWatchdog.
+//
+// Monitored matches run in slices of bounded work, see MatchOptions, so
+// interruption happens at the end of the current slice. Each slice
+// restarts the match with a doubled match limit, which costs up to
+// about twice the work of an unmonitored match, and every Matcher keeps
+// a private copy of its match context. Matches with a callout, e.g. of
+// a Tracer or Profiler, run once instead, as a restart would repeat the
+// callouts, and cannot be interrupted.
+type Watchdog struct {
+	limit       time.Duration
+	mu          sync.Mutex
+	inflight    map[*watchEntry]struct{}
+	incidents   []Incident
+	onInterrupt func(Incident)
+	stop        chan struct{}
+	stopOnce    sync.Once
+}
+
+// watchEntry is an in-flight match.
+type watchEntry struct {
+	m           *Matcher
+	length      int
+	started     time.Time
+	interrupted atomic.Bool
+}
+
+// NewWatchdog starts a watchdog which interrupts matches running longer
+// than limit. Stop must be called to release its goroutine.
+func NewWatchdog(limit time.Duration) *Watchdog {
+	w := &Watchdog{
+		limit:    limit,
+		inflight: make(map[*watchEntry]struct{}),
+		stop:     make(chan struct{}),
+	}
+	interval := limit / 4
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	go w.run(interval)
+	return w
+}
+
+func (w *Watchdog) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case now := <-ticker.C:
+			w.check(now)
+		}
+	}
+}
+
+// check interrupts the matches which exceeded the limit.
+func (w *Watchdog) check(now time.Time) {
+	var incidents []Incident
+	w.mu.Lock()
+	for e := range w.inflight {
+		if now.Sub(e.started) < w.limit || e.interrupted.Load() {
+			continue
+		}
+		e.interrupted.Store(true)
+		incident := e.incident(now)
+		if len(w.incidents) == maxWatchdogIncidents {
+			w.incidents = w.incidents[1:]
+		}
+		w.incidents = append(w.incidents, incident)
+		incidents = append(incidents, incident)
+	}
+	fn := w.onInterrupt
+	w.mu.Unlock()
+	if fn != nil {
+		for _, incident := range incidents {
+			fn(incident)
+		}
+	}
+}
+
+// incident describes the match. It is called while the match is still
+// registered, so the subject of the Matcher is the one being matched.
+func (e *watchEntry) incident(now time.Time) Incident {
+	subject := e.m.subjects
+	if e.m.subjectb != nil {
+		n := min(len(e.m.subjectb), maxIncidentSubject)
+		subject = string(e.m.subjectb[:n])
+	} else if len(subject) > maxIncidentSubject {
+		subject = subject[:maxIncidentSubject]
+	}
+	return Incident{
+		Pattern:       e.m.re.Pattern,
+		Subject:       subject,
+		SubjectLength: e.length,
+		Started:       e.started,
+		Duration:      now.Sub(e.started),
+	}
+}
+
+func (w *Watchdog) begin(m *Matcher, length int) *watchEntry {
+	e := &watchEntry{m: m, length: length, started: time.Now()}
+	w.mu.Lock()
+	w.inflight[e] = struct{}{}
+	w.mu.Unlock()
+	return e
+}
+
+func (w *Watchdog) end(e *watchEntry) {
+	w.mu.Lock()
+	delete(w.inflight, e)
+	w.mu.Unlock()
+}
+
+// OnInterrupt sets a function which is called for every interrupted
+// match. It runs on the goroutine of the watchdog and should not block.
+func (w *Watchdog) OnInterrupt(fn func(Incident)) {
+	w.mu.Lock()
+	w.onInterrupt = fn
+	w.mu.Unlock()
+}
+
+// InFlight returns the number of matches currently monitored.
+func (w *Watchdog) InFlight() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.inflight)
+}
+
+// Incidents returns the most recent interrupted matches, oldest first.
+func (w *Watchdog) Incidents() []Incident {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Incident(nil), w.incidents...)
+}
+
+// Stop stops the watchdog. Matches are no longer interrupted, but
+// remain monitored until the watchdog is uninstalled.
+func (w *Watchdog) Stop() {
+	w.stopOnce.Do(func() { close(w.stop) })
+}
+
+var defaultWatchdog atomic.Pointer[Watchdog]
+
+// SetWatchdog installs a watchdog for all matches whose context does not
+// set its own. Nil uninstalls it. Every match then pays for the
+// monitoring, see Watchdog, so a watchdog for the contexts of untrusted
+// patterns is cheaper.
+func SetWatchdog(w *Watchdog) {
+	defaultWatchdog.Store(w)
+}
+
+// SetWatchdog installs a watchdog for the matches using this context,
+// overriding the one installed with the package-level SetWatchdog.
+func (mc *MatchContext) SetWatchdog(w *Watchdog) {
+	mc.watchdog = w
+}
+
+// watchdog returns the watchdog monitoring the matches of m, or nil.
+func (m *Matcher) watchdog() *Watchdog {
+	if m.mctx != nil && m.mctx.watchdog != nil {
+		return m.mctx.watchdog
+	}
+	return defaultWatchdog.Load()
+}
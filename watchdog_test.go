@@ -0,0 +1,53 @@
+package pcre2
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchdog(t *testing.T) {
+	w := NewWatchdog(20 * time.Millisecond)
+	defer w.Stop()
+	interrupted := make(chan Incident, 1)
+	w.OnInterrupt(func(i Incident) { interrupted <- i })
+
+	re := MustCompile(`^(a+)+$`, 0)
+	defer re.Free()
+	mc := NewMatchContext()
+	defer mc.Free()
+	mc.SetWatchdog(w)
+	m := re.NewMatcher()
+	defer m.Free()
+	m.SetMatchContext(mc)
+
+	subject := strings.Repeat("a", 40) + "b"
+	assert.False(t, m.MatchString(subject, 0))
+	assert.Equal(t, ErrTimeout, m.GetError())
+	assert.Zero(t, w.InFlight())
+
+	incident := <-interrupted
+	assert.Equal(t, re.Pattern, incident.Pattern)
+	assert.Equal(t, subject, incident.Subject)
+	assert.Equal(t, len(subject), incident.SubjectLength)
+	assert.True(t, incident.Duration >= 20*time.Millisecond)
+	assert.Len(t, w.Incidents(), 1)
+
+	// Fast matches are not affected.
+	assert.True(t, m.MatchString("aaa", 0))
+}
+
+func TestDefaultWatchdog(t *testing.T) {
+	w := NewWatchdog(time.Hour)
+	defer w.Stop()
+	SetWatchdog(w)
+	defer SetWatchdog(nil)
+
+	m := MustCompile(`a+`, 0).NewMatcher()
+	assert.Equal(t, w, m.watchdog())
+	assert.True(t, m.MatchString("baa", 0))
+	assert.Equal(t, []int{1, 3}, m.Index())
+	assert.Empty(t, w.Incidents())
+}
@@ -0,0 +1,23 @@
+package pcre2
+
+// Zeroize clears the references the matcher holds to the last subject
+// and overwrites the recorded match results, so that no trace of a
+// sensitive subject lingers in the matcher. The matcher can be reused
+// afterwards. Slices and strings previously returned by the group
+// accessors belong to the caller and are not affected.
+//
+// Programs built with the pcre2_zeroize build tag zeroize matchers
+// automatically whenever they are freed or switched to another Regexp.
+func (m *Matcher) Zeroize() {
+	m.subjects = ""
+	m.subjectb = nil
+	m.matches = false
+	m.partial = false
+	m.rc = ERROR_NOMATCH
+	if m.mData != nil && m.mData.md != nil {
+		for i := range m.mData.ovector {
+			m.mData.ovector[i] = UNSET
+		}
+	}
+	clear(m.dfaWorkspace)
+}
@@ -0,0 +1,6 @@
+//go:build !pcre2_zeroize
+
+package pcre2
+
+// alwaysZeroize is set by the pcre2_zeroize build tag.
+const alwaysZeroize = false
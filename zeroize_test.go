@@ -0,0 +1,37 @@
+package pcre2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZeroize(t *testing.T) {
+	re := MustCompile(`password=(\S+)`, 0)
+	m := re.MatcherString("password=hunter2", 0)
+	assert.Equal(t, "hunter2", m.GroupString(1))
+
+	m.Zeroize()
+	assert.False(t, m.Matches())
+	assert.Empty(t, m.subjects)
+	assert.Nil(t, m.subjectb)
+	assert.False(t, m.Present(1))
+	assert.False(t, m.HasError())
+
+	assert.True(t, m.Match([]byte("password=secret"), 0))
+	assert.Equal(t, "secret", m.GroupString(1))
+}
+
+func TestZeroizeLongest(t *testing.T) {
+	re := MustCompile(`secret|secrets`, 0)
+	defer re.Free()
+	re.Longest()
+	m := re.MatcherString("my secrets", 0)
+	defer m.Free()
+	assert.Equal(t, "secrets", m.GroupString(0))
+	assert.NotEmpty(t, m.dfaWorkspace)
+	m.Zeroize()
+	for _, v := range m.dfaWorkspace {
+		assert.Zero(t, v)
+	}
+}